@@ -8,11 +8,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"memplane/internal/config"
 	"memplane/internal/httpserver"
 	"memplane/internal/logging"
 	"memplane/internal/memory"
+	"memplane/internal/metrics"
+	"memplane/internal/tracing"
 
 	"go.uber.org/zap"
 )
@@ -36,9 +39,74 @@ func run() error {
 	}
 	defer logger.Sync()
 
-	store := memory.NewStore()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint, cfg.TraceSampleRatio)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("flush traces", zap.Error(err))
+		}
+	}()
+
+	var limits config.Limits
+	if cfg.LimitsFile != "" {
+		limits, err = config.LoadLimits(cfg.LimitsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	m := metrics.New()
+	store, closeStore, err := openStore(cfg, limits, m, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeStore(); err != nil {
+			logger.Error("close store", zap.Error(err))
+		}
+	}()
 
-	router, err := httpserver.NewRouter(cfg.Environment, store)
+	if err := m.RegisterStore(store); err != nil {
+		return err
+	}
+
+	// The sweeper is stopped before the deferred closeStore runs, so no sweep
+	// races the store shutting down.
+	if retentionPolicy(limits).Enabled() {
+		sweepCtx, stopSweeper := context.WithCancel(context.Background())
+		sweeperDone := make(chan struct{})
+		go func() {
+			defer close(sweeperDone)
+			memory.RunSweeper(sweepCtx, store, cfg.RetentionSweepInterval, func(err error) {
+				logger.Error("retention sweep failed", zap.Error(err))
+			})
+		}()
+		defer func() {
+			stopSweeper()
+			<-sweeperDone
+		}()
+	}
+
+	routerOpts := []httpserver.Option{
+		httpserver.WithRateLimits(rateLimits(limits)),
+		httpserver.WithMetrics(m),
+		httpserver.WithLogger(logger),
+	}
+	if cfg.APIKeysFile != "" {
+		keys, err := httpserver.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return err
+		}
+		routerOpts = append(routerOpts, httpserver.WithAPIKeys(keys))
+	} else {
+		logger.Warn("authentication disabled; set MEMPLANE_API_KEYS_FILE to require api keys")
+	}
+
+	router, err := httpserver.NewRouter(cfg.Environment, store, routerOpts...)
 	if err != nil {
 		return err
 	}
@@ -87,3 +155,98 @@ func run() error {
 	logger.Info("server stopped")
 	return nil
 }
+
+func openStore(cfg config.Config, limits config.Limits, observer memory.Observer, logger *zap.Logger) (memory.Store, func() error, error) {
+	storeOpts := memory.StoreOptions{
+		Index: memory.IndexOptions{
+			M:              cfg.HNSWM,
+			EfConstruction: cfg.HNSWEfConstruction,
+			EfSearch:       cfg.HNSWEfSearch,
+			MinVectors:     cfg.HNSWMinVectors,
+		},
+		Quotas:       quotaPolicy(limits),
+		Retention:    retentionPolicy(limits),
+		SpanPolicies: spanPolicies(limits),
+		Observer:     observer,
+	}
+
+	if cfg.DataDir == "" {
+		return memory.NewStoreWithOptions(storeOpts), func() error { return nil }, nil
+	}
+
+	store, err := memory.OpenDurableStore(cfg.DataDir, memory.DurableOptions{
+		Store:            storeOpts,
+		SnapshotInterval: cfg.SnapshotInterval,
+		OnSnapshotError: func(err error) {
+			logger.Error("snapshot failed", zap.Error(err))
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, store.Close, nil
+}
+
+func quotaPolicy(limits config.Limits) memory.QuotaPolicy {
+	quota := func(l config.TenantLimits) memory.Quota {
+		return memory.Quota{
+			MaxEventsPerSession:  l.MaxEventsPerSession,
+			MaxSessionsPerTenant: l.MaxSessionsPerTenant,
+			MaxPayloadBytes:      l.MaxPayloadBytes,
+		}
+	}
+
+	policy := memory.QuotaPolicy{
+		Default: quota(limits.Default),
+		Tenants: make(map[string]memory.Quota, len(limits.Tenants)),
+	}
+	for tenantID, tenant := range limits.Tenants {
+		policy.Tenants[tenantID] = quota(tenant)
+	}
+	return policy
+}
+
+func retentionPolicy(limits config.Limits) memory.RetentionPolicy {
+	retention := func(l config.TenantLimits) memory.Retention {
+		return memory.Retention{
+			EventTTL:       time.Duration(l.EventTTL),
+			SessionIdleTTL: time.Duration(l.SessionIdleTTL),
+			RetainEvents:   l.RetainEventsPerSession,
+		}
+	}
+
+	policy := memory.RetentionPolicy{
+		Default: retention(limits.Default),
+		Tenants: make(map[string]memory.Retention, len(limits.Tenants)),
+	}
+	for tenantID, tenant := range limits.Tenants {
+		policy.Tenants[tenantID] = retention(tenant)
+	}
+	return policy
+}
+
+func spanPolicies(limits config.Limits) memory.SpanPolicies {
+	policies := memory.SpanPolicies{
+		Default: memory.SpanPolicy(limits.Default.SpanPolicy),
+		Tenants: make(map[string]memory.SpanPolicy, len(limits.Tenants)),
+	}
+	for tenantID, tenant := range limits.Tenants {
+		policies.Tenants[tenantID] = memory.SpanPolicy(tenant.SpanPolicy)
+	}
+	return policies
+}
+
+func rateLimits(limits config.Limits) httpserver.RateLimits {
+	rate := func(l config.TenantLimits) httpserver.RateLimit {
+		return httpserver.RateLimit{RequestsPerSecond: l.RequestsPerSecond, Burst: l.Burst}
+	}
+
+	result := httpserver.RateLimits{
+		Default: rate(limits.Default),
+		Tenants: make(map[string]httpserver.RateLimit, len(limits.Tenants)),
+	}
+	for tenantID, tenant := range limits.Tenants {
+		result.Tenants[tenantID] = rate(tenant)
+	}
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,13 +12,20 @@ import (
 )
 
 const (
-	defaultHTTPAddr          = ":8080"
-	defaultShutdownTimeout   = 10 * time.Second
-	defaultReadHeaderTimeout = 5 * time.Second
-	defaultWriteTimeout      = 15 * time.Second
-	defaultIdleTimeout       = 60 * time.Second
-	defaultLogLevel          = "info"
-	defaultEnvironment       = "production"
+	defaultHTTPAddr           = ":8080"
+	defaultShutdownTimeout    = 10 * time.Second
+	defaultReadHeaderTimeout  = 5 * time.Second
+	defaultWriteTimeout       = 15 * time.Second
+	defaultIdleTimeout        = 60 * time.Second
+	defaultLogLevel           = "info"
+	defaultEnvironment        = "production"
+	defaultSnapshotInterval   = 5 * time.Minute
+	defaultHNSWM              = 16
+	defaultHNSWEfConstruction = 200
+	defaultHNSWEfSearch       = 64
+	defaultHNSWMinVectors     = 1024
+	defaultTraceSampleRatio   = 1.0
+	defaultRetentionSweep     = time.Minute
 )
 
 type Config struct {
@@ -28,6 +36,25 @@ type Config struct {
 	IdleTimeout       time.Duration
 	LogLevel          string
 	Environment       string
+	// DataDir enables the durable store when set; empty keeps events in memory only.
+	DataDir string
+	// SnapshotInterval is how often the durable store snapshots and compacts its log.
+	SnapshotInterval time.Duration
+	// HNSW* tune the per-session approximate nearest neighbour index.
+	HNSWM              int
+	HNSWEfConstruction int
+	HNSWEfSearch       int
+	HNSWMinVectors     int
+	// APIKeysFile lists hashed bearer API keys; empty disables authentication.
+	APIKeysFile string
+	// LimitsFile holds per-tenant rate limits and storage quotas; empty means unlimited.
+	LimitsFile string
+	// OTLPEndpoint is the OTLP/HTTP collector URL for traces; empty disables export.
+	OTLPEndpoint string
+	// TraceSampleRatio is the fraction of new traces sampled when exporting.
+	TraceSampleRatio float64
+	// RetentionSweepInterval is how often expired events and sessions are removed.
+	RetentionSweepInterval time.Duration
 }
 
 func Load() (Config, error) {
@@ -43,6 +70,15 @@ func Load() (Config, error) {
 		IdleTimeout:       defaultIdleTimeout,
 		LogLevel:          defaultLogLevel,
 		Environment:       defaultEnvironment,
+		SnapshotInterval:  defaultSnapshotInterval,
+
+		HNSWM:              defaultHNSWM,
+		HNSWEfConstruction: defaultHNSWEfConstruction,
+		HNSWEfSearch:       defaultHNSWEfSearch,
+		HNSWMinVectors:     defaultHNSWMinVectors,
+
+		TraceSampleRatio:       defaultTraceSampleRatio,
+		RetentionSweepInterval: defaultRetentionSweep,
 	}
 
 	if v := strings.TrimSpace(os.Getenv("MEMPLANE_HTTP_ADDR")); v != "" {
@@ -77,6 +113,73 @@ func Load() (Config, error) {
 		cfg.LogLevel = strings.ToLower(v)
 	}
 
+	if v := strings.TrimSpace(os.Getenv("MEMPLANE_DATA_DIR")); v != "" {
+		cfg.DataDir = v
+	}
+
+	if d, ok, err := readDurationEnv("MEMPLANE_SNAPSHOT_INTERVAL"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.SnapshotInterval = d
+	}
+
+	if d, ok, err := readDurationEnv("MEMPLANE_RETENTION_SWEEP_INTERVAL"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.RetentionSweepInterval = d
+	}
+
+	if n, ok, err := readPositiveIntEnv("MEMPLANE_HNSW_M"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.HNSWM = n
+	}
+
+	if n, ok, err := readPositiveIntEnv("MEMPLANE_HNSW_EF_CONSTRUCTION"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.HNSWEfConstruction = n
+	}
+
+	if n, ok, err := readPositiveIntEnv("MEMPLANE_HNSW_EF_SEARCH"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.HNSWEfSearch = n
+	}
+
+	if n, ok, err := readPositiveIntEnv("MEMPLANE_HNSW_MIN_VECTORS"); err != nil {
+		return Config{}, err
+	} else if ok {
+		cfg.HNSWMinVectors = n
+	}
+
+	if v := strings.TrimSpace(os.Getenv("MEMPLANE_API_KEYS_FILE")); v != "" {
+		cfg.APIKeysFile = v
+	}
+
+	if v := strings.TrimSpace(os.Getenv("MEMPLANE_LIMITS_FILE")); v != "" {
+		cfg.LimitsFile = v
+	}
+
+	if v := strings.TrimSpace(os.Getenv("MEMPLANE_OTLP_ENDPOINT")); v != "" {
+		cfg.OTLPEndpoint = v
+	}
+
+	if v := strings.TrimSpace(os.Getenv("MEMPLANE_TRACE_SAMPLE_RATIO")); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse MEMPLANE_TRACE_SAMPLE_RATIO: %w", err)
+		}
+		if ratio < 0 || ratio > 1 {
+			return Config{}, fmt.Errorf("MEMPLANE_TRACE_SAMPLE_RATIO must be between 0 and 1")
+		}
+		cfg.TraceSampleRatio = ratio
+	}
+
+	if cfg.HNSWM < 2 {
+		return Config{}, fmt.Errorf("MEMPLANE_HNSW_M must be at least 2")
+	}
+
 	if v := strings.TrimSpace(os.Getenv("MEMPLANE_ENV")); v != "" {
 		cfg.Environment = strings.ToLower(v)
 	}
@@ -106,3 +209,20 @@ func readDurationEnv(key string) (time.Duration, bool, error) {
 
 	return d, true, nil
 }
+
+func readPositiveIntEnv(key string) (int, bool, error) {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("parse %s: %w", key, err)
+	}
+	if n <= 0 {
+		return 0, false, fmt.Errorf("%s must be positive", key)
+	}
+
+	return n, true, nil
+}
@@ -14,6 +14,17 @@ func TestLoadDefaults(t *testing.T) {
 	setEnv(t, "MEMPLANE_IDLE_TIMEOUT", "")
 	setEnv(t, "MEMPLANE_LOG_LEVEL", "")
 	setEnv(t, "MEMPLANE_ENV", "")
+	setEnv(t, "MEMPLANE_DATA_DIR", "")
+	setEnv(t, "MEMPLANE_SNAPSHOT_INTERVAL", "")
+	setEnv(t, "MEMPLANE_HNSW_M", "")
+	setEnv(t, "MEMPLANE_HNSW_EF_CONSTRUCTION", "")
+	setEnv(t, "MEMPLANE_HNSW_EF_SEARCH", "")
+	setEnv(t, "MEMPLANE_HNSW_MIN_VECTORS", "")
+	setEnv(t, "MEMPLANE_API_KEYS_FILE", "")
+	setEnv(t, "MEMPLANE_LIMITS_FILE", "")
+	setEnv(t, "MEMPLANE_OTLP_ENDPOINT", "")
+	setEnv(t, "MEMPLANE_TRACE_SAMPLE_RATIO", "")
+	setEnv(t, "MEMPLANE_RETENTION_SWEEP_INTERVAL", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -41,6 +52,25 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.Environment != defaultEnvironment {
 		t.Fatalf("expected default environment %q, got %q", defaultEnvironment, cfg.Environment)
 	}
+	if cfg.DataDir != "" {
+		t.Fatalf("expected empty data dir, got %q", cfg.DataDir)
+	}
+	if cfg.SnapshotInterval != defaultSnapshotInterval {
+		t.Fatalf("expected default snapshot interval %v, got %v", defaultSnapshotInterval, cfg.SnapshotInterval)
+	}
+	if cfg.HNSWM != defaultHNSWM || cfg.HNSWEfConstruction != defaultHNSWEfConstruction ||
+		cfg.HNSWEfSearch != defaultHNSWEfSearch || cfg.HNSWMinVectors != defaultHNSWMinVectors {
+		t.Fatalf("unexpected default hnsw settings: %#v", cfg)
+	}
+	if cfg.APIKeysFile != "" || cfg.LimitsFile != "" {
+		t.Fatalf("expected empty api keys and limits files, got %q and %q", cfg.APIKeysFile, cfg.LimitsFile)
+	}
+	if cfg.OTLPEndpoint != "" || cfg.TraceSampleRatio != defaultTraceSampleRatio {
+		t.Fatalf("expected tracing disabled with ratio %v, got %q and %v", defaultTraceSampleRatio, cfg.OTLPEndpoint, cfg.TraceSampleRatio)
+	}
+	if cfg.RetentionSweepInterval != defaultRetentionSweep {
+		t.Fatalf("expected default retention sweep interval %v, got %v", defaultRetentionSweep, cfg.RetentionSweepInterval)
+	}
 }
 
 func TestLoadFromEnv(t *testing.T) {
@@ -51,6 +81,17 @@ func TestLoadFromEnv(t *testing.T) {
 	setEnv(t, "MEMPLANE_IDLE_TIMEOUT", "30s")
 	setEnv(t, "MEMPLANE_LOG_LEVEL", "debug")
 	setEnv(t, "MEMPLANE_ENV", "development")
+	setEnv(t, "MEMPLANE_DATA_DIR", "/var/lib/memplane")
+	setEnv(t, "MEMPLANE_SNAPSHOT_INTERVAL", "30s")
+	setEnv(t, "MEMPLANE_HNSW_M", "8")
+	setEnv(t, "MEMPLANE_HNSW_EF_CONSTRUCTION", "100")
+	setEnv(t, "MEMPLANE_HNSW_EF_SEARCH", "32")
+	setEnv(t, "MEMPLANE_HNSW_MIN_VECTORS", "500")
+	setEnv(t, "MEMPLANE_API_KEYS_FILE", "/etc/memplane/keys.json")
+	setEnv(t, "MEMPLANE_LIMITS_FILE", "/etc/memplane/limits.json")
+	setEnv(t, "MEMPLANE_OTLP_ENDPOINT", "http://collector:4318")
+	setEnv(t, "MEMPLANE_TRACE_SAMPLE_RATIO", "0.25")
+	setEnv(t, "MEMPLANE_RETENTION_SWEEP_INTERVAL", "30s")
 
 	cfg, err := Load()
 	if err != nil {
@@ -78,6 +119,27 @@ func TestLoadFromEnv(t *testing.T) {
 	if cfg.Environment != "development" {
 		t.Fatalf("expected environment %q, got %q", "development", cfg.Environment)
 	}
+	if cfg.DataDir != "/var/lib/memplane" {
+		t.Fatalf("expected data dir %q, got %q", "/var/lib/memplane", cfg.DataDir)
+	}
+	if cfg.SnapshotInterval != 30*time.Second {
+		t.Fatalf("expected snapshot interval %v, got %v", 30*time.Second, cfg.SnapshotInterval)
+	}
+	if cfg.HNSWM != 8 || cfg.HNSWEfConstruction != 100 || cfg.HNSWEfSearch != 32 || cfg.HNSWMinVectors != 500 {
+		t.Fatalf("unexpected hnsw settings: %#v", cfg)
+	}
+	if cfg.APIKeysFile != "/etc/memplane/keys.json" {
+		t.Fatalf("expected api keys file %q, got %q", "/etc/memplane/keys.json", cfg.APIKeysFile)
+	}
+	if cfg.LimitsFile != "/etc/memplane/limits.json" {
+		t.Fatalf("expected limits file %q, got %q", "/etc/memplane/limits.json", cfg.LimitsFile)
+	}
+	if cfg.OTLPEndpoint != "http://collector:4318" || cfg.TraceSampleRatio != 0.25 {
+		t.Fatalf("expected otlp endpoint %q with ratio 0.25, got %q and %v", "http://collector:4318", cfg.OTLPEndpoint, cfg.TraceSampleRatio)
+	}
+	if cfg.RetentionSweepInterval != 30*time.Second {
+		t.Fatalf("expected retention sweep interval 30s, got %v", cfg.RetentionSweepInterval)
+	}
 }
 
 func TestLoadRejectsInvalidTimeout(t *testing.T) {
@@ -120,6 +182,39 @@ func TestLoadRejectsInvalidIdleTimeout(t *testing.T) {
 	}
 }
 
+func TestLoadRejectsInvalidSnapshotInterval(t *testing.T) {
+	setEnv(t, "MEMPLANE_SNAPSHOT_INTERVAL", "invalid")
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestLoadRejectsInvalidHNSWSettings(t *testing.T) {
+	for _, tc := range []struct{ key, value string }{
+		{key: "MEMPLANE_HNSW_M", value: "1"},
+		{key: "MEMPLANE_HNSW_EF_SEARCH", value: "0"},
+		{key: "MEMPLANE_HNSW_EF_CONSTRUCTION", value: "many"},
+	} {
+		setEnv(t, tc.key, tc.value)
+		_, err := Load()
+		if err == nil {
+			t.Fatalf("expected error for %s=%q, got nil", tc.key, tc.value)
+		}
+		setEnv(t, tc.key, "")
+	}
+}
+
+func TestLoadRejectsInvalidTraceSampleRatio(t *testing.T) {
+	for _, value := range []string{"often", "-0.1", "1.5"} {
+		setEnv(t, "MEMPLANE_TRACE_SAMPLE_RATIO", value)
+		_, err := Load()
+		if err == nil {
+			t.Fatalf("expected error for %q, got nil", value)
+		}
+	}
+}
+
 func TestLoadRejectsInvalidEnvironment(t *testing.T) {
 	setEnv(t, "MEMPLANE_ENV", "staging")
 	_, err := Load()
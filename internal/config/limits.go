@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TenantLimits are the rate limits, storage quotas, retention and span policy
+// applied to a tenant. Zero fields are unlimited in the default and inherit the default
+// in a tenant override.
+type TenantLimits struct {
+	RequestsPerSecond    float64 `json:"requests_per_second"`
+	Burst                int     `json:"burst"`
+	MaxEventsPerSession  int     `json:"max_events_per_session"`
+	MaxSessionsPerTenant int     `json:"max_sessions_per_tenant"`
+	MaxPayloadBytes      int64   `json:"max_payload_bytes"`
+	// EventTTL, SessionIdleTTL and RetainEventsPerSession are enforced by the
+	// retention sweeper rather than by rejecting writes.
+	EventTTL               Duration `json:"event_ttl"`
+	SessionIdleTTL         Duration `json:"session_idle_ttl"`
+	RetainEventsPerSession int      `json:"retain_events_per_session"`
+	// SpanPolicy is "allow", "reject-overlap" or "require-contiguous". Empty
+	// allows any spans in the default and inherits it in a tenant override.
+	SpanPolicy string `json:"span_policy"`
+}
+
+// Duration is a time.Duration written in JSON as a Go duration string, such
+// as "720h".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"720h\"")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Limits is the contents of MEMPLANE_LIMITS_FILE.
+type Limits struct {
+	Default TenantLimits            `json:"default"`
+	Tenants map[string]TenantLimits `json:"tenants"`
+}
+
+// LoadLimits reads and validates a JSON limits file.
+func LoadLimits(path string) (Limits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Limits{}, fmt.Errorf("read limits: %w", err)
+	}
+
+	var limits Limits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return Limits{}, fmt.Errorf("parse limits: %w", err)
+	}
+
+	if err := limits.Default.validate(); err != nil {
+		return Limits{}, fmt.Errorf("default limits: %w", err)
+	}
+	for tenantID, tenant := range limits.Tenants {
+		if err := tenant.validate(); err != nil {
+			return Limits{}, fmt.Errorf("limits for tenant %q: %w", tenantID, err)
+		}
+	}
+
+	return limits, nil
+}
+
+func (l TenantLimits) validate() error {
+	if l.RequestsPerSecond < 0 || l.Burst < 0 || l.MaxEventsPerSession < 0 ||
+		l.MaxSessionsPerTenant < 0 || l.MaxPayloadBytes < 0 ||
+		l.EventTTL < 0 || l.SessionIdleTTL < 0 || l.RetainEventsPerSession < 0 {
+		return fmt.Errorf("limits must be non-negative")
+	}
+	switch l.SpanPolicy {
+	case "", "allow", "reject-overlap", "require-contiguous":
+	default:
+		return fmt.Errorf("span_policy must be \"allow\", \"reject-overlap\" or \"require-contiguous\"")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	contents := `{
+		"default": {"requests_per_second": 10, "burst": 20, "max_events_per_session": 1000},
+		"tenants": {"tenant_1": {"requests_per_second": 100, "event_ttl": "720h", "session_idle_ttl": "24h", "retain_events_per_session": 500, "span_policy": "reject-overlap"}}
+	}`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write limits: %v", err)
+	}
+
+	limits, err := LoadLimits(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if limits.Default.RequestsPerSecond != 10 || limits.Default.Burst != 20 || limits.Default.MaxEventsPerSession != 1000 {
+		t.Fatalf("unexpected default limits: %#v", limits.Default)
+	}
+	tenant := limits.Tenants["tenant_1"]
+	if tenant.RequestsPerSecond != 100 || time.Duration(tenant.EventTTL) != 720*time.Hour ||
+		time.Duration(tenant.SessionIdleTTL) != 24*time.Hour || tenant.RetainEventsPerSession != 500 ||
+		tenant.SpanPolicy != "reject-overlap" {
+		t.Fatalf("unexpected tenant limits: %#v", limits.Tenants)
+	}
+}
+
+func TestLoadLimitsRejectsNegativeValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"tenants": {"tenant_1": {"burst": -1}}}`), 0o600); err != nil {
+		t.Fatalf("write limits: %v", err)
+	}
+
+	if _, err := LoadLimits(path); err == nil {
+		t.Fatalf("expected error for negative burst")
+	}
+}
+
+func TestLoadLimitsRejectsUnknownSpanPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"default": {"span_policy": "strict"}}`), 0o600); err != nil {
+		t.Fatalf("write limits: %v", err)
+	}
+
+	if _, err := LoadLimits(path); err == nil {
+		t.Fatalf("expected error for unknown span policy")
+	}
+}
+
+func TestLoadLimitsRejectsInvalidDurations(t *testing.T) {
+	for _, contents := range []string{
+		`{"default": {"event_ttl": 3600}}`,
+		`{"default": {"event_ttl": "a while"}}`,
+		`{"default": {"session_idle_ttl": "-1h"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "limits.json")
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("write limits: %v", err)
+		}
+		if _, err := LoadLimits(path); err == nil {
+			t.Fatalf("expected error for %s", contents)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scope is a permission granted to an API key.
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+	// ScopeAdmin implies every other scope.
+	ScopeAdmin Scope = "admin"
+)
+
+const principalContextKey = "memplane.principal"
+
+// APIKey binds the SHA-256 hash of a bearer token to one tenant and its scopes.
+// The token itself is never stored.
+type APIKey struct {
+	ID        string  `json:"id"`
+	TenantID  string  `json:"tenant_id"`
+	Scopes    []Scope `json:"scopes"`
+	KeySHA256 string  `json:"key_sha256"`
+}
+
+// APIKeys is an immutable set of API keys indexed by token hash.
+type APIKeys struct {
+	byHash map[string]APIKey
+}
+
+// NewAPIKeys validates keys and indexes them by hash.
+func NewAPIKeys(keys []APIKey) (*APIKeys, error) {
+	byHash := make(map[string]APIKey, len(keys))
+	ids := make(map[string]struct{}, len(keys))
+	for i, key := range keys {
+		if strings.TrimSpace(key.ID) == "" {
+			return nil, fmt.Errorf("api key %d: id is required", i)
+		}
+		if _, exists := ids[key.ID]; exists {
+			return nil, fmt.Errorf("api key %q: duplicate id", key.ID)
+		}
+		ids[key.ID] = struct{}{}
+		if strings.TrimSpace(key.TenantID) == "" {
+			return nil, fmt.Errorf("api key %q: tenant_id is required", key.ID)
+		}
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("api key %q: at least one scope is required", key.ID)
+		}
+		for _, scope := range key.Scopes {
+			switch scope {
+			case ScopeRead, ScopeWrite, ScopeAdmin:
+			default:
+				return nil, fmt.Errorf("api key %q: scope must be one of: read, write, admin", key.ID)
+			}
+		}
+
+		hash := strings.ToLower(key.KeySHA256)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: key_sha256 must be a hex-encoded SHA-256 digest", key.ID)
+		}
+		if _, exists := byHash[hash]; exists {
+			return nil, fmt.Errorf("api key %q: duplicate key_sha256", key.ID)
+		}
+		key.KeySHA256 = hash
+		byHash[hash] = key
+	}
+
+	return &APIKeys{byHash: byHash}, nil
+}
+
+// LoadAPIKeys reads a JSON array of APIKey entries from path.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read api keys: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parse api keys: %w", err)
+	}
+	return NewAPIKeys(keys)
+}
+
+// HashAPIKey returns the hex SHA-256 digest stored for a bearer token.
+func HashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKeys) lookup(token string) (APIKey, bool) {
+	key, ok := k.byHash[HashAPIKey(token)]
+	return key, ok
+}
+
+func (k APIKey) allows(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+var errMissingBearerToken = errors.New("missing bearer token")
+
+// requireScope authenticates the bearer token and checks it grants scope.
+// With no keys configured authentication is disabled and every request passes.
+func requireScope(keys *APIKeys, scope Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keys == nil {
+			c.Next()
+			return
+		}
+
+		token, err := bearerToken(c.GetHeader("Authorization"))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="memplane"`)
+			writeError(c, http.StatusUnauthorized, err.Error())
+			c.Abort()
+			return
+		}
+		key, ok := keys.lookup(token)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="memplane", error="invalid_token"`)
+			writeError(c, http.StatusUnauthorized, "invalid api key")
+			c.Abort()
+			return
+		}
+		if !key.allows(scope) {
+			writeError(c, http.StatusForbidden, fmt.Sprintf("api key lacks %s scope", scope))
+			c.Abort()
+			return
+		}
+
+		c.Set(principalContextKey, key)
+		c.Next()
+	}
+}
+
+func bearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", errMissingBearerToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// authorizeTenant rejects the request unless its API key is bound to
+// tenantID. Handlers call it once they have parsed the tenant from the
+// request. It always passes when authentication is disabled.
+func authorizeTenant(c *gin.Context, tenantID string) bool {
+	value, ok := c.Get(principalContextKey)
+	if !ok {
+		return true
+	}
+	if key := value.(APIKey); key.TenantID != tenantID {
+		writeError(c, http.StatusForbidden, "api key is not authorized for tenant_id")
+		return false
+	}
+	return true
+}
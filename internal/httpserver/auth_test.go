@@ -0,0 +1,132 @@
+package httpserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"memplane/internal/memory"
+)
+
+func newAuthTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	keys, err := NewAPIKeys([]APIKey{
+		{ID: "reader", TenantID: "tenant_1", Scopes: []Scope{ScopeRead}, KeySHA256: HashAPIKey("read-token")},
+		{ID: "writer", TenantID: "tenant_1", Scopes: []Scope{ScopeRead, ScopeWrite}, KeySHA256: HashAPIKey("write-token")},
+		{ID: "admin", TenantID: "tenant_2", Scopes: []Scope{ScopeAdmin}, KeySHA256: HashAPIKey("admin-token")},
+	})
+	if err != nil {
+		t.Fatalf("new api keys: %v", err)
+	}
+
+	router, err := NewRouter("test", memory.NewStore(), WithAPIKeys(keys))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	return router
+}
+
+func TestAuthEnforcesKeysScopesAndTenant(t *testing.T) {
+	router := newAuthTestRouter(t)
+
+	createBody := func(tenantID string) string {
+		return `{"event_id":"evt_1","tenant_id":"` + tenantID +
+			`","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z"}`
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header string
+		status int
+	}{
+		{name: "missing token", method: http.MethodGet, path: "/v1/events?tenant_id=tenant_1&session_id=session_1", status: http.StatusUnauthorized},
+		{name: "wrong scheme", method: http.MethodGet, path: "/v1/events?tenant_id=tenant_1&session_id=session_1", header: "Basic read-token", status: http.StatusUnauthorized},
+		{name: "unknown token", method: http.MethodGet, path: "/v1/events?tenant_id=tenant_1&session_id=session_1", header: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "read scope reads", method: http.MethodGet, path: "/v1/events?tenant_id=tenant_1&session_id=session_1", header: "Bearer read-token", status: http.StatusOK},
+		{name: "read scope cannot write", method: http.MethodPost, path: "/v1/events", body: createBody("tenant_1"), header: "Bearer read-token", status: http.StatusForbidden},
+		{name: "other tenant in query", method: http.MethodGet, path: "/v1/events?tenant_id=tenant_2&session_id=session_1", header: "Bearer read-token", status: http.StatusForbidden},
+		{name: "other tenant in body", method: http.MethodPost, path: "/v1/events", body: createBody("tenant_2"), header: "Bearer write-token", status: http.StatusForbidden},
+		{name: "write scope writes", method: http.MethodPost, path: "/v1/events", body: createBody("tenant_1"), header: "Bearer write-token", status: http.StatusCreated},
+		{name: "admin implies write", method: http.MethodPost, path: "/v1/events", body: createBody("tenant_2"), header: "bearer admin-token", status: http.StatusCreated},
+		{name: "retrieve checks tenant", method: http.MethodPost, path: "/v1/retrieve", body: `{"tenant_id":"tenant_2","session_id":"session_1","event_ids":["evt_1"],"top_k":1}`, header: "Bearer read-token", status: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%s: expected WWW-Authenticate header", tc.name)
+		}
+	}
+}
+
+func TestAuthLeavesHealthOpen(t *testing.T) {
+	router := newAuthTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	contents := `[{"id":"k1","tenant_id":"tenant_1","scopes":["read"],"key_sha256":"` + strings.ToUpper(HashAPIKey("secret")) + `"}]`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write keys: %v", err)
+	}
+
+	keys, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("load api keys: %v", err)
+	}
+	key, ok := keys.lookup("secret")
+	if !ok || key.ID != "k1" || key.TenantID != "tenant_1" {
+		t.Fatalf("expected key k1 for tenant_1, got %#v (found=%v)", key, ok)
+	}
+}
+
+func TestNewAPIKeysRejectsInvalidEntries(t *testing.T) {
+	valid := HashAPIKey("secret")
+	cases := []struct {
+		name string
+		keys []APIKey
+	}{
+		{name: "missing id", keys: []APIKey{{TenantID: "t", Scopes: []Scope{ScopeRead}, KeySHA256: valid}}},
+		{name: "missing tenant", keys: []APIKey{{ID: "k", Scopes: []Scope{ScopeRead}, KeySHA256: valid}}},
+		{name: "no scopes", keys: []APIKey{{ID: "k", TenantID: "t", KeySHA256: valid}}},
+		{name: "unknown scope", keys: []APIKey{{ID: "k", TenantID: "t", Scopes: []Scope{"delete"}, KeySHA256: valid}}},
+		{name: "bad hash", keys: []APIKey{{ID: "k", TenantID: "t", Scopes: []Scope{ScopeRead}, KeySHA256: "secret"}}},
+		{name: "duplicate hash", keys: []APIKey{
+			{ID: "a", TenantID: "t", Scopes: []Scope{ScopeRead}, KeySHA256: valid},
+			{ID: "b", TenantID: "t", Scopes: []Scope{ScopeRead}, KeySHA256: valid},
+		}},
+	}
+
+	for _, tc := range cases {
+		if _, err := NewAPIKeys(tc.keys); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
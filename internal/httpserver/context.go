@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxContextBodyBytes int64 = maxJSONBodyBytes
+const maxContextTokenBudget = 1 << 20
+const defaultContextSeparator = "\n\n"
+
+// contextRequest takes the same query as retrieveRequest plus a token budget.
+// Separator defaults to a blank line between events.
+type contextRequest struct {
+	retrieveRequest
+	TokenBudget int     `json:"token_budget"`
+	Separator   *string `json:"separator"`
+}
+
+func (h eventsHandler) assembleContext(c *gin.Context) {
+	var req contextRequest
+	if err := bindJSONWithLimit(c, &req, maxContextBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	if req.TokenBudget <= 0 || req.TokenBudget > maxContextTokenBudget {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("token_budget must be between 1 and %d", maxContextTokenBudget))
+		return
+	}
+	hasQuery := req.QueryText != "" || len(req.QueryEmbedding) > 0
+	if len(req.EventIDs) > 0 && hasQuery {
+		writeError(c, http.StatusBadRequest, "event_ids are mutually exclusive with query_text and query_embedding")
+		return
+	}
+	if len(req.EventIDs) == 0 && !hasQuery {
+		writeError(c, http.StatusBadRequest, "one of event_ids, query_text and query_embedding is required")
+		return
+	}
+	if len(req.EventIDs) > maxRetrieveAnchorEventIDs {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("event_ids must contain at most %d items", maxRetrieveAnchorEventIDs))
+		return
+	}
+	for _, eventID := range req.EventIDs {
+		if strings.TrimSpace(eventID) == "" {
+			writeError(c, http.StatusBadRequest, "event_ids must not contain empty values")
+			return
+		}
+	}
+	if req.TopK > maxRetrieveTopK {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("top_k must be at most %d", maxRetrieveTopK))
+		return
+	}
+
+	metric := memory.SimilarityCosine
+	if req.Metric != "" {
+		metric = memory.SimilarityMetric(req.Metric)
+	}
+	separator := defaultContextSeparator
+	if req.Separator != nil {
+		separator = *req.Separator
+	}
+	recency, err := req.Recency.recency()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	assembled, err := h.store.AssembleContext(c.Request.Context(), memory.ContextRequest{
+		TenantID:       req.TenantID,
+		SessionID:      req.SessionID,
+		AnchorEventIDs: req.EventIDs,
+		QueryText:      req.QueryText,
+		QueryEmbedding: req.QueryEmbedding,
+		Metric:         metric,
+		TopK:           req.TopK,
+		BufferBefore:   req.BufferBefore,
+		BufferAfter:    req.BufferAfter,
+		TokenBudget:    req.TokenBudget,
+		Separator:      separator,
+		Recency:        recency,
+	})
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, assembled)
+}
@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"memplane/internal/memory"
+)
+
+func TestAssembleContextEndpoint(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, body := range []string{
+		`{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z","payload":{"text":"first"}}`,
+		`{"event_id":"evt_2","tenant_id":"tenant_1","session_id":"session_1","start_token":10,"end_token_exclusive":20,"created_at":"2026-02-10T12:00:01Z","payload":{"text":"second"}}`,
+		`{"event_id":"evt_3","tenant_id":"tenant_1","session_id":"session_1","start_token":20,"end_token_exclusive":30,"created_at":"2026-02-10T12:00:02Z","payload":{"text":"third"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "no budget", body: `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_2"],"top_k":1}`, status: http.StatusBadRequest},
+		{name: "budget too large", body: `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_2"],"top_k":1,"token_budget":2000000}`, status: http.StatusBadRequest},
+		{name: "no query", body: `{"tenant_id":"tenant_1","session_id":"session_1","top_k":1,"token_budget":20}`, status: http.StatusBadRequest},
+		{name: "both queries", body: `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_2"],"query_embedding":[1,0],"top_k":1,"token_budget":20}`, status: http.StatusBadRequest},
+		{name: "empty event id", body: `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":[" "],"top_k":1,"token_budget":20}`, status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/context", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	body := `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_2"],"top_k":1,"buffer_before":1,"buffer_after":1,"token_budget":20}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/context", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var assembled memory.AssembledContext
+	if err := json.Unmarshal(rec.Body.Bytes(), &assembled); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if assembled.Text != "first\n\nsecond" || assembled.TokensUsed != 20 || assembled.TokenBudget != 20 || len(assembled.Events) != 2 {
+		t.Fatalf("unexpected context: %+v", assembled)
+	}
+	if len(assembled.Dropped) != 1 || assembled.Dropped[0].EventID != "evt_3" || assembled.Dropped[0].Reason != memory.DropTokenBudget {
+		t.Fatalf("expected evt_3 dropped for budget, got %+v", assembled.Dropped)
+	}
+}
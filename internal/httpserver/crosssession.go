@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxRetrieveSessionIDs = 256
+
+// crossSessionRetrieveRequest searches the sessions of one tenant by
+// similarity. session_ids and session_labels narrow the search; without
+// either every session of the tenant is searched.
+type crossSessionRetrieveRequest struct {
+	TenantID       string            `json:"tenant_id" binding:"required"`
+	SessionIDs     []string          `json:"session_ids"`
+	SessionLabels  map[string]string `json:"session_labels"`
+	QueryEmbedding []float32         `json:"query_embedding"`
+	Metric         string            `json:"metric"`
+	TopK           int               `json:"top_k"`
+	BufferBefore   int               `json:"buffer_before"`
+	BufferAfter    int               `json:"buffer_after"`
+	Recency        *recencyRequest   `json:"recency"`
+}
+
+type crossSessionRetrieveResponse struct {
+	Sessions []memory.SessionResults `json:"sessions"`
+}
+
+func (h eventsHandler) retrieveAcrossSessions(c *gin.Context) {
+	var req crossSessionRetrieveRequest
+	if err := bindJSONWithLimit(c, &req, maxRetrieveBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	if len(req.QueryEmbedding) == 0 {
+		writeError(c, http.StatusBadRequest, "query_embedding is required")
+		return
+	}
+	if req.TopK > maxRetrieveTopK {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("top_k must be at most %d", maxRetrieveTopK))
+		return
+	}
+	if len(req.SessionIDs) > maxRetrieveSessionIDs {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("session_ids must contain at most %d items", maxRetrieveSessionIDs))
+		return
+	}
+	for _, sessionID := range req.SessionIDs {
+		if strings.TrimSpace(sessionID) == "" {
+			writeError(c, http.StatusBadRequest, "session_ids must not contain empty values")
+			return
+		}
+	}
+
+	metric := memory.SimilarityCosine
+	if req.Metric != "" {
+		metric = memory.SimilarityMetric(req.Metric)
+	}
+	recency, err := req.Recency.recency()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sessions, err := h.store.RetrieveAcrossSessions(c.Request.Context(), memory.CrossSessionQuery{
+		TenantID:       req.TenantID,
+		SessionIDs:     req.SessionIDs,
+		Labels:         req.SessionLabels,
+		QueryEmbedding: req.QueryEmbedding,
+		Metric:         metric,
+		TopK:           req.TopK,
+		BufferBefore:   req.BufferBefore,
+		BufferAfter:    req.BufferAfter,
+		Recency:        recency,
+	})
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, crossSessionRetrieveResponse{Sessions: sessions})
+}
@@ -0,0 +1,68 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetrieveAcrossSessionsEndpoint(t *testing.T) {
+	router := newTestRouter(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "event in session_1", method: http.MethodPost, path: "/v1/events", body: `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z","embeddings":[[1,0]]}`, status: http.StatusCreated},
+		{name: "event in session_2", method: http.MethodPost, path: "/v1/events", body: `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_2","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z","embeddings":[[0,1]]}`, status: http.StatusCreated},
+		{name: "invalid label", method: http.MethodPut, path: "/v1/sessions/session_2/labels", body: `{"tenant_id":"tenant_1","labels":{"":"u1"}}`, status: http.StatusBadRequest},
+		{name: "label session_2", method: http.MethodPut, path: "/v1/sessions/session_2/labels", body: `{"tenant_id":"tenant_1","labels":{"user":"u1"}}`, status: http.StatusOK},
+		{name: "missing query", method: http.MethodPost, path: "/v1/retrieve/sessions", body: `{"tenant_id":"tenant_1","top_k":1}`, status: http.StatusBadRequest},
+		{name: "empty session id", method: http.MethodPost, path: "/v1/retrieve/sessions", body: `{"tenant_id":"tenant_1","session_ids":[""],"query_embedding":[1,0],"top_k":1}`, status: http.StatusBadRequest},
+		{name: "no top_k", method: http.MethodPost, path: "/v1/retrieve/sessions", body: `{"tenant_id":"tenant_1","query_embedding":[1,0]}`, status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	for _, tc := range []struct {
+		body     string
+		sessions []string
+	}{
+		{body: `{"tenant_id":"tenant_1","query_embedding":[1,0],"top_k":2}`, sessions: []string{"session_1", "session_2"}},
+		{body: `{"tenant_id":"tenant_1","session_labels":{"user":"u1"},"query_embedding":[1,0],"top_k":2}`, sessions: []string{"session_2"}},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/retrieve/sessions", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp crossSessionRetrieveResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if len(resp.Sessions) != len(tc.sessions) {
+			t.Fatalf("expected sessions %v, got %+v", tc.sessions, resp.Sessions)
+		}
+		for i, sessionID := range tc.sessions {
+			if resp.Sessions[i].SessionID != sessionID || len(resp.Sessions[i].Events) != 1 {
+				t.Fatalf("expected sessions %v, got %+v", tc.sessions, resp.Sessions)
+			}
+		}
+	}
+}
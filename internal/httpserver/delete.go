@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type deleteEventRequest struct {
+	TenantID  string `form:"tenant_id" binding:"required"`
+	SessionID string `form:"session_id" binding:"required"`
+}
+
+type deleteSessionRequest struct {
+	TenantID string `form:"tenant_id" binding:"required"`
+}
+
+// deletionResponse reports what was removed, so callers can record the
+// erasure they asked for.
+type deletionResponse struct {
+	DeletedSessions int `json:"deleted_sessions"`
+	DeletedEvents   int `json:"deleted_events"`
+}
+
+func (h eventsHandler) deleteEvent(c *gin.Context) {
+	var req deleteEventRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id and session_id are required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	deletion, err := h.store.DeleteEvent(c.Request.Context(), req.TenantID, req.SessionID, c.Param("event_id"))
+	writeDeletion(c, deletion, err, "event not found")
+}
+
+func (h eventsHandler) deleteSession(c *gin.Context) {
+	var req deleteSessionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	deletion, err := h.store.DeleteSession(c.Request.Context(), req.TenantID, c.Param("session_id"))
+	writeDeletion(c, deletion, err, "session not found")
+}
+
+// purgeTenant removes every session of a tenant. Purging a tenant with
+// nothing stored succeeds, so retries are safe.
+func (h eventsHandler) purgeTenant(c *gin.Context) {
+	tenantID := c.Param("tenant_id")
+	if !admitTenant(c, h.limiter, tenantID) {
+		return
+	}
+
+	deletion, err := h.store.PurgeTenant(c.Request.Context(), tenantID)
+	writeDeletion(c, deletion, err, "")
+}
+
+// writeDeletion responds 404 with notFound when nothing was deleted, unless
+// notFound is empty.
+func writeDeletion(c *gin.Context, deletion memory.Deletion, err error, notFound string) {
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	if deletion == (memory.Deletion{}) && notFound != "" {
+		writeError(c, http.StatusNotFound, notFound)
+		return
+	}
+	c.JSON(http.StatusOK, deletionResponse{
+		DeletedSessions: deletion.Sessions,
+		DeletedEvents:   deletion.Events,
+	})
+}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestDeleteEndpoints(t *testing.T) {
+	store := memory.NewStore()
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	for _, event := range []struct{ eventID, tenantID, sessionID string }{
+		{"evt_1", "tenant_1", "session_1"},
+		{"evt_2", "tenant_1", "session_1"},
+		{"evt_1", "tenant_1", "session_2"},
+		{"evt_1", "tenant_2", "session_1"},
+	} {
+		e, err := memory.NewEvent(event.eventID, event.tenantID, event.sessionID, 0, 1, now)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		if err := store.Append(context.Background(), e); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		status  int
+		deleted deletionResponse
+	}{
+		{name: "event", path: "/v1/events/evt_1?tenant_id=tenant_1&session_id=session_1", status: http.StatusOK, deleted: deletionResponse{DeletedEvents: 1}},
+		{name: "event again", path: "/v1/events/evt_1?tenant_id=tenant_1&session_id=session_1", status: http.StatusNotFound},
+		{name: "event missing session_id", path: "/v1/events/evt_2?tenant_id=tenant_1", status: http.StatusBadRequest},
+		{name: "session", path: "/v1/sessions/session_2?tenant_id=tenant_1", status: http.StatusOK, deleted: deletionResponse{DeletedSessions: 1, DeletedEvents: 1}},
+		{name: "session again", path: "/v1/sessions/session_2?tenant_id=tenant_1", status: http.StatusNotFound},
+		{name: "tenant", path: "/v1/tenants/tenant_1", status: http.StatusOK, deleted: deletionResponse{DeletedSessions: 1, DeletedEvents: 1}},
+		{name: "tenant again", path: "/v1/tenants/tenant_1", status: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var got deletionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: decode response: %v", tc.name, err)
+		}
+		if got != tc.deleted {
+			t.Fatalf("%s: expected %+v, got %+v", tc.name, tc.deleted, got)
+		}
+	}
+
+	if got := store.ListBySession("tenant_2", "session_1"); len(got) != 1 {
+		t.Fatalf("expected other tenant untouched, got %#v", got)
+	}
+}
+
+func TestTenantPurgeRequiresAdminScope(t *testing.T) {
+	router := newAuthTestRouter(t)
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{name: "write scope", path: "/v1/tenants/tenant_1", header: "Bearer write-token", status: http.StatusForbidden},
+		{name: "admin other tenant", path: "/v1/tenants/tenant_1", header: "Bearer admin-token", status: http.StatusForbidden},
+		{name: "admin own tenant", path: "/v1/tenants/tenant_2", header: "Bearer admin-token", status: http.StatusOK},
+		{name: "write deletes session", path: "/v1/sessions/session_1?tenant_id=tenant_1", header: "Bearer write-token", status: http.StatusNotFound},
+		{name: "read cannot delete", path: "/v1/sessions/session_1?tenant_id=tenant_1", header: "Bearer read-token", status: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+		req.Header.Set("Authorization", tc.header)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,21 +14,44 @@ import (
 )
 
 type eventsHandler struct {
-	store *memory.Store
+	store   memory.Store
+	limiter *rateLimiter
+}
+
+type getEventRequest struct {
+	TenantID  string `form:"tenant_id" binding:"required"`
+	SessionID string `form:"session_id" binding:"required"`
 }
 
 type listEventsRequest struct {
 	TenantID  string `form:"tenant_id" binding:"required"`
 	SessionID string `form:"session_id" binding:"required"`
+	Limit     string `form:"limit"`
+	Cursor    string `form:"cursor"`
+	// Order is "asc" (default) or "desc" over the session order.
+	Order string `form:"order"`
+	// FromToken and ToToken keep events overlapping [from_token, to_token).
+	FromToken string `form:"from_token"`
+	ToToken   string `form:"to_token"`
+	// CreatedAfter and CreatedBefore are RFC 3339 bounds on created_at.
+	CreatedAfter  string `form:"created_after"`
+	CreatedBefore string `form:"created_before"`
 }
 
+// nextCursorHeader carries the cursor for the next page of GET /v1/events;
+// it is absent on the last page.
+const nextCursorHeader = "X-Next-Cursor"
+
 const maxJSONBodyBytes int64 = 1 << 20
 const maxCreateEventBodyBytes int64 = maxJSONBodyBytes
 const maxSegmentBodyBytes int64 = maxJSONBodyBytes
 const maxRetrieveBodyBytes int64 = maxJSONBodyBytes
 const maxSegmentSurpriseValues = 8192
+const maxRefinementTokens = 2048
 const maxRetrieveAnchorEventIDs = 256
 const maxRetrieveTopK = maxRetrieveAnchorEventIDs
+const defaultListLimit = 100
+const maxListLimit = 1000
 
 var (
 	errRequestBodyTooLarge = errors.New("request body too large")
@@ -35,36 +59,103 @@ var (
 )
 
 type segmentRequest struct {
-	TenantID       string    `json:"tenant_id" binding:"required"`
-	SessionID      string    `json:"session_id" binding:"required"`
-	StartToken     int       `json:"start_token"`
-	Surprise       []float64 `json:"surprise"`
-	Threshold      float64   `json:"threshold"`
-	MinBoundaryGap int       `json:"min_boundary_gap"`
-	CreatedAt      time.Time `json:"created_at"`
-	EventIDPrefix  string    `json:"event_id_prefix"`
+	TenantID   string    `json:"tenant_id" binding:"required"`
+	SessionID  string    `json:"session_id" binding:"required"`
+	StartToken int       `json:"start_token"`
+	Surprise   []float64 `json:"surprise"`
+	// Logprobs derives Surprise server-side from per-token log-probabilities
+	// in the OpenAI-compatible logprobs.content shape; set at most one of the two.
+	Logprobs              []tokenLogprob `json:"logprobs"`
+	SurpriseNormalization string         `json:"surprise_normalization"`
+	// ThresholdMode is "fixed" (default), using Threshold, or "adaptive",
+	// using mean + ThresholdGamma*std over the last ThresholdWindow values.
+	ThresholdMode   string    `json:"threshold_mode"`
+	Threshold       float64   `json:"threshold"`
+	ThresholdWindow int       `json:"threshold_window"`
+	ThresholdGamma  float64   `json:"threshold_gamma"`
+	MinBoundaryGap  int       `json:"min_boundary_gap"`
+	CreatedAt       time.Time `json:"created_at"`
+	EventIDPrefix   string    `json:"event_id_prefix"`
+	// Refinement optionally moves surprise boundaries using token similarity.
+	Refinement *segmentRefinement `json:"refinement,omitempty"`
+	// Stream continues the session's pending tail across calls; Flush also
+	// closes it once this slice is processed.
+	Stream bool `json:"stream"`
+	Flush  bool `json:"flush"`
+}
+
+// tokenLogprob mirrors one entry of an OpenAI-compatible logprobs.content
+// array, so model output can be forwarded verbatim.
+type tokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     *float64     `json:"logprob"`
+	Bytes       []int        `json:"bytes"`
+	TopLogprobs []topLogprob `json:"top_logprobs"`
+}
+
+type topLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
+}
+
+// flushSegmentRequest closes a session's pending stream tail as one event.
+type flushSegmentRequest struct {
+	TenantID      string    `json:"tenant_id" binding:"required"`
+	SessionID     string    `json:"session_id" binding:"required"`
+	CreatedAt     time.Time `json:"created_at"`
+	EventIDPrefix string    `json:"event_id_prefix"`
+}
+
+// segmentRefinement carries the token graph as either an explicit similarity
+// matrix or per-token key vectors; exactly one must be set.
+type segmentRefinement struct {
+	Objective  string      `json:"objective"`
+	Radius     int         `json:"radius"`
+	Similarity [][]float64 `json:"similarity"`
+	Keys       [][]float64 `json:"keys"`
 }
 
 type segmentResponse struct {
-	Boundaries []int          `json:"boundaries"`
+	RawBoundaries []int `json:"raw_boundaries"`
+	Boundaries    []int `json:"boundaries"`
+	// Surprise echoes the scores derived from logprobs; omitted when the
+	// client sent surprise directly.
+	Surprise []float64 `json:"surprise,omitempty"`
+	// Thresholds holds the threshold each raw boundary's surprise peak exceeded.
+	Thresholds []float64      `json:"thresholds"`
 	Events     []memory.Event `json:"events"`
+	// Pending is the token range a streaming call left open, if any.
+	Pending *memory.PendingTail `json:"pending,omitempty"`
 }
 
+const (
+	thresholdModeFixed    = "fixed"
+	thresholdModeAdaptive = "adaptive"
+)
+
+// retrieveRequest selects anchors either explicitly via EventIDs or by
+// similarity to QueryEmbedding; exactly one of the two must be set.
 type retrieveRequest struct {
-	TenantID     string   `json:"tenant_id" binding:"required"`
-	SessionID    string   `json:"session_id" binding:"required"`
-	EventIDs     []string `json:"event_ids"`
-	TopK         int      `json:"top_k"`
-	BufferBefore int      `json:"buffer_before"`
-	BufferAfter  int      `json:"buffer_after"`
+	TenantID       string    `json:"tenant_id" binding:"required"`
+	SessionID      string    `json:"session_id" binding:"required"`
+	EventIDs       []string  `json:"event_ids"`
+	QueryText      string    `json:"query_text"`
+	QueryEmbedding []float32 `json:"query_embedding"`
+	Metric         string    `json:"metric"`
+	TopK           int       `json:"top_k"`
+	BufferBefore   int       `json:"buffer_before"`
+	BufferAfter    int       `json:"buffer_after"`
+	// Recency weights similarity ranking, so it needs query_embedding.
+	Recency *recencyRequest `json:"recency"`
 }
 
 type retrieveResponse struct {
-	Events []memory.Event `json:"events"`
+	Events []memory.RetrievedEvent `json:"events"`
 }
 
-func newEventsHandler(store *memory.Store) eventsHandler {
-	return eventsHandler{store: store}
+func newEventsHandler(store memory.Store, limiter *rateLimiter) eventsHandler {
+	return eventsHandler{store: store, limiter: limiter}
 }
 
 func (h eventsHandler) create(c *gin.Context) {
@@ -74,17 +165,18 @@ func (h eventsHandler) create(c *gin.Context) {
 		return
 	}
 
+	if !admitTenant(c, h.limiter, event.TenantID) {
+		return
+	}
 	event.CreatedAt = event.CreatedAt.UTC()
 
-	if err := h.store.Append(event); err != nil {
-		if errors.Is(err, memory.ErrDuplicateEventID) {
-			writeError(c, http.StatusConflict, err.Error())
-			return
-		}
-		writeError(c, http.StatusBadRequest, err.Error())
+	if err := h.store.Append(c.Request.Context(), event); err != nil {
+		writeStoreError(c, err)
 		return
 	}
 
+	event.Version = memory.FirstEventVersion
+	setETag(c, event.Version)
 	c.JSON(http.StatusCreated, event)
 }
 
@@ -94,9 +186,102 @@ func (h eventsHandler) list(c *gin.Context) {
 		writeError(c, http.StatusBadRequest, "tenant_id and session_id are required")
 		return
 	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	query, err := req.query()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := h.store.ListEvents(c.Request.Context(), query)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Events)
+}
+
+func (h eventsHandler) get(c *gin.Context) {
+	var req getEventRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id and session_id are required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
 
-	events := h.store.ListBySession(req.TenantID, req.SessionID)
-	c.JSON(http.StatusOK, events)
+	event, ok := h.store.Get(req.TenantID, req.SessionID, c.Param("event_id"))
+	if !ok {
+		writeError(c, http.StatusNotFound, "event not found")
+		return
+	}
+	setETag(c, event.Version)
+	c.JSON(http.StatusOK, event)
+}
+
+func (req listEventsRequest) query() (memory.ListQuery, error) {
+	query := memory.ListQuery{
+		TenantID:  req.TenantID,
+		SessionID: req.SessionID,
+		Limit:     defaultListLimit,
+		Cursor:    req.Cursor,
+	}
+
+	switch req.Order {
+	case "", "asc":
+	case "desc":
+		query.Reverse = true
+	default:
+		return memory.ListQuery{}, errors.New(`order must be "asc" or "desc"`)
+	}
+
+	var err error
+	if req.Limit != "" {
+		if query.Limit, err = strconv.Atoi(req.Limit); err != nil || query.Limit < 1 || query.Limit > maxListLimit {
+			return memory.ListQuery{}, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+	}
+	if query.FromToken, err = parseOptionalInt("from_token", req.FromToken); err != nil {
+		return memory.ListQuery{}, err
+	}
+	if query.ToToken, err = parseOptionalInt("to_token", req.ToToken); err != nil {
+		return memory.ListQuery{}, err
+	}
+	if query.CreatedAfter, err = parseOptionalTime("created_after", req.CreatedAfter); err != nil {
+		return memory.ListQuery{}, err
+	}
+	if query.CreatedBefore, err = parseOptionalTime("created_before", req.CreatedBefore); err != nil {
+		return memory.ListQuery{}, err
+	}
+	return query, nil
+}
+
+func parseOptionalInt(field, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", field)
+	}
+	return n, nil
+}
+
+func parseOptionalTime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", field)
+	}
+	return t.UTC(), nil
 }
 
 func (h eventsHandler) segment(c *gin.Context) {
@@ -106,8 +291,11 @@ func (h eventsHandler) segment(c *gin.Context) {
 		return
 	}
 
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
 	req.CreatedAt = req.CreatedAt.UTC()
-	if len(req.Surprise) > maxSegmentSurpriseValues {
+	if len(req.Surprise) > maxSegmentSurpriseValues || len(req.Logprobs) > maxSegmentSurpriseValues {
 		writeError(
 			c,
 			http.StatusBadRequest,
@@ -116,47 +304,180 @@ func (h eventsHandler) segment(c *gin.Context) {
 		return
 	}
 
-	events, boundaries, err := memory.BuildEventsFromSurprise(
-		req.TenantID,
-		req.SessionID,
-		req.StartToken,
-		req.Surprise,
-		req.Threshold,
-		req.MinBoundaryGap,
-		req.CreatedAt,
-		req.EventIDPrefix,
-	)
-	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+	var derivedSurprise []float64
+	if len(req.Logprobs) > 0 {
+		if len(req.Surprise) > 0 {
+			writeError(c, http.StatusBadRequest, "surprise and logprobs are mutually exclusive")
+			return
+		}
+		surprise, err := surpriseFromLogprobs(req.Logprobs, req.SurpriseNormalization)
+		if err != nil {
+			writeError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Surprise = surprise
+		derivedSurprise = surprise
+	} else if req.SurpriseNormalization != "" {
+		writeError(c, http.StatusBadRequest, "surprise_normalization requires logprobs")
 		return
 	}
 
-	if err := h.store.AppendMany(events); err != nil {
-		if errors.Is(err, memory.ErrDuplicateEventID) {
-			writeError(c, http.StatusConflict, err.Error())
+	segmentReq := memory.SegmentRequest{
+		TenantID:       req.TenantID,
+		SessionID:      req.SessionID,
+		StartToken:     req.StartToken,
+		Surprise:       req.Surprise,
+		Threshold:      req.Threshold,
+		MinBoundaryGap: req.MinBoundaryGap,
+		CreatedAt:      req.CreatedAt,
+		EventIDPrefix:  req.EventIDPrefix,
+		Stream:         req.Stream,
+		Flush:          req.Flush,
+	}
+	switch req.ThresholdMode {
+	case "", thresholdModeFixed:
+	case thresholdModeAdaptive:
+		segmentReq.Adaptive = &memory.AdaptiveThreshold{
+			Window: req.ThresholdWindow,
+			Gamma:  req.ThresholdGamma,
+		}
+	default:
+		writeError(c, http.StatusBadRequest, "threshold_mode must be one of: fixed, adaptive")
+		return
+	}
+	if req.Refinement != nil {
+		refinement, err := buildRefinement(*req.Refinement, len(req.Surprise))
+		if err != nil {
+			writeError(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeError(c, http.StatusBadRequest, err.Error())
+		segmentReq.Refinement = &refinement
+	}
+
+	h.writeSegmentation(c, segmentReq, derivedSurprise)
+}
+
+func surpriseFromLogprobs(tokens []tokenLogprob, normalization string) ([]float64, error) {
+	logprobs := make([]memory.TokenLogprob, len(tokens))
+	for i, token := range tokens {
+		if token.Logprob == nil {
+			return nil, fmt.Errorf("logprobs[%d].logprob is required", i)
+		}
+		logprobs[i].Logprob = *token.Logprob
+		if len(token.TopLogprobs) > 0 {
+			logprobs[i].TopLogprobs = make([]float64, len(token.TopLogprobs))
+			for j, alternative := range token.TopLogprobs {
+				logprobs[i].TopLogprobs[j] = alternative.Logprob
+			}
+		}
+	}
+
+	if normalization == "" {
+		normalization = string(memory.SurpriseNLL)
+	}
+	return memory.SurpriseFromLogprobs(logprobs, memory.SurpriseNormalization(normalization))
+}
+
+func (h eventsHandler) flushSegment(c *gin.Context) {
+	var req flushSegmentRequest
+	if err := bindJSONWithLimit(c, &req, maxSegmentBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	// A flush carries no new surprise, so the threshold settings are unused
+	// beyond passing validation.
+	h.writeSegmentation(c, memory.SegmentRequest{
+		TenantID:       req.TenantID,
+		SessionID:      req.SessionID,
+		MinBoundaryGap: 1,
+		CreatedAt:      req.CreatedAt.UTC(),
+		EventIDPrefix:  req.EventIDPrefix,
+		Stream:         true,
+		Flush:          true,
+	}, nil)
+}
+
+func (h eventsHandler) writeSegmentation(c *gin.Context, req memory.SegmentRequest, derivedSurprise []float64) {
+	segmentation, err := h.store.Segment(c.Request.Context(), req)
+	if err != nil {
+		writeStoreError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, segmentResponse{
-		Boundaries: boundaries,
-		Events:     events,
+		RawBoundaries: segmentation.RawBoundaries,
+		Boundaries:    segmentation.Boundaries,
+		Surprise:      derivedSurprise,
+		Thresholds:    segmentation.Thresholds,
+		Events:        segmentation.Events,
+		Pending:       segmentation.Pending,
 	})
 }
 
+func buildRefinement(req segmentRefinement, tokens int) (memory.Refinement, error) {
+	if tokens > maxRefinementTokens {
+		return memory.Refinement{}, fmt.Errorf("refinement supports at most %d surprise values", maxRefinementTokens)
+	}
+	if (req.Similarity == nil) == (req.Keys == nil) {
+		return memory.Refinement{}, errors.New("refinement requires exactly one of similarity or keys")
+	}
+
+	objective := memory.RefinementModularity
+	if req.Objective != "" {
+		objective = memory.RefinementObjective(req.Objective)
+	}
+
+	var (
+		similarity memory.TokenSimilarity
+		err        error
+	)
+	if req.Similarity != nil {
+		similarity, err = memory.NewSimilarityMatrix(req.Similarity)
+	} else {
+		similarity, err = memory.NewKeySimilarity(req.Keys)
+	}
+	if err != nil {
+		return memory.Refinement{}, err
+	}
+
+	return memory.Refinement{
+		Objective:  objective,
+		Radius:     req.Radius,
+		Similarity: similarity,
+	}, nil
+}
+
 func (h eventsHandler) retrieve(c *gin.Context) {
 	var req retrieveRequest
 	if err := bindJSONWithLimit(c, &req, maxRetrieveBodyBytes); err != nil {
 		writeError(c, statusForBindError(err), err.Error())
 		return
 	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	if req.QueryText != "" {
+		h.retrieveByText(c, req)
+		return
+	}
+	if len(req.QueryEmbedding) > 0 {
+		h.retrieveBySimilarity(c, req)
+		return
+	}
 
 	if len(req.EventIDs) == 0 {
 		writeError(c, http.StatusBadRequest, "event_ids must contain at least one event id")
 		return
 	}
+	if req.Recency != nil {
+		writeError(c, http.StatusBadRequest, "recency requires query_embedding")
+		return
+	}
 
 	if len(req.EventIDs) > maxRetrieveAnchorEventIDs {
 		writeError(
@@ -178,6 +499,7 @@ func (h eventsHandler) retrieve(c *gin.Context) {
 	}
 
 	events, err := h.store.RetrieveByAnchors(
+		c.Request.Context(),
 		req.TenantID,
 		req.SessionID,
 		req.EventIDs,
@@ -185,19 +507,124 @@ func (h eventsHandler) retrieve(c *gin.Context) {
 		req.BufferBefore,
 		req.BufferAfter,
 	)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, retrieveResponse{Events: events})
+}
+
+func (h eventsHandler) retrieveBySimilarity(c *gin.Context, req retrieveRequest) {
+	if len(req.EventIDs) > 0 {
+		writeError(c, http.StatusBadRequest, "event_ids and query_embedding are mutually exclusive")
+		return
+	}
+	if req.TopK > maxRetrieveTopK {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("top_k must be at most %d", maxRetrieveTopK))
+		return
+	}
+
+	metric := memory.SimilarityCosine
+	if req.Metric != "" {
+		metric = memory.SimilarityMetric(req.Metric)
+	}
+	recency, err := req.Recency.recency()
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := h.store.RetrieveBySimilarity(c.Request.Context(), memory.SimilarityQuery{
+		TenantID:       req.TenantID,
+		SessionID:      req.SessionID,
+		QueryEmbedding: req.QueryEmbedding,
+		Metric:         metric,
+		TopK:           req.TopK,
+		BufferBefore:   req.BufferBefore,
+		BufferAfter:    req.BufferAfter,
+		Recency:        recency,
+	})
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, retrieveResponse{Events: events})
+}
+
+// retrieveByText ranks by BM25 over payload text, fused with similarity when
+// query_embedding is set too.
+func (h eventsHandler) retrieveByText(c *gin.Context, req retrieveRequest) {
+	if len(req.EventIDs) > 0 {
+		writeError(c, http.StatusBadRequest, "event_ids and query_text are mutually exclusive")
+		return
+	}
+	if req.TopK > maxRetrieveTopK {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("top_k must be at most %d", maxRetrieveTopK))
+		return
+	}
+
+	metric := memory.SimilarityCosine
+	if req.Metric != "" {
+		metric = memory.SimilarityMetric(req.Metric)
+	}
+	recency, err := req.Recency.recency()
 	if err != nil {
 		writeError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	events, err := h.store.RetrieveByText(c.Request.Context(), memory.TextQuery{
+		TenantID:       req.TenantID,
+		SessionID:      req.SessionID,
+		Text:           req.QueryText,
+		QueryEmbedding: req.QueryEmbedding,
+		Metric:         metric,
+		TopK:           req.TopK,
+		BufferBefore:   req.BufferBefore,
+		BufferAfter:    req.BufferAfter,
+		Recency:        recency,
+	})
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, retrieveResponse{Events: events})
 }
 
+// writeError writes an error body carrying the request ID, so a caller can
+// quote it when reporting a problem.
 func writeError(c *gin.Context, status int, message string) {
-	c.JSON(status, gin.H{"error": message})
+	c.JSON(status, gin.H{"error": message, "request_id": requestID(c)})
+}
+
+// writeStoreError maps a rejected store operation to its HTTP status.
+func writeStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, memory.ErrDuplicateEventID), errors.Is(err, memory.ErrSpanConflict):
+		writeError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, memory.ErrQuotaExceeded):
+		writeError(c, http.StatusInsufficientStorage, err.Error())
+	case errors.Is(err, memory.ErrSessionNotFound), errors.Is(err, memory.ErrEventNotFound):
+		writeError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, memory.ErrVersionConflict):
+		writeError(c, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, memory.ErrInvalidInput):
+		writeError(c, http.StatusBadRequest, err.Error())
+	default:
+		// Anything else is a storage failure such as a write-ahead log error;
+		// logRequests records it and the client gets no internals.
+		_ = c.Error(err)
+		writeError(c, http.StatusInternalServerError, "internal server error")
+	}
 }
 
 func bindJSONWithLimit(c *gin.Context, dst any, maxBodyBytes int64) error {
+	_, span := tracer.Start(c.Request.Context(), "httpserver.DecodeJSON")
+	defer span.End()
+
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
 	if err := c.ShouldBindJSON(dst); err != nil {
 		var maxBytesErr *http.MaxBytesError
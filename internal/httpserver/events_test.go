@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -36,6 +37,59 @@ func TestCreateEventSuccess(t *testing.T) {
 	}
 }
 
+func TestCreateEventWithPayloadIsReturnedFromRetrieve(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":3,"created_at":"2026-02-10T12:00:00Z","payload":{"text":"hello there world","token_ids":[15339,1070,1917],"role":"user","speaker":"alice"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+
+	body = `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_1"],"top_k":1,"buffer_before":0,"buffer_after":0}`
+	req = httptest.NewRequest(http.MethodPost, "/v1/retrieve", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var resp struct {
+		Events []memory.Event `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].Payload == nil {
+		t.Fatalf("expected one event with payload, got %#v", resp.Events)
+	}
+	payload := resp.Events[0].Payload
+	if payload.Text != "hello there world" || len(payload.TokenIDs) != 3 || payload.Role != "user" || payload.Speaker != "alice" {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+}
+
+func TestCreateEventRejectsMismatchedTokenIDs(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z","payload":{"token_ids":[1,2,3]}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
 func TestCreateEventRejectsDuplicate(t *testing.T) {
 	router := newTestRouter(t)
 
@@ -135,10 +189,10 @@ func TestListEventsSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("new event: %v", err)
 	}
-	if err := store.Append(first); err != nil {
+	if err := store.Append(context.Background(), first); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
-	if err := store.Append(second); err != nil {
+	if err := store.Append(context.Background(), second); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
 
@@ -189,6 +243,64 @@ func TestListEventsRejectsMissingQuery(t *testing.T) {
 	}
 }
 
+func TestListEventsPaginates(t *testing.T) {
+	store := memory.NewStore()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		event, err := memory.NewEvent(fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, i*10+10, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	list := func(query string) ([]string, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/events?tenant_id=tenant_1&session_id=session_1&"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", query, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var events []memory.Event
+		if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.EventID
+		}
+		return ids, rec.Header().Get(nextCursorHeader)
+	}
+
+	var got []string
+	query := "order=desc&limit=2&from_token=5&created_before=2026-02-10T12:04:00Z"
+	for {
+		ids, cursor := list(query)
+		got = append(got, ids...)
+		if cursor == "" {
+			break
+		}
+		query = "order=desc&limit=2&from_token=5&created_before=2026-02-10T12:04:00Z&cursor=" + cursor
+	}
+	if want := "evt_3,evt_2,evt_1,evt_0"; strings.Join(got, ",") != want {
+		t.Fatalf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+
+	for _, query := range []string{"limit=0", "limit=1001", "order=newest", "from_token=x", "to_token=5&from_token=5", "created_after=yesterday", "cursor=bogus"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/events?tenant_id=tenant_1&session_id=session_1&"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
 func TestSegmentSuccess(t *testing.T) {
 	store := memory.NewStore()
 	router, err := NewRouter("test", store)
@@ -237,6 +349,276 @@ func TestSegmentSuccess(t *testing.T) {
 	}
 }
 
+func TestSegmentWithRefinementReportsRawAndRefinedBoundaries(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"start_token":0,
+		"surprise":[0.1,1.4,0.1,0.1,0.1,0.1],
+		"threshold":0.8,
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:00Z",
+		"event_id_prefix":"seg",
+		"refinement":{"objective":"conductance","keys":[[1,0],[1,0],[1,0],[1,0.1],[0,1],[0.1,1]]}
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		RawBoundaries []int          `json:"raw_boundaries"`
+		Boundaries    []int          `json:"boundaries"`
+		Events        []memory.Event `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.RawBoundaries) != 1 || resp.RawBoundaries[0] != 2 {
+		t.Fatalf("unexpected raw boundaries: %#v", resp.RawBoundaries)
+	}
+	if len(resp.Boundaries) != 1 || resp.Boundaries[0] != 4 {
+		t.Fatalf("unexpected refined boundaries: %#v", resp.Boundaries)
+	}
+}
+
+func TestSegmentRejectsRefinementWithBothInputs(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"start_token":0,
+		"surprise":[0.1,1.4,0.1],
+		"threshold":0.8,
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:00Z",
+		"event_id_prefix":"seg",
+		"refinement":{"similarity":[[1,0,0],[0,1,0],[0,0,1]],"keys":[[1],[1],[1]]}
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestSegmentAdaptiveThresholdUsesSessionHistory(t *testing.T) {
+	router := newTestRouter(t)
+
+	post := func(startToken int, surprise, prefix string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{
+			"tenant_id":"tenant_1",
+			"session_id":"session_1",
+			"start_token":%d,
+			"surprise":%s,
+			"threshold_mode":"adaptive",
+			"threshold_window":4,
+			"threshold_gamma":1,
+			"min_boundary_gap":1,
+			"created_at":"2026-02-14T12:00:00Z",
+			"event_id_prefix":%q
+		}`, startToken, surprise, prefix)
+		req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(0, "[1,1.1,1,1.2]", "a"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec := post(4, "[1,1.1,1,3,1]", "b")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Boundaries []int     `json:"boundaries"`
+		Thresholds []float64 `json:"thresholds"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.Boundaries) != 1 || resp.Boundaries[0] != 8 {
+		t.Fatalf("unexpected boundaries: %#v", resp.Boundaries)
+	}
+	if len(resp.Thresholds) != 1 || resp.Thresholds[0] <= 1 || resp.Thresholds[0] >= 3 {
+		t.Fatalf("unexpected thresholds: %#v", resp.Thresholds)
+	}
+}
+
+func TestSegmentStreamDefersBoundaryAtChunkEdgeUntilFlush(t *testing.T) {
+	router := newTestRouter(t)
+
+	post := func(path, body string) segmentResponse {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d from %s, got %d: %s", http.StatusCreated, path, rec.Code, rec.Body.String())
+		}
+
+		var resp segmentResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return resp
+	}
+
+	first := post("/v1/segment", `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"start_token":0,
+		"surprise":[0.1,0.2,1.2],
+		"threshold":0.8,
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:00Z",
+		"event_id_prefix":"a",
+		"stream":true
+	}`)
+	if len(first.Events) != 0 || first.Pending == nil || first.Pending.EndTokenExclusive != 3 {
+		t.Fatalf("expected an open tail and no events, got %#v", first)
+	}
+
+	second := post("/v1/segment", `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"start_token":3,
+		"surprise":[0.1,0.1],
+		"threshold":0.8,
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:01Z",
+		"event_id_prefix":"b",
+		"stream":true
+	}`)
+	if len(second.Boundaries) != 1 || second.Boundaries[0] != 3 || len(second.Events) != 1 {
+		t.Fatalf("expected boundary 3 to close one event, got %#v", second)
+	}
+
+	flushed := post("/v1/segment/flush", `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"created_at":"2026-02-14T12:00:02Z",
+		"event_id_prefix":"c"
+	}`)
+	if flushed.Pending != nil || len(flushed.Events) != 1 || flushed.Events[0].StartToken != 3 || flushed.Events[0].EndTokenExclusive != 5 {
+		t.Fatalf("expected flush to close tokens [3, 5), got %#v", flushed)
+	}
+}
+
+func TestSegmentDerivesSurpriseFromLogprobs(t *testing.T) {
+	router := newTestRouter(t)
+
+	// logprobs.content as returned by an OpenAI-compatible API.
+	body := `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"start_token":0,
+		"logprobs":[
+			{"token":"The","logprob":-0.1,"bytes":[84,104,101],"top_logprobs":[{"token":"The","logprob":-0.1,"bytes":[84,104,101]}]},
+			{"token":" cat","logprob":-0.2,"bytes":[32,99,97,116],"top_logprobs":[]},
+			{"token":" !","logprob":-3.0,"bytes":[32,33],"top_logprobs":[]},
+			{"token":" Then","logprob":-0.3,"bytes":null,"top_logprobs":[]}
+		],
+		"threshold":1.0,
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:00Z",
+		"event_id_prefix":"seg"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var resp segmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	wantSurprise := []float64{0.1, 0.2, 3.0, 0.3}
+	if len(resp.Surprise) != len(wantSurprise) {
+		t.Fatalf("expected surprise %v, got %v", wantSurprise, resp.Surprise)
+	}
+	for i := range wantSurprise {
+		if resp.Surprise[i] != wantSurprise[i] {
+			t.Fatalf("expected surprise %v, got %v", wantSurprise, resp.Surprise)
+		}
+	}
+	if len(resp.Boundaries) != 1 || resp.Boundaries[0] != 3 {
+		t.Fatalf("unexpected boundaries: %#v", resp.Boundaries)
+	}
+}
+
+func TestSegmentRejectsInvalidLogprobInput(t *testing.T) {
+	router := newTestRouter(t)
+
+	cases := []struct {
+		name   string
+		fields string
+	}{
+		{name: "surprise and logprobs", fields: `"surprise":[0.1],"logprobs":[{"token":"a","logprob":-0.1}]`},
+		{name: "missing logprob", fields: `"logprobs":[{"token":"a"}]`},
+		{name: "positive logprob", fields: `"logprobs":[{"token":"a","logprob":0.5}]`},
+		{name: "normalization without logprobs", fields: `"surprise":[0.1],"surprise_normalization":"entropy"`},
+		{name: "entropy without alternatives", fields: `"logprobs":[{"token":"a","logprob":-0.1}],"surprise_normalization":"entropy"`},
+	}
+
+	for _, tc := range cases {
+		body := `{"tenant_id":"tenant_1","session_id":"session_1",` + tc.fields +
+			`,"threshold":1,"min_boundary_gap":1,"created_at":"2026-02-14T12:00:00Z","event_id_prefix":"seg"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, http.StatusBadRequest, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestSegmentRejectsUnknownThresholdMode(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{
+		"tenant_id":"tenant_1",
+		"session_id":"session_1",
+		"surprise":[0.1,1.4,0.1],
+		"threshold_mode":"quantile",
+		"min_boundary_gap":1,
+		"created_at":"2026-02-14T12:00:00Z",
+		"event_id_prefix":"seg"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/segment", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
 func TestSegmentRejectsTooManySurpriseValues(t *testing.T) {
 	router := newTestRouter(t)
 
@@ -323,7 +705,7 @@ func TestRetrieveSuccessWithBuffers(t *testing.T) {
 		if err != nil {
 			t.Fatalf("new event: %v", err)
 		}
-		if err := store.Append(event); err != nil {
+		if err := store.Append(context.Background(), event); err != nil {
 			t.Fatalf("append event: %v", err)
 		}
 	}
@@ -345,7 +727,7 @@ func TestRetrieveSuccessWithBuffers(t *testing.T) {
 	}
 
 	var resp struct {
-		Events []memory.Event `json:"events"`
+		Events []memory.RetrievedEvent `json:"events"`
 	}
 	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("unmarshal response: %v", err)
@@ -356,6 +738,124 @@ func TestRetrieveSuccessWithBuffers(t *testing.T) {
 	if resp.Events[0].EventID != "evt_2" || resp.Events[1].EventID != "evt_3" || resp.Events[2].EventID != "evt_4" {
 		t.Fatalf("unexpected events: %#v", resp.Events)
 	}
+	for i, role := range []memory.RetrievalRole{memory.RetrievalBeforeBuffer, memory.RetrievalAnchor, memory.RetrievalAfterBuffer} {
+		retrieval := resp.Events[i].Retrieval
+		if retrieval.Role != role || retrieval.Rank != 1 || len(retrieval.Anchors) != 1 || retrieval.Anchors[0] != "evt_3" {
+			t.Fatalf("expected %s of evt_3, got %+v", role, retrieval)
+		}
+	}
+}
+
+func TestRetrieveByQueryText(t *testing.T) {
+	router := newTestRouter(t)
+
+	for i, text := range []string{"deploy failed with E1234", "retrying the deploy", "all green"} {
+		body := fmt.Sprintf(`{"event_id":"evt_%d","tenant_id":"tenant_1","session_id":"session_1","start_token":%d,"end_token_exclusive":%d,"created_at":"2026-02-10T12:00:00Z","payload":{"text":%q}}`, i+1, i*10, (i+1)*10, text)
+		req := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		events []string
+	}{
+		{name: "bm25", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_text":"e1234","top_k":1,"buffer_after":1}`, status: http.StatusOK, events: []string{"evt_1", "evt_2"}},
+		{name: "context", path: "/v1/context", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_text":"deploy retrying","top_k":1,"token_budget":10}`, status: http.StatusOK, events: []string{"evt_2"}},
+		{name: "no terms", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_text":"?!","top_k":1}`, status: http.StatusBadRequest},
+		{name: "with anchors", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_text":"deploy","event_ids":["evt_1"],"top_k":1}`, status: http.StatusBadRequest},
+		{name: "context with anchors", path: "/v1/context", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_text":"deploy","event_ids":["evt_1"],"top_k":1,"token_budget":10}`, status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+
+		var resp struct {
+			Events []memory.Event `json:"events"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: unmarshal response: %v", tc.name, err)
+		}
+		ids := make([]string, len(resp.Events))
+		for i, event := range resp.Events {
+			ids[i] = event.EventID
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.events, ",") {
+			t.Fatalf("%s: expected events %v, got %v", tc.name, tc.events, ids)
+		}
+	}
+}
+
+func TestRetrieveByQueryEmbedding(t *testing.T) {
+	store := memory.NewStore()
+	base := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	vectors := [][]float32{{1, 0}, {0, 1}, {0.7, 0.7}}
+	for i, vector := range vectors {
+		event, err := memory.NewEvent(fmt.Sprintf("evt_%d", i+1), "tenant_1", "session_1", i*10, (i+1)*10, base)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		event.Embeddings = [][]float32{vector}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	body := `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[0,1],"metric":"cosine","top_k":1,"buffer_before":0,"buffer_after":1}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/retrieve", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Events []memory.Event `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.Events) != 2 || resp.Events[0].EventID != "evt_2" || resp.Events[1].EventID != "evt_3" {
+		t.Fatalf("unexpected events: %#v", resp.Events)
+	}
+}
+
+func TestRetrieveRejectsEventIDsWithQueryEmbedding(t *testing.T) {
+	router := newTestRouter(t)
+
+	body := `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_1"],"query_embedding":[1,0],"top_k":1}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/retrieve", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
 }
 
 func TestRetrieveRejectsInvalidRequest(t *testing.T) {
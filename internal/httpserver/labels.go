@@ -0,0 +1,33 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxLabelsBodyBytes int64 = 64 << 10
+
+// sessionLabelsRequest replaces the labels of one session. Empty labels
+// remove them all.
+type sessionLabelsRequest struct {
+	TenantID string            `json:"tenant_id" binding:"required"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func (h eventsHandler) setSessionLabels(c *gin.Context) {
+	var req sessionLabelsRequest
+	if err := bindJSONWithLimit(c, &req, maxLabelsBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	if err := h.store.SetSessionLabels(c.Request.Context(), req.TenantID, c.Param("session_id"), req.Labels); err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
@@ -0,0 +1,139 @@
+package httpserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiterIdleSweep is how often buckets that have refilled completely
+// are dropped; a full bucket is indistinguishable from a new one.
+const rateLimiterIdleSweep = time.Minute
+
+// RateLimit is a token bucket refilled at RequestsPerSecond up to Burst
+// requests. A zero RequestsPerSecond is unlimited.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RateLimits holds the default limit and per-tenant overrides. Each tenant
+// gets one bucket per route. A zero field in an override inherits the default.
+type RateLimits struct {
+	Default RateLimit
+	Tenants map[string]RateLimit
+}
+
+func (l RateLimits) forTenant(tenantID string) RateLimit {
+	limit := l.Default
+	override, ok := l.Tenants[tenantID]
+	if !ok {
+		return limit
+	}
+	if override.RequestsPerSecond != 0 {
+		limit.RequestsPerSecond = override.RequestsPerSecond
+	}
+	if override.Burst != 0 {
+		limit.Burst = override.Burst
+	}
+	return limit
+}
+
+type bucketKey struct {
+	tenantID string
+	route    string
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	limits RateLimits
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{
+		limits:  limits,
+		now:     time.Now,
+		buckets: make(map[bucketKey]*tokenBucket),
+	}
+}
+
+// allow takes one token from the tenant's bucket for route. When the bucket
+// is empty it returns how long until the next token is available.
+func (l *rateLimiter) allow(tenantID, route string) (bool, time.Duration) {
+	limit := l.limits.forTenant(tenantID)
+	if limit.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+	burst := float64(max(1, limit.Burst))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimiterIdleSweep {
+		l.sweepLocked(now)
+	}
+
+	key := bucketKey{tenantID: tenantID, route: route}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(burst, bucket.tokens+elapsed*limit.RequestsPerSecond)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / limit.RequestsPerSecond * float64(time.Second))
+		return false, wait
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) sweepLocked(now time.Time) {
+	for key, bucket := range l.buckets {
+		limit := l.limits.forTenant(key.tenantID)
+		refilled := bucket.tokens + now.Sub(bucket.last).Seconds()*limit.RequestsPerSecond
+		if refilled >= float64(max(1, limit.Burst)) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// admitTenant checks that the caller may act for tenantID and that the
+// tenant's rate limit for this route has a token left. On rejection it has
+// already written the response.
+func admitTenant(c *gin.Context, limiter *rateLimiter, tenantID string) bool {
+	c.Set(tenantIDContextKey, tenantID)
+	if !authorizeTenant(c, tenantID) {
+		return false
+	}
+	if limiter == nil {
+		return true
+	}
+
+	ok, wait := limiter.allow(tenantID, c.Request.Method+" "+c.FullPath())
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(c, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestRateLimiterRefillsOverTime(t *testing.T) {
+	now := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimits{
+		Default: RateLimit{RequestsPerSecond: 2, Burst: 2},
+		Tenants: map[string]RateLimit{"tenant_vip": {Burst: 3}},
+	})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("tenant_1", "GET /v1/events"); !ok {
+			t.Fatalf("expected request %d within burst to pass", i)
+		}
+	}
+	ok, wait := limiter.allow("tenant_1", "GET /v1/events")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected rejection with 500ms wait, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := limiter.allow("tenant_1", "POST /v1/retrieve"); !ok {
+		t.Fatalf("expected another route to have its own bucket")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.allow("tenant_vip", "GET /v1/events"); !ok {
+			t.Fatalf("expected override burst request %d to pass", i)
+		}
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.allow("tenant_1", "GET /v1/events"); !ok {
+		t.Fatalf("expected a token after refill")
+	}
+}
+
+func TestRateLimiterDropsIdleBuckets(t *testing.T) {
+	now := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimits{Default: RateLimit{RequestsPerSecond: 1, Burst: 1}})
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("tenant_1", "GET /v1/events")
+	now = now.Add(2 * rateLimiterIdleSweep)
+	limiter.allow("tenant_2", "GET /v1/events")
+
+	if _, ok := limiter.buckets[bucketKey{tenantID: "tenant_1", route: "GET /v1/events"}]; ok {
+		t.Fatalf("expected refilled bucket to be swept")
+	}
+}
+
+func TestRouterRateLimitsPerTenant(t *testing.T) {
+	router, err := NewRouter("test", memory.NewStore(), WithRateLimits(RateLimits{
+		Default: RateLimit{RequestsPerSecond: 0.001, Burst: 1},
+	}))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	get := func(tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/events?tenant_id="+tenantID+"&session_id=session_1", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("tenant_1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	rec := get("tenant_1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After header")
+	}
+	if rec := get("tenant_2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another tenant to be unaffected, got %d", rec.Code)
+	}
+}
+
+func TestCreateEventQuotaExceededReturnsInsufficientStorage(t *testing.T) {
+	store := memory.NewStoreWithOptions(memory.StoreOptions{Quotas: memory.QuotaPolicy{
+		Default: memory.Quota{MaxSessionsPerTenant: 1},
+	}})
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	for i, sessionID := range []string{"session_1", "session_2"} {
+		body := `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"` + sessionID +
+			`","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		want := http.StatusCreated
+		if i == 1 {
+			want = http.StatusInsufficientStorage
+		}
+		if rec.Code != want {
+			t.Fatalf("%s: expected status %d, got %d: %s", sessionID, want, rec.Code, rec.Body.String())
+		}
+	}
+}
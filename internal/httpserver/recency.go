@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"errors"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+var errRecencyHalfLifeFormat = errors.New(`recency half_life must be a duration such as "24h"`)
+
+// recencyRequest selects recency weighting for a similarity ranking.
+// half_life applies to the created_at basis and half_life_tokens to the
+// tokens basis.
+type recencyRequest struct {
+	Basis          memory.RecencyBasis `json:"basis"`
+	HalfLife       string              `json:"half_life"`
+	HalfLifeTokens int                 `json:"half_life_tokens"`
+	Weight         float64             `json:"weight"`
+}
+
+// recency converts the request; a nil request means no recency weighting.
+// The store validates the values.
+func (r *recencyRequest) recency() (*memory.Recency, error) {
+	if r == nil {
+		return nil, nil
+	}
+	recency := &memory.Recency{
+		Basis:          r.Basis,
+		HalfLifeTokens: r.HalfLifeTokens,
+		Weight:         r.Weight,
+	}
+	if r.HalfLife != "" {
+		halfLife, err := time.ParseDuration(r.HalfLife)
+		if err != nil {
+			return nil, errRecencyHalfLifeFormat
+		}
+		recency.HalfLife = halfLife
+	}
+	return recency, nil
+}
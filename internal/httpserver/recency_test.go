@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestRetrieveWithRecency(t *testing.T) {
+	router := newTestRouter(t)
+
+	// Ages run to the server clock, so created_at is set relative to it.
+	now := time.Now().UTC()
+	for _, body := range []string{
+		fmt.Sprintf(`{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":%q,"embeddings":[[1,0]]}`, now.Add(-48*time.Hour).Format(time.RFC3339)),
+		fmt.Sprintf(`{"event_id":"evt_2","tenant_id":"tenant_1","session_id":"session_1","start_token":10,"end_token_exclusive":20,"created_at":%q,"embeddings":[[1,0]]}`, now.Add(-24*time.Hour).Format(time.RFC3339)),
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		anchor string
+	}{
+		{name: "without recency", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1}`, status: http.StatusOK, anchor: "evt_1"},
+		{name: "created_at", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1,"recency":{"basis":"created_at","half_life":"24h","weight":0.1}}`, status: http.StatusOK, anchor: "evt_2"},
+		{name: "tokens", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1,"recency":{"basis":"tokens","half_life_tokens":100,"weight":0.1}}`, status: http.StatusOK, anchor: "evt_2"},
+		{name: "cross-session", path: "/v1/retrieve/sessions", body: `{"tenant_id":"tenant_1","query_embedding":[1,0],"top_k":1,"recency":{"basis":"tokens","half_life_tokens":100,"weight":0.1}}`, status: http.StatusOK, anchor: "evt_2"},
+		{name: "bad half_life", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1,"recency":{"basis":"created_at","half_life":"a day","weight":0.1}}`, status: http.StatusBadRequest},
+		{name: "bad weight", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1,"recency":{"basis":"created_at","half_life":"24h","weight":2}}`, status: http.StatusBadRequest},
+		{name: "dot metric", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"metric":"dot","top_k":1,"recency":{"basis":"created_at","half_life":"24h","weight":0.1}}`, status: http.StatusBadRequest},
+		{name: "anchors", path: "/v1/retrieve", body: `{"tenant_id":"tenant_1","session_id":"session_1","event_ids":["evt_1"],"top_k":1,"recency":{"basis":"created_at","half_life":"24h","weight":0.1}}`, status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+
+		var resp struct {
+			Events   []memory.RetrievedEvent `json:"events"`
+			Sessions []memory.SessionResults `json:"sessions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: unmarshal response: %v", tc.name, err)
+		}
+		if len(resp.Sessions) == 1 {
+			resp.Events = resp.Sessions[0].Events
+		}
+		if len(resp.Events) != 1 || resp.Events[0].EventID != tc.anchor {
+			t.Fatalf("%s: expected %s, got %+v", tc.name, tc.anchor, resp.Events)
+		}
+	}
+}
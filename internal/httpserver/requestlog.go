@@ -0,0 +1,135 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "memplane.request_id"
+	tenantIDContextKey  = "memplane.tenant_id"
+
+	maxRequestIDLength = 128
+)
+
+// assignRequestID reuses a well-formed X-Request-ID from the caller so logs
+// can be joined across services, and otherwise generates one. The ID is
+// echoed in the response header.
+func assignRequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	// crypto/rand.Read never returns an error.
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// logRequests writes one log line per request once it has been served.
+// tenant_id is present once a handler has admitted the request for a tenant.
+// request_bytes counts what the handler read rather than Content-Length,
+// which is unknown for chunked bodies.
+func logRequests(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		body := &countingBody{ReadCloser: http.NoBody}
+		if c.Request.Body != nil {
+			body.ReadCloser = c.Request.Body
+		}
+		c.Request.Body = body
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.String("request_id", requestID(c)),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int64("request_bytes", body.n),
+			zap.Int("response_bytes", max(0, c.Writer.Size())),
+		}
+		if tenantID := c.GetString(tenantIDContextKey); tenantID != "" {
+			fields = append(fields, zap.String("tenant_id", tenantID))
+		}
+		if value, ok := c.Get(principalContextKey); ok {
+			fields = append(fields, zap.String("api_key_id", value.(APIKey).ID))
+		}
+		if err := c.Errors.Last(); err != nil {
+			fields = append(fields, zap.Error(err.Err))
+		}
+
+		if status >= http.StatusInternalServerError {
+			logger.Error("request", fields...)
+			return
+		}
+		logger.Info("request", fields...)
+	}
+}
+
+// recoverPanics turns a handler panic into a 500 and logs it with the stack
+// of the panicking goroutine.
+func recoverPanics(logger *zap.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logger.Error("panic recovered",
+			zap.String("request_id", requestID(c)),
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+			zap.Any("panic", recovered),
+			zap.Stack("stack"),
+		)
+		writeError(c, http.StatusInternalServerError, "internal server error")
+		c.Abort()
+	})
+}
@@ -0,0 +1,185 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestIDIsPropagatedOrGenerated(t *testing.T) {
+	router := newTestRouter(t)
+
+	cases := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{name: "propagated", incoming: "req-123_abc", reuse: true},
+		{name: "missing", incoming: ""},
+		{name: "invalid characters", incoming: "bad id\n"},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/v1/events?tenant_id=tenant_1", nil)
+		if tc.incoming != "" {
+			req.Header.Set(requestIDHeader, tc.incoming)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(requestIDHeader)
+		if tc.reuse && got != tc.incoming {
+			t.Fatalf("%s: expected request id %q, got %q", tc.name, tc.incoming, got)
+		}
+		if !tc.reuse && (got == tc.incoming || len(got) != 32) {
+			t.Fatalf("%s: expected a generated request id, got %q", tc.name, got)
+		}
+
+		var body struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode error body: %v", tc.name, err)
+		}
+		if body.Error == "" || body.RequestID != got {
+			t.Fatalf("%s: expected error body with request id %q, got %+v", tc.name, got, body)
+		}
+	}
+}
+
+func TestRequestsAreLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	router, err := NewRouter("test", memory.NewStore(), WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	body := `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestIDHeader, "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("request").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("expected one request log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	want := map[string]any{
+		"request_id":    "req-1",
+		"method":        http.MethodPost,
+		"route":         "/v1/events",
+		"status":        int64(http.StatusCreated),
+		"tenant_id":     "tenant_1",
+		"request_bytes": int64(len(body)),
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Fatalf("expected %s=%v, got %v", key, value, fields[key])
+		}
+	}
+	if _, ok := fields["latency"]; !ok {
+		t.Fatalf("expected latency field, got %v", fields)
+	}
+
+	// A chunked body has no Content-Length; the bytes read are logged.
+	body = strings.Replace(body, "evt_1", "evt_2", 1)
+	req = httptest.NewRequest(http.MethodPost, "/v1/events", io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries = logs.FilterMessage("request").AllUntimed()
+	if got := entries[len(entries)-1].ContextMap()["request_bytes"]; got != int64(len(body)) {
+		t.Fatalf("expected request_bytes=%d for a chunked body, got %v", len(body), got)
+	}
+}
+
+func TestPanicsAreRecoveredAndLoggedWithStack(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	router, err := NewRouter("test", memory.NewStore(), WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	router.GET("/panic", func(*gin.Context) { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), rec.Header().Get(requestIDHeader)) {
+		t.Fatalf("expected request id in error body, got %s", rec.Body.String())
+	}
+
+	panics := logs.FilterMessage("panic recovered").AllUntimed()
+	if len(panics) != 1 {
+		t.Fatalf("expected one panic log entry, got %d", len(panics))
+	}
+	fields := panics[0].ContextMap()
+	if fields["panic"] != "boom" {
+		t.Fatalf("expected panic value boom, got %v", fields["panic"])
+	}
+	if stack, _ := fields["stack"].(string); !strings.Contains(stack, "requestlog_test") {
+		t.Fatalf("expected stack to include the panicking handler, got %q", stack)
+	}
+	requests := logs.FilterMessage("request").AllUntimed()
+	if len(requests) != 1 || requests[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("expected the request to be logged at error level, got %v", requests)
+	}
+}
+
+// failingStore fails similarity retrieval the way a broken durable store would.
+type failingStore struct {
+	memory.Store
+	err error
+}
+
+func (s failingStore) RetrieveBySimilarity(context.Context, memory.SimilarityQuery) ([]memory.RetrievedEvent, error) {
+	return nil, s.err
+}
+
+func TestStoreFailuresAreLoggedAndHiddenFromClients(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	storeErr := errors.New("sync write-ahead log: input/output error")
+	router, err := NewRouter("test", failingStore{Store: memory.NewStore(), err: storeErr}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	body := `{"tenant_id":"tenant_1","session_id":"session_1","query_embedding":[1,0],"top_k":1}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/retrieve", strings.NewReader(body)))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusInternalServerError, rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "write-ahead log") {
+		t.Fatalf("expected a generic error body, got %s", rec.Body.String())
+	}
+
+	requests := logs.FilterMessage("request").AllUntimed()
+	if len(requests) != 1 || requests[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("expected the request to be logged at error level, got %v", requests)
+	}
+	if got := requests[0].ContextMap()["error"]; got != storeErr.Error() {
+		t.Fatalf("expected the store error to be logged, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxRetentionBodyBytes int64 = 4 << 10
+
+// sessionRetentionRequest overrides the tenant's retention for one session.
+// Durations are Go duration strings such as "720h"; empty or zero fields
+// inherit the tenant policy.
+type sessionRetentionRequest struct {
+	TenantID       string `json:"tenant_id" binding:"required"`
+	EventTTL       string `json:"event_ttl"`
+	SessionIdleTTL string `json:"session_idle_ttl"`
+	RetainEvents   int    `json:"retain_events"`
+}
+
+func (h eventsHandler) setSessionRetention(c *gin.Context) {
+	var req sessionRetentionRequest
+	if err := bindJSONWithLimit(c, &req, maxRetentionBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	retention := memory.Retention{RetainEvents: req.RetainEvents}
+	var err error
+	if retention.EventTTL, err = parseRetentionDuration("event_ttl", req.EventTTL); err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if retention.SessionIdleTTL, err = parseRetentionDuration("session_idle_ttl", req.SessionIdleTTL); err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.store.SetSessionRetention(c.Request.Context(), req.TenantID, c.Param("session_id"), retention); err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func parseRetentionDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as \"720h\"", field)
+	}
+	return d, nil
+}
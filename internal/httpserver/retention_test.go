@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestSetSessionRetention(t *testing.T) {
+	store := memory.NewStoreWithOptions(memory.StoreOptions{
+		Retention: memory.RetentionPolicy{Default: memory.Retention{SessionIdleTTL: 720 * time.Hour}},
+	})
+	event, err := memory.NewEvent("evt_1", "tenant_1", "session_1", 0, 1, time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "ok", path: "/v1/sessions/session_1/retention", body: `{"tenant_id":"tenant_1","event_ttl":"24h","retain_events":100}`, status: http.StatusOK},
+		{name: "clear", path: "/v1/sessions/session_1/retention", body: `{"tenant_id":"tenant_1"}`, status: http.StatusOK},
+		{name: "missing session", path: "/v1/sessions/session_2/retention", body: `{"tenant_id":"tenant_1","event_ttl":"24h"}`, status: http.StatusNotFound},
+		{name: "bad duration", path: "/v1/sessions/session_1/retention", body: `{"tenant_id":"tenant_1","session_idle_ttl":"soon"}`, status: http.StatusBadRequest},
+		{name: "negative", path: "/v1/sessions/session_1/retention", body: `{"tenant_id":"tenant_1","retain_events":-1}`, status: http.StatusBadRequest},
+		{name: "missing tenant", path: "/v1/sessions/session_1/retention", body: `{"event_ttl":"24h"}`, status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Without a retention policy there is no sweep to honour an override.
+	disabled := memory.NewStore()
+	if err := disabled.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	router, err = NewRouter("test", disabled)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/v1/sessions/session_1/retention", strings.NewReader(`{"tenant_id":"tenant_1","event_ttl":"24h"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d without a retention policy, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
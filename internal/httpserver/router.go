@@ -4,39 +4,130 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"memplane/internal/memory"
+	"memplane/internal/metrics"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func NewRouter(environment string, store *memory.Store) (*gin.Engine, error) {
+// Option configures optional router behaviour.
+type Option func(*routerOptions)
+
+type routerOptions struct {
+	apiKeys    *APIKeys
+	rateLimits *RateLimits
+	metrics    *metrics.Metrics
+	logger     *zap.Logger
+}
+
+// WithAPIKeys requires a bearer API key on every /v1 route and restricts each
+// request to the key's tenant and scopes. Without it the API is unauthenticated.
+func WithAPIKeys(keys *APIKeys) Option {
+	return func(opts *routerOptions) {
+		opts.apiKeys = keys
+	}
+}
+
+// WithRateLimits applies per-tenant token-bucket limits to every /v1 route.
+func WithRateLimits(limits RateLimits) Option {
+	return func(opts *routerOptions) {
+		opts.rateLimits = &limits
+	}
+}
+
+// WithMetrics records every request in m and serves m on GET /metrics.
+// Like /health, /metrics does not require an API key.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(opts *routerOptions) {
+		opts.metrics = m
+	}
+}
+
+// WithLogger logs every request, and any recovered panic, to logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(opts *routerOptions) {
+		opts.logger = logger
+	}
+}
+
+func NewRouter(environment string, store memory.Store, opts ...Option) (*gin.Engine, error) {
 	if store == nil {
 		return nil, errors.New("memory store is required")
 	}
 
+	options := routerOptions{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	EnableStrictJSONDecoding()
 	gin.SetMode(ginMode(environment))
 
 	router := gin.New()
-	router.Use(gin.Recovery())
+	router.Use(assignRequestID(), traceRequests())
+	if options.metrics != nil {
+		router.Use(observeRequests(options.metrics))
+	}
+	router.Use(logRequests(options.logger), recoverPanics(options.logger))
 	if err := router.SetTrustedProxies(nil); err != nil {
 		return nil, fmt.Errorf("set trusted proxies: %w", err)
 	}
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	if options.metrics != nil {
+		router.GET("/metrics", gin.WrapH(options.metrics.Handler()))
+	}
 
-	eventsHandler := newEventsHandler(store)
+	var limiter *rateLimiter
+	if options.rateLimits != nil {
+		limiter = newRateLimiter(*options.rateLimits)
+	}
+
+	eventsHandler := newEventsHandler(store, limiter)
 	v1 := router.Group("/v1")
-	v1.POST("/events", eventsHandler.create)
-	v1.GET("/events", eventsHandler.list)
-	v1.POST("/segment", eventsHandler.segment)
-	v1.POST("/retrieve", eventsHandler.retrieve)
+	read := requireScope(options.apiKeys, ScopeRead)
+	write := requireScope(options.apiKeys, ScopeWrite)
+	v1.POST("/events", write, eventsHandler.create)
+	v1.GET("/events", read, eventsHandler.list)
+	v1.GET("/events/:event_id", read, eventsHandler.get)
+	v1.GET("/sessions", read, eventsHandler.listSessions)
+	v1.GET("/sessions/:session_id", read, eventsHandler.getSession)
+	v1.GET("/sessions/:session_id/spans", read, eventsHandler.spanReport)
+	v1.POST("/segment", write, eventsHandler.segment)
+	v1.POST("/segment/flush", write, eventsHandler.flushSegment)
+	v1.POST("/retrieve", read, eventsHandler.retrieve)
+	v1.POST("/retrieve/sessions", read, eventsHandler.retrieveAcrossSessions)
+	v1.POST("/context", read, eventsHandler.assembleContext)
+	v1.PATCH("/events/:event_id", write, eventsHandler.patchEvent)
+	v1.DELETE("/events/:event_id", write, eventsHandler.deleteEvent)
+	v1.DELETE("/sessions/:session_id", write, eventsHandler.deleteSession)
+	v1.PUT("/sessions/:session_id/retention", write, eventsHandler.setSessionRetention)
+	v1.PUT("/sessions/:session_id/span-policy", write, eventsHandler.setSessionSpanPolicy)
+	v1.PUT("/sessions/:session_id/labels", write, eventsHandler.setSessionLabels)
+	v1.DELETE("/tenants/:tenant_id", requireScope(options.apiKeys, ScopeAdmin), eventsHandler.purgeTenant)
 
 	return router, nil
 }
 
+// observeRequests reports each request under its route template so that path
+// parameters and unknown paths cannot blow up label cardinality.
+func observeRequests(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
+
 func ginMode(environment string) string {
 	switch environment {
 	case "development":
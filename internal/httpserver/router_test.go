@@ -3,9 +3,11 @@ package httpserver
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"memplane/internal/memory"
+	"memplane/internal/metrics"
 )
 
 func TestHealth(t *testing.T) {
@@ -34,3 +36,29 @@ func TestNewRouterRequiresStore(t *testing.T) {
 		t.Fatalf("expected error for nil store")
 	}
 }
+
+func TestMetricsRecordsRouteTemplates(t *testing.T) {
+	router, err := NewRouter("test", memory.NewStore(), WithMetrics(metrics.New()))
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	for _, path := range []string{"/v1/events?tenant_id=tenant_1&session_id=session_1", "/no/such/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`memplane_http_requests_total{method="GET",route="/v1/events",status="200"} 1`,
+		`memplane_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics output:\n%s", want, body)
+		}
+	}
+}
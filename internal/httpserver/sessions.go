@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type listSessionsRequest struct {
+	TenantID string `form:"tenant_id" binding:"required"`
+	Limit    string `form:"limit"`
+	Cursor   string `form:"cursor"`
+}
+
+type getSessionRequest struct {
+	TenantID string `form:"tenant_id" binding:"required"`
+}
+
+// listSessions pages through the tenant's sessions by session id, with the
+// next page cursor in the same header as GET /v1/events.
+func (h eventsHandler) listSessions(c *gin.Context) {
+	var req listSessionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	query := memory.SessionQuery{TenantID: req.TenantID, Limit: defaultListLimit, Cursor: req.Cursor}
+	if req.Limit != "" {
+		limit, err := strconv.Atoi(req.Limit)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			writeError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxListLimit))
+			return
+		}
+		query.Limit = limit
+	}
+
+	page, err := h.store.ListSessions(c.Request.Context(), query)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Sessions)
+}
+
+func (h eventsHandler) getSession(c *gin.Context) {
+	var req getSessionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	summary, ok := h.store.GetSession(req.TenantID, c.Param("session_id"))
+	if !ok {
+		writeError(c, http.StatusNotFound, "session not found")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,68 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestLookupEndpoints(t *testing.T) {
+	store := memory.NewStore()
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	for _, sessionID := range []string{"session_1", "session_2", "session_3"} {
+		event, err := memory.NewEvent("evt_1", "tenant_1", sessionID, 0, 10, now)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		status int
+		cursor bool
+	}{
+		{name: "event", path: "/v1/events/evt_1?tenant_id=tenant_1&session_id=session_1", status: http.StatusOK},
+		{name: "missing event", path: "/v1/events/evt_2?tenant_id=tenant_1&session_id=session_1", status: http.StatusNotFound},
+		{name: "event without session", path: "/v1/events/evt_1?tenant_id=tenant_1", status: http.StatusBadRequest},
+		{name: "sessions", path: "/v1/sessions?tenant_id=tenant_1&limit=2", status: http.StatusOK, cursor: true},
+		{name: "sessions bad limit", path: "/v1/sessions?tenant_id=tenant_1&limit=0", status: http.StatusBadRequest},
+		{name: "sessions without tenant", path: "/v1/sessions", status: http.StatusBadRequest},
+		{name: "session", path: "/v1/sessions/session_2?tenant_id=tenant_1", status: http.StatusOK},
+		{name: "missing session", path: "/v1/sessions/session_9?tenant_id=tenant_1", status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(nextCursorHeader) != ""; got != tc.cursor {
+			t.Fatalf("%s: expected cursor %v, got %v", tc.name, tc.cursor, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sessions/session_2?tenant_id=tenant_1", nil))
+	var summary memory.SessionSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if summary.SessionID != "session_2" || summary.Events != 1 || summary.EndTokenExclusive != 10 || !summary.FirstCreatedAt.Equal(now) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+}
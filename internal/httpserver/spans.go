@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSpanPolicyBodyBytes int64 = 4 << 10
+
+// sessionSpanPolicyRequest overrides the tenant's span policy for one
+// session. An empty span_policy inherits the tenant policy again.
+type sessionSpanPolicyRequest struct {
+	TenantID   string            `json:"tenant_id" binding:"required"`
+	SpanPolicy memory.SpanPolicy `json:"span_policy"`
+}
+
+type spanReportRequest struct {
+	TenantID string `form:"tenant_id" binding:"required"`
+}
+
+func (h eventsHandler) setSessionSpanPolicy(c *gin.Context) {
+	var req sessionSpanPolicyRequest
+	if err := bindJSONWithLimit(c, &req, maxSpanPolicyBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	if err := h.store.SetSessionSpanPolicy(c.Request.Context(), req.TenantID, c.Param("session_id"), req.SpanPolicy); err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// spanReport lists the gaps and overlaps between a session's events.
+func (h eventsHandler) spanReport(c *gin.Context) {
+	var req spanReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	report, ok := h.store.SpanReport(req.TenantID, c.Param("session_id"))
+	if !ok {
+		writeError(c, http.StatusNotFound, "session not found")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
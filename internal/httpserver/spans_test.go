@@ -0,0 +1,54 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"memplane/internal/memory"
+)
+
+func TestSessionSpanPolicyEndpoints(t *testing.T) {
+	router := newTestRouter(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "report for missing session", method: http.MethodGet, path: "/v1/sessions/session_1/spans?tenant_id=tenant_1", status: http.StatusNotFound},
+		{name: "unknown policy", method: http.MethodPut, path: "/v1/sessions/session_1/span-policy", body: `{"tenant_id":"tenant_1","span_policy":"strict"}`, status: http.StatusBadRequest},
+		{name: "set policy", method: http.MethodPut, path: "/v1/sessions/session_1/span-policy", body: `{"tenant_id":"tenant_1","span_policy":"reject-overlap"}`, status: http.StatusOK},
+		{name: "first event", method: http.MethodPost, path: "/v1/events", body: `{"event_id":"evt_1","tenant_id":"tenant_1","session_id":"session_1","start_token":0,"end_token_exclusive":10,"created_at":"2026-02-10T12:00:00Z"}`, status: http.StatusCreated},
+		{name: "overlapping event", method: http.MethodPost, path: "/v1/events", body: `{"event_id":"evt_2","tenant_id":"tenant_1","session_id":"session_1","start_token":5,"end_token_exclusive":15,"created_at":"2026-02-10T12:00:00Z"}`, status: http.StatusConflict},
+		{name: "event after gap", method: http.MethodPost, path: "/v1/events", body: `{"event_id":"evt_3","tenant_id":"tenant_1","session_id":"session_1","start_token":20,"end_token_exclusive":30,"created_at":"2026-02-10T12:00:00Z"}`, status: http.StatusCreated},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sessions/session_1/spans?tenant_id=tenant_1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var report memory.SpanReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if report.Policy != memory.SpanPolicyRejectOverlap || len(report.Gaps) != 1 || report.Gaps[0].StartToken != 10 || len(report.Overlaps) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
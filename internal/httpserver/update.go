@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"memplane/internal/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxUpdateEventBodyBytes int64 = maxJSONBodyBytes
+
+var errPreconditionFailed = errors.New("If-Match does not name a current event version")
+
+// patchEventRequest replaces the fields it sets. A field that is omitted or
+// null is left as it is; an empty object or array clears it.
+type patchEventRequest struct {
+	TenantID   string             `json:"tenant_id" binding:"required"`
+	SessionID  string             `json:"session_id" binding:"required"`
+	Payload    *memory.Payload    `json:"payload"`
+	Metadata   *map[string]string `json:"metadata"`
+	Embeddings *[][]float32       `json:"embeddings"`
+	Tags       *[]string          `json:"tags"`
+}
+
+// patchEvent updates one event. With an If-Match header the update only
+// applies while the event is still at that version; otherwise it applies
+// unconditionally.
+func (h eventsHandler) patchEvent(c *gin.Context) {
+	var req patchEventRequest
+	if err := bindJSONWithLimit(c, &req, maxUpdateEventBodyBytes); err != nil {
+		writeError(c, statusForBindError(err), err.Error())
+		return
+	}
+	if !admitTenant(c, h.limiter, req.TenantID) {
+		return
+	}
+
+	ifVersion, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		writeError(c, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+
+	event, err := h.store.UpdateEvent(c.Request.Context(), req.TenantID, req.SessionID, c.Param("event_id"), memory.EventPatch{
+		Payload:    req.Payload,
+		Metadata:   req.Metadata,
+		Embeddings: req.Embeddings,
+		Tags:       req.Tags,
+	}, ifVersion)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	setETag(c, event.Version)
+	c.JSON(http.StatusOK, event)
+}
+
+// setETag exposes an event version as a strong entity tag.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// parseIfMatch returns the version an If-Match header requires, or 0 when
+// any version will do. Weak tags never match, since versions are exact.
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, errPreconditionFailed
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < memory.FirstEventVersion {
+		return 0, errPreconditionFailed
+	}
+	return version, nil
+}
@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func TestPatchEvent(t *testing.T) {
+	store := memory.NewStore()
+	event, err := memory.NewEvent("evt_1", "tenant_1", "session_1", 0, 2, time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	router, err := NewRouter("test", store)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		ifMatch string
+		body    string
+		status  int
+		etag    string
+	}{
+		{name: "conditional", path: "/v1/events/evt_1", ifMatch: `"1"`, body: `{"tenant_id":"tenant_1","session_id":"session_1","tags":["reviewed"]}`, status: http.StatusOK, etag: `"2"`},
+		{name: "stale", path: "/v1/events/evt_1", ifMatch: `"1"`, body: `{"tenant_id":"tenant_1","session_id":"session_1","tags":[]}`, status: http.StatusPreconditionFailed},
+		{name: "weak tag", path: "/v1/events/evt_1", ifMatch: `W/"2"`, body: `{"tenant_id":"tenant_1","session_id":"session_1","tags":[]}`, status: http.StatusPreconditionFailed},
+		{name: "unconditional", path: "/v1/events/evt_1", body: `{"tenant_id":"tenant_1","session_id":"session_1","metadata":{"k":"v"}}`, status: http.StatusOK, etag: `"3"`},
+		{name: "span is immutable", path: "/v1/events/evt_1", body: `{"tenant_id":"tenant_1","session_id":"session_1","start_token":5}`, status: http.StatusBadRequest},
+		{name: "invalid payload", path: "/v1/events/evt_1", body: `{"tenant_id":"tenant_1","session_id":"session_1","payload":{"token_ids":[1]}}`, status: http.StatusBadRequest},
+		{name: "missing event", path: "/v1/events/evt_2", body: `{"tenant_id":"tenant_1","session_id":"session_1","tags":[]}`, status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPatch, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.ifMatch != "" {
+			req.Header.Set("If-Match", tc.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("ETag"); got != tc.etag {
+			t.Fatalf("%s: expected ETag %q, got %q", tc.name, tc.etag, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/events/evt_1?tenant_id=tenant_1&session_id=session_1", nil))
+	var got memory.Event
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Header().Get("ETag") != `"3"` || got.Version != 3 || got.Tags[0] != "reviewed" || got.Metadata["k"] != "v" {
+		t.Fatalf("unexpected event %+v with ETag %q", got, rec.Header().Get("ETag"))
+	}
+}
@@ -1,12 +1,36 @@
 package memory
 
-import "errors"
+import "math"
+
+// maxSurpriseHistory bounds both the adaptive window and the per-session
+// surprise history the store keeps for it.
+const maxSurpriseHistory = 4096
 
 var (
-	errNegativeSurpriseThreshold = errors.New("surprise threshold must be non-negative")
-	errInvalidMinBoundaryGap     = errors.New("minimum boundary gap must be positive")
+	errNegativeSurpriseThreshold = invalidInput("surprise threshold must be non-negative")
+	errInvalidMinBoundaryGap     = invalidInput("minimum boundary gap must be positive")
+	errAdaptiveWindowInvalid     = invalidInput("adaptive threshold window must be between 1 and %d", maxSurpriseHistory)
+	errAdaptiveGammaInvalid      = invalidInput("adaptive threshold gamma must be finite and non-negative")
 )
 
+// AdaptiveThreshold sets the threshold at each token to mean + Gamma*std of
+// the Window surprise values before it, so boundaries track the local surprise
+// level rather than one absolute cut-off.
+type AdaptiveThreshold struct {
+	Window int
+	Gamma  float64
+}
+
+func (a AdaptiveThreshold) validate() error {
+	if a.Window <= 0 || a.Window > maxSurpriseHistory {
+		return errAdaptiveWindowInvalid
+	}
+	if a.Gamma < 0 || math.IsNaN(a.Gamma) || math.IsInf(a.Gamma, 0) {
+		return errAdaptiveGammaInvalid
+	}
+	return nil
+}
+
 func DetectBoundaries(surprise []float64, threshold float64, minBoundaryGap int) ([]int, error) {
 	if threshold < 0 {
 		return nil, errNegativeSurpriseThreshold
@@ -14,15 +38,96 @@ func DetectBoundaries(surprise []float64, threshold float64, minBoundaryGap int)
 	if minBoundaryGap <= 0 {
 		return nil, errInvalidMinBoundaryGap
 	}
+
+	boundaries, _ := detectBoundaries(surprise, minBoundaryGap, 0, func(int) float64 { return threshold })
+	return boundaries, nil
+}
+
+// DetectBoundariesAdaptive detects boundaries like DetectBoundaries with an
+// adaptive threshold. history holds earlier surprise values of the same
+// stream, oldest first, and seeds the window for the first tokens. It also
+// returns the threshold each boundary's peak was compared against.
+func DetectBoundariesAdaptive(
+	surprise []float64,
+	history []float64,
+	adaptive AdaptiveThreshold,
+	minBoundaryGap int,
+) ([]int, []float64, error) {
+	if err := adaptive.validate(); err != nil {
+		return nil, nil, err
+	}
+	if minBoundaryGap <= 0 {
+		return nil, nil, errInvalidMinBoundaryGap
+	}
+
+	thresholds := adaptiveThresholds(surprise, history, adaptive)
+	boundaries, peakThresholds := detectBoundaries(surprise, minBoundaryGap, 0, func(i int) float64 {
+		return thresholds[i]
+	})
+	return boundaries, peakThresholds, nil
+}
+
+// adaptiveThresholds returns the threshold for every token in surprise using
+// running sums over the trailing window.
+func adaptiveThresholds(surprise, history []float64, adaptive AdaptiveThreshold) []float64 {
+	if len(history) > adaptive.Window {
+		history = history[len(history)-adaptive.Window:]
+	}
+	series := make([]float64, 0, len(history)+len(surprise))
+	series = append(series, history...)
+	series = append(series, surprise...)
+
+	thresholds := make([]float64, len(surprise))
+	var sum, sumSquares float64
+	for _, value := range history {
+		sum += value
+		sumSquares += value * value
+	}
+	windowStart := 0
+	for i := range surprise {
+		end := len(history) + i
+		if i > 0 {
+			value := series[end-1]
+			sum += value
+			sumSquares += value * value
+		}
+		for end-windowStart > adaptive.Window {
+			value := series[windowStart]
+			sum -= value
+			sumSquares -= value * value
+			windowStart++
+		}
+
+		count := float64(end - windowStart)
+		if count == 0 {
+			// Nothing precedes the first token of a stream; it can never be a peak.
+			thresholds[i] = math.Inf(1)
+			continue
+		}
+		mean := sum / count
+		variance := max(0, sumSquares/count-mean*mean)
+		thresholds[i] = mean + adaptive.Gamma*math.Sqrt(variance)
+	}
+
+	return thresholds
+}
+
+// detectBoundaries returns local surprise peaks above thresholdAt as the
+// boundaries after them, plus each peak's threshold. Boundaries below
+// minBoundary are ignored; streaming uses it to keep minBoundaryGap from a
+// boundary confirmed by an earlier call.
+func detectBoundaries(surprise []float64, minBoundaryGap, minBoundary int, thresholdAt func(int) float64) ([]int, []float64) {
 	if len(surprise) < 3 {
-		return []int{}, nil
+		return []int{}, []float64{}
 	}
 
 	boundaries := make([]int, 0)
 	peaks := make([]float64, 0)
+	thresholds := make([]float64, 0)
 
 	for i := 1; i < len(surprise)-1; i++ {
 		score := surprise[i]
+		threshold := thresholdAt(i)
 		if score <= threshold {
 			continue
 		}
@@ -33,16 +138,14 @@ func DetectBoundaries(surprise []float64, threshold float64, minBoundaryGap int)
 
 		// Boundary is the first token after the peak token.
 		boundary := i + 1
-		last := len(boundaries) - 1
-		if last < 0 {
-			boundaries = append(boundaries, boundary)
-			peaks = append(peaks, score)
+		if boundary < minBoundary {
 			continue
 		}
-
-		if boundary-boundaries[last] >= minBoundaryGap {
+		last := len(boundaries) - 1
+		if last < 0 || boundary-boundaries[last] >= minBoundaryGap {
 			boundaries = append(boundaries, boundary)
 			peaks = append(peaks, score)
+			thresholds = append(thresholds, threshold)
 			continue
 		}
 
@@ -50,8 +153,9 @@ func DetectBoundaries(surprise []float64, threshold float64, minBoundaryGap int)
 		if score > peaks[last] {
 			boundaries[last] = boundary
 			peaks[last] = score
+			thresholds[last] = threshold
 		}
 	}
 
-	return boundaries, nil
+	return boundaries, thresholds
 }
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"errors"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -91,3 +92,72 @@ func TestDetectBoundariesRejectsPlateauAsPeak(t *testing.T) {
 		t.Fatalf("expected no boundaries, got %v", got)
 	}
 }
+
+func TestDetectBoundariesAdaptiveUsesHistoryWindow(t *testing.T) {
+	t.Parallel()
+
+	history := []float64{1, 1.1, 1, 1.2}
+	scores := []float64{1, 1.1, 1, 3, 1}
+	adaptive := AdaptiveThreshold{Window: 4, Gamma: 1}
+
+	got, thresholds, err := DetectBoundariesAdaptive(scores, history, adaptive, 1)
+	if err != nil {
+		t.Fatalf("detect boundaries: %v", err)
+	}
+
+	want := []int{4}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected boundaries %v, got %v", want, got)
+	}
+
+	// The peak at index 3 is compared against the four values before it.
+	window := []float64{1.2, 1, 1.1, 1}
+	mean := (window[0] + window[1] + window[2] + window[3]) / 4
+	var variance float64
+	for _, value := range window {
+		variance += (value - mean) * (value - mean)
+	}
+	wantThreshold := mean + math.Sqrt(variance/4)
+	if len(thresholds) != 1 || math.Abs(thresholds[0]-wantThreshold) > 1e-9 {
+		t.Fatalf("expected threshold %v, got %v", wantThreshold, thresholds)
+	}
+
+	// Without history the small early bump stands out against a one-value window.
+	got, _, err = DetectBoundariesAdaptive(scores, nil, adaptive, 1)
+	if err != nil {
+		t.Fatalf("detect boundaries without history: %v", err)
+	}
+	want = []int{2, 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected boundaries %v without history, got %v", want, got)
+	}
+}
+
+func TestDetectBoundariesAdaptiveRejectsInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		adaptive AdaptiveThreshold
+		gap      int
+		err      error
+	}{
+		{name: "zero window", adaptive: AdaptiveThreshold{Window: 0, Gamma: 1}, gap: 1, err: errAdaptiveWindowInvalid},
+		{name: "window too large", adaptive: AdaptiveThreshold{Window: maxSurpriseHistory + 1, Gamma: 1}, gap: 1, err: errAdaptiveWindowInvalid},
+		{name: "negative gamma", adaptive: AdaptiveThreshold{Window: 4, Gamma: -1}, gap: 1, err: errAdaptiveGammaInvalid},
+		{name: "nan gamma", adaptive: AdaptiveThreshold{Window: 4, Gamma: math.NaN()}, gap: 1, err: errAdaptiveGammaInvalid},
+		{name: "zero gap", adaptive: AdaptiveThreshold{Window: 4, Gamma: 1}, gap: 0, err: errInvalidMinBoundaryGap},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := DetectBoundariesAdaptive([]float64{0, 1, 0}, nil, tc.adaptive, tc.gap)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
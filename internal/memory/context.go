@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	errContextBudgetNonPositive = invalidInput("token_budget must be positive")
+	errContextQueryRequired     = invalidInput("exactly one of anchor event ids and a query text or embedding is required")
+)
+
+// DropReason says why AssembleContext left a candidate event out.
+type DropReason string
+
+const (
+	// DropTokenBudget: the event did not fit in what was left of the budget.
+	DropTokenBudget DropReason = "token_budget"
+	// DropNoText: the event has no payload text to contribute.
+	DropNoText DropReason = "no_text"
+	// DropAnchorDropped: the event is a buffer neighbour of an anchor that
+	// did not fit, so it would have no context to support.
+	DropAnchorDropped DropReason = "anchor_dropped"
+)
+
+// ContextRequest asks for the events that best answer a query, packed into a
+// token budget. Either AnchorEventIDs is set, or one or both of QueryText and
+// QueryEmbedding, which rank like RetrieveByText and RetrieveBySimilarity.
+type ContextRequest struct {
+	TenantID       string
+	SessionID      string
+	AnchorEventIDs []string
+	QueryText      string
+	QueryEmbedding []float32
+	Metric         SimilarityMetric
+	TopK           int
+	BufferBefore   int
+	BufferAfter    int
+	// TokenBudget caps the summed token spans of the packed events.
+	TokenBudget int
+	// Separator joins the payload texts of the packed events.
+	Separator string
+	// Recency weights similarity ranking and needs QueryEmbedding.
+	Recency *Recency
+}
+
+// DroppedEvent is a candidate AssembleContext left out.
+type DroppedEvent struct {
+	EventID string     `json:"event_id"`
+	Tokens  int        `json:"tokens"`
+	Reason  DropReason `json:"reason"`
+}
+
+// AssembledContext is the result of AssembleContext.
+type AssembledContext struct {
+	// Text joins the payload texts of Events with the request separator.
+	Text string `json:"text"`
+	// Events are the packed events in session order.
+	Events      []Event        `json:"events"`
+	TokensUsed  int            `json:"tokens_used"`
+	TokenBudget int            `json:"token_budget"`
+	Dropped     []DroppedEvent `json:"dropped"`
+}
+
+func (req ContextRequest) validate() error {
+	if req.TokenBudget <= 0 {
+		return errContextBudgetNonPositive
+	}
+	hasQuery := req.QueryText != "" || len(req.QueryEmbedding) > 0
+	if (len(req.AnchorEventIDs) > 0) == hasQuery {
+		return errContextQueryRequired
+	}
+	if req.TopK <= 0 {
+		return errRetrieveTopKNonPositive
+	}
+	if req.BufferBefore < 0 || req.BufferAfter < 0 {
+		return errRetrieveBufferNegative
+	}
+	if req.QueryText != "" {
+		if err := validateQueryText(req.QueryText); err != nil {
+			return err
+		}
+	}
+	if len(req.QueryEmbedding) > 0 {
+		if err := req.Metric.validate(); err != nil {
+			return err
+		}
+		if err := validateVector(req.QueryEmbedding); err != nil {
+			return fmt.Errorf("query_embedding: %w", err)
+		}
+	} else if req.Recency != nil {
+		return errRecencyNeedsVector
+	}
+	return req.Recency.validate(req.Metric)
+}
+
+// AssembleContext ranks anchors like RetrieveByAnchors, RetrieveByText or
+// RetrieveBySimilarity, then packs greedily in rank order: each anchor
+// followed by its buffer neighbours, nearest first. An event costs its token
+// span; events without payload text are skipped at no cost. The packed events
+// are returned in session order, so the text reads as it happened.
+func (s *InMemoryStore) AssembleContext(ctx context.Context, req ContextRequest) (result AssembledContext, err error) {
+	_, span := tracer.Start(ctx, "memory.AssembleContext", trace.WithAttributes(
+		attribute.Int("token_budget", req.TokenBudget),
+		attribute.Int("top_k", req.TopK),
+	))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("tokens_used", result.TokensUsed),
+			attribute.Int("dropped", len(result.Dropped)),
+		)
+		endSpan(span, err)
+	}()
+
+	if err := req.validate(); err != nil {
+		return AssembledContext{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
+
+	result = AssembledContext{Events: []Event{}, TokenBudget: req.TokenBudget, Dropped: []DroppedEvent{}}
+	key := sessionKey{tenantID: req.TenantID, sessionID: req.SessionID}
+	session, ok := s.sessions[key]
+	if !ok || len(session.ordered) == 0 {
+		return result, nil
+	}
+	session.touch(s.now())
+
+	var anchors []int
+	switch {
+	case len(req.AnchorEventIDs) > 0:
+		anchors = session.anchorIndexes(req.AnchorEventIDs, min(req.TopK, len(req.AnchorEventIDs), len(session.ordered)))
+	case req.QueryText != "":
+		if anchors, _, err = s.textAnchors(key, session, req.QueryText, req.QueryEmbedding, req.Metric, req.TopK, req.Recency); err != nil {
+			return AssembledContext{}, err
+		}
+	default:
+		if session.embeddingDim == 0 {
+			return result, nil
+		}
+		if len(req.QueryEmbedding) != session.embeddingDim {
+			return AssembledContext{}, errQueryEmbeddingDimMismatch
+		}
+		var useIndex bool
+		anchors, _, useIndex = s.similarAnchors(session, req.QueryEmbedding, req.Metric, req.TopK, req.Recency)
+		span.SetAttributes(attribute.Bool("index", useIndex))
+	}
+
+	packed := packContext(session.ordered, anchors, req.BufferBefore, req.BufferAfter, req.TokenBudget, &result)
+	texts := make([]string, len(packed))
+	for i, index := range packed {
+		event := session.ordered[index]
+		result.Events = append(result.Events, event.clone())
+		texts[i] = event.Payload.Text
+	}
+	result.Text = strings.Join(texts, req.Separator)
+
+	if s.opts.Observer != nil {
+		s.opts.Observer.ObserveRetrieval(req.TenantID, req.TopK, len(anchors), len(result.Events))
+	}
+	return result, nil
+}
+
+// packContext fills budget from the anchors in rank order and returns the
+// packed positions in session order. It records the tokens used and every
+// candidate it left out, in session order, in result.
+func packContext(ordered []Event, anchors []int, bufferBefore, bufferAfter, budget int, result *AssembledContext) []int {
+	packed := make(map[int]struct{})
+	dropped := make(map[int]DropReason)
+	// offer packs the event unless it was already packed or ruled out, and
+	// returns why it was left out, or "" if it is packed.
+	offer := func(index int) DropReason {
+		if _, ok := packed[index]; ok {
+			return ""
+		}
+		if reason, ok := dropped[index]; ok && reason != DropAnchorDropped {
+			return reason
+		}
+		event := ordered[index]
+		switch {
+		case event.Payload == nil || event.Payload.Text == "":
+			dropped[index] = DropNoText
+		case result.TokensUsed+event.tokenCount() > budget:
+			// The budget only shrinks, so this is final.
+			dropped[index] = DropTokenBudget
+		default:
+			delete(dropped, index)
+			packed[index] = struct{}{}
+			result.TokensUsed += event.tokenCount()
+			return ""
+		}
+		return dropped[index]
+	}
+
+	for _, anchor := range anchors {
+		neighbours := bufferNeighbours(len(ordered), anchor, bufferBefore, bufferAfter)
+		if offer(anchor) == DropTokenBudget {
+			for _, index := range neighbours {
+				_, isPacked := packed[index]
+				if _, ok := dropped[index]; !ok && !isPacked {
+					dropped[index] = DropAnchorDropped
+				}
+			}
+			continue
+		}
+		for _, index := range neighbours {
+			offer(index)
+		}
+	}
+
+	for _, index := range slices.Sorted(maps.Keys(dropped)) {
+		result.Dropped = append(result.Dropped, DroppedEvent{
+			EventID: ordered[index].EventID,
+			Tokens:  ordered[index].tokenCount(),
+			Reason:  dropped[index],
+		})
+	}
+	return slices.Sorted(maps.Keys(packed))
+}
+
+// bufferNeighbours lists the buffer positions around anchor, nearest first
+// and, at equal distance, the earlier one first.
+func bufferNeighbours(n, anchor, bufferBefore, bufferAfter int) []int {
+	var neighbours []int
+	for distance := 1; distance <= max(bufferBefore, bufferAfter); distance++ {
+		if distance <= bufferBefore && anchor-distance >= 0 {
+			neighbours = append(neighbours, anchor-distance)
+		}
+		if distance <= bufferAfter && anchor+distance < n {
+			neighbours = append(neighbours, anchor+distance)
+		}
+	}
+	return neighbours
+}
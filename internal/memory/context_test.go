@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// contextEvents is a session of six 10-token events; evt_2 has no text and
+// evt_4 and evt_5 carry vectors.
+func contextEvents(t *testing.T) []Event {
+	t.Helper()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var events []Event
+	for i, text := range []string{"zero", "one", "", "three", "four", "five"} {
+		event := mustEvent(t, "evt_"+string(rune('0'+i)), "tenant_1", "session_1", i*10, i*10+10, now)
+		if text != "" {
+			event.Payload = &Payload{Text: text}
+		}
+		events = append(events, event)
+	}
+	events[4].Embeddings = [][]float32{{1, 0}}
+	events[5].Embeddings = [][]float32{{0, 1}}
+	return events
+}
+
+func TestAssembleContextPacksInRankOrder(t *testing.T) {
+	store := mustStore(t, contextEvents(t)...)
+
+	cases := []struct {
+		name    string
+		req     ContextRequest
+		text    string
+		used    int
+		dropped []DroppedEvent
+	}{
+		{
+			name: "fits",
+			req:  ContextRequest{AnchorEventIDs: []string{"evt_3"}, TopK: 1, BufferBefore: 1, BufferAfter: 1, TokenBudget: 100},
+			text: "three|four",
+			used: 20,
+			dropped: []DroppedEvent{
+				{EventID: "evt_2", Tokens: 10, Reason: DropNoText},
+			},
+		},
+		{
+			// The nearer neighbour wins the last slot; evt_0 is ranked second
+			// but its anchor no longer fits, so its neighbour is not tried.
+			name: "budget",
+			req:  ContextRequest{AnchorEventIDs: []string{"evt_4", "evt_0"}, TopK: 2, BufferBefore: 2, BufferAfter: 1, TokenBudget: 20},
+			text: "three|four",
+			used: 20,
+			dropped: []DroppedEvent{
+				{EventID: "evt_0", Tokens: 10, Reason: DropTokenBudget},
+				{EventID: "evt_1", Tokens: 10, Reason: DropAnchorDropped},
+				{EventID: "evt_2", Tokens: 10, Reason: DropNoText},
+				{EventID: "evt_5", Tokens: 10, Reason: DropTokenBudget},
+			},
+		},
+		{
+			name:    "similarity",
+			req:     ContextRequest{QueryEmbedding: []float32{0, 1}, Metric: SimilarityCosine, TopK: 1, BufferBefore: 1, TokenBudget: 15},
+			text:    "five",
+			used:    10,
+			dropped: []DroppedEvent{{EventID: "evt_4", Tokens: 10, Reason: DropTokenBudget}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc.req.TenantID, tc.req.SessionID, tc.req.Separator = "tenant_1", "session_1", "|"
+		got, err := store.AssembleContext(context.Background(), tc.req)
+		if err != nil {
+			t.Fatalf("%s: assemble: %v", tc.name, err)
+		}
+		if got.Text != tc.text || got.TokensUsed != tc.used || got.TokenBudget != tc.req.TokenBudget {
+			t.Fatalf("%s: expected %q using %d tokens, got %q using %d", tc.name, tc.text, tc.used, got.Text, got.TokensUsed)
+		}
+		if !slices.Equal(got.Dropped, tc.dropped) {
+			t.Fatalf("%s: expected dropped %+v, got %+v", tc.name, tc.dropped, got.Dropped)
+		}
+	}
+}
+
+func TestAssembleContextRejectsInvalidRequests(t *testing.T) {
+	store := mustStore(t, contextEvents(t)...)
+
+	for name, req := range map[string]ContextRequest{
+		"no budget":      {AnchorEventIDs: []string{"evt_1"}, TopK: 1},
+		"no query":       {TopK: 1, TokenBudget: 10},
+		"both queries":   {AnchorEventIDs: []string{"evt_1"}, QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 1, TokenBudget: 10},
+		"no top_k":       {AnchorEventIDs: []string{"evt_1"}, TokenBudget: 10},
+		"dimension":      {QueryEmbedding: []float32{1, 0, 0}, Metric: SimilarityCosine, TopK: 1, TokenBudget: 10},
+		"unknown metric": {QueryEmbedding: []float32{1, 0}, Metric: "l2", TopK: 1, TokenBudget: 10},
+	} {
+		req.TenantID, req.SessionID = "tenant_1", "session_1"
+		if _, err := store.AssembleContext(context.Background(), req); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	got, err := store.AssembleContext(context.Background(), ContextRequest{
+		TenantID: "tenant_1", SessionID: "missing", AnchorEventIDs: []string{"evt_1"}, TopK: 1, TokenBudget: 10,
+	})
+	if err != nil || got.Events == nil || len(got.Events) != 0 || got.Dropped == nil {
+		t.Fatalf("expected an empty result for a missing session, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CrossSessionQuery asks for the events of a tenant most similar to a query,
+// wherever they were recorded. SessionIDs and Labels narrow the sessions
+// searched; when both are set a session must satisfy both, and when neither
+// is set every session of the tenant is searched.
+type CrossSessionQuery struct {
+	TenantID   string
+	SessionIDs []string
+	// Labels selects sessions carrying every listed label.
+	Labels         map[string]string
+	QueryEmbedding []float32
+	Metric         SimilarityMetric
+	TopK           int
+	BufferBefore   int
+	BufferAfter    int
+	// Recency ages events by the store clock, or with the tokens basis from
+	// the end of their own session.
+	Recency *Recency
+}
+
+// SessionResults holds the events retrieved from one session, in session
+// order.
+type SessionResults struct {
+	SessionID string           `json:"session_id"`
+	Events    []RetrievedEvent `json:"events"`
+}
+
+func (query CrossSessionQuery) validate() error {
+	if query.TenantID == "" {
+		return errTenantIDRequired
+	}
+	if query.TopK <= 0 {
+		return errRetrieveTopKNonPositive
+	}
+	if query.BufferBefore < 0 || query.BufferAfter < 0 {
+		return errRetrieveBufferNegative
+	}
+	if err := validateLabels(query.Labels); err != nil {
+		return err
+	}
+	if err := query.Metric.validate(); err != nil {
+		return err
+	}
+	if err := validateVector(query.QueryEmbedding); err != nil {
+		return fmt.Errorf("query_embedding: %w", err)
+	}
+	return query.Recency.validate(query.Metric)
+}
+
+// RetrieveAcrossSessions picks the TopK events of the tenant most similar to
+// the query across every selected session, then expands each with its
+// contiguity buffers inside its own session. Results are grouped by session,
+// the session holding the best match first, and provenance ranks are global.
+// Sessions whose embeddings have another dimension than the query are
+// skipped, as are sessions that do not exist.
+func (s *InMemoryStore) RetrieveAcrossSessions(ctx context.Context, query CrossSessionQuery) (_ []SessionResults, err error) {
+	_, span := tracer.Start(ctx, "memory.RetrieveAcrossSessions", trace.WithAttributes(
+		attribute.String("metric", string(query.Metric)),
+		attribute.Int("top_k", query.TopK),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
+
+	sessions := s.selectSessionsLocked(query)
+	span.SetAttributes(attribute.Int("sessions", len(sessions)))
+
+	type hit struct {
+		session *sessionEvents
+		index   int
+		score   float64
+	}
+	var hits []hit
+	for _, session := range sessions {
+		anchors, scores, _ := s.similarAnchors(session, query.QueryEmbedding, query.Metric, query.TopK, query.Recency)
+		for i, anchor := range anchors {
+			hits = append(hits, hit{session: session, index: anchor, score: scores[i]})
+		}
+	}
+	// Sessions are visited in id order, so the stable sort breaks score ties
+	// by session id and then by position in the session.
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].score > hits[j].score
+	})
+	hits = hits[:min(query.TopK, len(hits))]
+
+	// Group the hits by session, keeping the order in which each session
+	// first appears in the ranking.
+	type group struct {
+		session *sessionEvents
+		anchors []int
+		scores  []float64
+		ranks   []int
+	}
+	var groups []*group
+	bySession := make(map[*sessionEvents]*group)
+	for rank, h := range hits {
+		g, ok := bySession[h.session]
+		if !ok {
+			g = &group{session: h.session}
+			bySession[h.session] = g
+			groups = append(groups, g)
+		}
+		g.anchors = append(g.anchors, h.index)
+		g.scores = append(g.scores, h.score)
+		g.ranks = append(g.ranks, rank+1)
+	}
+
+	results := make([]SessionResults, 0, len(groups))
+	returned := 0
+	for _, g := range groups {
+		g.session.touch(s.now())
+		events := expandAnchors(g.session.ordered, g.anchors, g.scores, query.BufferBefore, query.BufferAfter)
+		// expandAnchors ranks within the session; map back to the global rank.
+		for i := range events {
+			events[i].Retrieval.Rank = g.ranks[events[i].Retrieval.Rank-1]
+		}
+		returned += len(events)
+		results = append(results, SessionResults{SessionID: g.session.ordered[0].SessionID, Events: events})
+	}
+
+	if s.opts.Observer != nil {
+		s.opts.Observer.ObserveRetrieval(query.TenantID, query.TopK, len(hits), returned)
+	}
+	return results, nil
+}
+
+// selectSessionsLocked returns the tenant's sessions that match query and
+// hold embeddings of the query's dimension, ordered by session id. The
+// caller must hold s.mu.
+func (s *InMemoryStore) selectSessionsLocked(query CrossSessionQuery) []*sessionEvents {
+	sessionIDs := s.tenantSessions[query.TenantID]
+	if len(query.SessionIDs) > 0 {
+		sessionIDs = slices.Clone(query.SessionIDs)
+		slices.Sort(sessionIDs)
+		sessionIDs = slices.Compact(sessionIDs)
+	}
+
+	var sessions []*sessionEvents
+	for _, sessionID := range sessionIDs {
+		session, ok := s.sessions[sessionKey{tenantID: query.TenantID, sessionID: sessionID}]
+		if !ok || session.embeddingDim != len(query.QueryEmbedding) || !matchesLabels(session.labels, query.Labels) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
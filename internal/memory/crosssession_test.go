@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+// crossSessionEvents is five sessions of three events each; only the middle
+// event carries a vector.
+func crossSessionEvents(t *testing.T) []Event {
+	t.Helper()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var events []Event
+	for _, session := range []struct {
+		tenantID, sessionID string
+		vector              []float32
+	}{
+		{"tenant_1", "session_a", []float32{1, 0}},
+		{"tenant_1", "session_b", []float32{0.8, 0.6}},
+		{"tenant_1", "session_c", []float32{0, 1}},
+		{"tenant_1", "session_d", []float32{1, 0, 0}},
+		{"tenant_2", "session_a", []float32{1, 0}},
+	} {
+		for i := range 3 {
+			event := mustEvent(t, fmt.Sprintf("evt_%d", i), session.tenantID, session.sessionID, i*10, i*10+10, now)
+			if i == 1 {
+				event.Embeddings = [][]float32{session.vector}
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestRetrieveAcrossSessionsGroupsBySession(t *testing.T) {
+	store := mustStore(t, crossSessionEvents(t)...)
+	if err := store.SetSessionLabels(context.Background(), "tenant_1", "session_b", map[string]string{"user": "u1", "channel": "chat"}); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+	if err := store.SetSessionLabels(context.Background(), "tenant_1", "session_c", map[string]string{"user": "u1"}); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		query CrossSessionQuery
+		want  map[string]int // session id -> global rank of its anchor
+		order []string
+	}{
+		{
+			name:  "whole tenant",
+			query: CrossSessionQuery{TopK: 2},
+			order: []string{"session_a", "session_b"},
+			want:  map[string]int{"session_a": 1, "session_b": 2},
+		},
+		{
+			name:  "explicit sessions",
+			query: CrossSessionQuery{SessionIDs: []string{"session_c", "session_b", "missing", "session_c"}, TopK: 5},
+			order: []string{"session_b", "session_c"},
+			want:  map[string]int{"session_b": 1, "session_c": 2},
+		},
+		{
+			name:  "labels",
+			query: CrossSessionQuery{Labels: map[string]string{"user": "u1", "channel": "chat"}, TopK: 5},
+			order: []string{"session_b"},
+			want:  map[string]int{"session_b": 1},
+		},
+		{
+			name:  "ids and labels",
+			query: CrossSessionQuery{SessionIDs: []string{"session_a", "session_c"}, Labels: map[string]string{"user": "u1"}, TopK: 5},
+			order: []string{"session_c"},
+			want:  map[string]int{"session_c": 1},
+		},
+		{
+			name:  "no match",
+			query: CrossSessionQuery{Labels: map[string]string{"user": "u2"}, TopK: 5},
+			order: []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		tc.query.TenantID, tc.query.QueryEmbedding, tc.query.Metric = "tenant_1", []float32{1, 0}, SimilarityCosine
+		tc.query.BufferBefore, tc.query.BufferAfter = 1, 0
+		got, err := store.RetrieveAcrossSessions(context.Background(), tc.query)
+		if err != nil {
+			t.Fatalf("%s: retrieve: %v", tc.name, err)
+		}
+
+		order := make([]string, len(got))
+		for i, results := range got {
+			order[i] = results.SessionID
+			if ids := retrievedIDs(results.Events); !slices.Equal(ids, []string{"evt_0", "evt_1"}) {
+				t.Fatalf("%s: expected %s to return evt_0 and evt_1, got %v", tc.name, results.SessionID, ids)
+			}
+			for _, event := range results.Events {
+				if event.SessionID != results.SessionID || event.TenantID != "tenant_1" {
+					t.Fatalf("%s: expected events of %s, got %+v", tc.name, results.SessionID, event.Event)
+				}
+				if event.Retrieval.Rank != tc.want[results.SessionID] {
+					t.Fatalf("%s: expected %s rank %d, got %d", tc.name, results.SessionID, tc.want[results.SessionID], event.Retrieval.Rank)
+				}
+			}
+		}
+		if !slices.Equal(order, tc.order) {
+			t.Fatalf("%s: expected sessions %v, got %v", tc.name, tc.order, order)
+		}
+	}
+}
+
+func TestRetrieveAcrossSessionsRejectsInvalidQueries(t *testing.T) {
+	store := NewStore()
+
+	for name, query := range map[string]CrossSessionQuery{
+		"no tenant":      {QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 1},
+		"no top_k":       {TenantID: "tenant_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine},
+		"no query":       {TenantID: "tenant_1", Metric: SimilarityCosine, TopK: 1},
+		"unknown metric": {TenantID: "tenant_1", QueryEmbedding: []float32{1, 0}, Metric: "l2", TopK: 1},
+		"bad label":      {TenantID: "tenant_1", Labels: map[string]string{"": "x"}, QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 1},
+	} {
+		if _, err := store.RetrieveAcrossSessions(context.Background(), query); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDurableStoreReplaysSessionLabels(t *testing.T) {
+	dir := t.TempDir()
+	labels := map[string]string{"user": "u1"}
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.SetSessionLabels(context.Background(), "tenant_1", "session_1", labels); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	for _, snapshot := range []bool{false, true} {
+		reopened := mustOpenDurableStore(t, dir)
+		if summary, ok := reopened.GetSession("tenant_1", "session_1"); !ok || !maps.Equal(summary.Labels, labels) {
+			t.Fatalf("snapshot=%v: expected the labels to survive a restart, got %+v", snapshot, summary)
+		}
+		if err := reopened.Snapshot(); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+}
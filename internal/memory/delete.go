@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Deletion reports what a delete removed. A zero Deletion means nothing
+// matched.
+type Deletion struct {
+	Sessions int
+	Events   int
+}
+
+func (s *InMemoryStore) DeleteEvent(ctx context.Context, tenantID, sessionID, eventID string) (Deletion, error) {
+	return s.deleteEvent(ctx, tenantID, sessionID, eventID, nil)
+}
+
+func (s *InMemoryStore) DeleteSession(ctx context.Context, tenantID, sessionID string) (Deletion, error) {
+	return s.deleteSession(ctx, tenantID, sessionID, nil)
+}
+
+func (s *InMemoryStore) PurgeTenant(ctx context.Context, tenantID string) (Deletion, error) {
+	return s.purgeTenant(ctx, tenantID, nil)
+}
+
+// deleteEvent removes one event. Like appendMany, commit runs once the event
+// is known to exist and before any state changes; it is skipped when nothing
+// matches so misses are never logged.
+func (s *InMemoryStore) deleteEvent(
+	ctx context.Context,
+	tenantID, sessionID, eventID string,
+	commit func() error,
+) (_ Deletion, err error) {
+	_, span := tracer.Start(ctx, "memory.DeleteEvent")
+	defer func() { endSpan(span, err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	session, ok := s.sessions[key]
+	if !ok {
+		return Deletion{}, nil
+	}
+	if _, ok := session.byID[eventID]; !ok {
+		return Deletion{}, nil
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return Deletion{}, err
+		}
+	}
+
+	return Deletion{Events: s.removeEventsLocked(key, session, []string{eventID})}, nil
+}
+
+// removeEventsLocked removes the listed events from a session and returns how
+// many it found. The caller must hold s.mu.
+func (s *InMemoryStore) removeEventsLocked(key sessionKey, session *sessionEvents, eventIDs []string) int {
+	remove := make(map[string]struct{}, len(eventIDs))
+	for _, eventID := range eventIDs {
+		if _, ok := session.byID[eventID]; ok {
+			remove[eventID] = struct{}{}
+		}
+	}
+	if len(remove) == 0 {
+		return 0
+	}
+
+	usage := s.tenantUsage(key.tenantID)
+	session.ordered = slices.DeleteFunc(session.ordered, func(event Event) bool {
+		if _, ok := remove[event.EventID]; !ok {
+			return false
+		}
+		delete(session.byID, event.EventID)
+		usage.events--
+		usage.payloadBytes -= event.payloadBytes()
+		s.unindexTextLocked(event)
+		s.unindexEventLocked(session, event)
+		return true
+	})
+	session.recomputeExtents()
+	s.compactIndexLocked(session)
+	return len(remove)
+}
+
+// unindexEventLocked turns the vectors of event into tombstones in the
+// session index, dropping the index once no live vector is left. The caller
+// must hold s.mu.
+func (s *InMemoryStore) unindexEventLocked(session *sessionEvents, event Event) {
+	if session.index == nil || len(event.Embeddings) == 0 {
+		return
+	}
+	session.index.remove(event.EventID)
+	if session.index.len() == 0 {
+		session.index = nil
+		session.embeddingDim = 0
+	}
+}
+
+// compactIndexLocked rebuilds the session index once tombstones outnumber
+// live vectors, which keeps searches from wading through dead nodes while
+// spreading the rebuild cost over many deletions. The caller must hold s.mu.
+func (s *InMemoryStore) compactIndexLocked(session *sessionEvents) {
+	if session.index != nil && session.index.tombstones > session.index.len() {
+		s.reindexSessionLocked(session)
+	}
+}
+
+// reindexSessionLocked rebuilds the session index from the stored events,
+// dropping every tombstone. Levels are derived from event ids, so the
+// rebuilt graph is the same on every replica and replay. The caller must
+// hold s.mu.
+func (s *InMemoryStore) reindexSessionLocked(session *sessionEvents) {
+	session.index = nil
+	session.embeddingDim = 0
+	for _, event := range session.ordered {
+		if dim := event.embeddingDim(); dim != 0 {
+			session.embeddingDim = dim
+			s.indexEvent(session, event)
+		}
+	}
+}
+
+// deleteSession removes a session with its events, surprise history and any
+// open stream tail.
+func (s *InMemoryStore) deleteSession(
+	ctx context.Context,
+	tenantID, sessionID string,
+	commit func() error,
+) (_ Deletion, err error) {
+	_, span := tracer.Start(ctx, "memory.DeleteSession")
+	defer func() { endSpan(span, err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	if _, ok := s.sessions[key]; !ok {
+		return Deletion{}, nil
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return Deletion{}, err
+		}
+	}
+
+	return s.removeSessionsLocked([]sessionKey{key}), nil
+}
+
+// purgeTenant removes every session of a tenant.
+func (s *InMemoryStore) purgeTenant(ctx context.Context, tenantID string, commit func() error) (_ Deletion, err error) {
+	_, span := tracer.Start(ctx, "memory.PurgeTenant")
+	defer func() { endSpan(span, err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	var keys []sessionKey
+	for _, sessionID := range s.tenantSessions[tenantID] {
+		keys = append(keys, sessionKey{tenantID: tenantID, sessionID: sessionID})
+	}
+	if len(keys) == 0 {
+		return Deletion{}, nil
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return Deletion{}, err
+		}
+	}
+
+	deletion := s.removeSessionsLocked(keys)
+	span.SetAttributes(attribute.Int("sessions", deletion.Sessions), attribute.Int("events", deletion.Events))
+	return deletion, nil
+}
+
+// removeSessionsLocked drops sessions and their usage. A tenant left with no
+// sessions is forgotten entirely, so it disappears from Stats. The caller
+// must hold s.mu.
+func (s *InMemoryStore) removeSessionsLocked(keys []sessionKey) Deletion {
+	var deletion Deletion
+	for _, key := range keys {
+		session := s.sessions[key]
+		delete(s.sessions, key)
+		sessionIDs := s.tenantSessions[key.tenantID]
+		if i, ok := slices.BinarySearch(sessionIDs, key.sessionID); ok {
+			sessionIDs = slices.Delete(sessionIDs, i, i+1)
+		}
+		if len(sessionIDs) == 0 {
+			delete(s.tenantSessions, key.tenantID)
+		} else {
+			s.tenantSessions[key.tenantID] = sessionIDs
+		}
+
+		usage := s.tenantUsage(key.tenantID)
+		usage.sessions--
+		usage.events -= len(session.ordered)
+		for _, event := range session.ordered {
+			usage.payloadBytes -= event.payloadBytes()
+			s.unindexTextLocked(event)
+		}
+		if usage.sessions == 0 {
+			delete(s.usage, key.tenantID)
+		}
+
+		deletion.Sessions++
+		deletion.Events += len(session.ordered)
+	}
+	return deletion
+}
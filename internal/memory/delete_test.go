@@ -0,0 +1,287 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStoreDeleteEventKeepsSessionConsistent(t *testing.T) {
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Index: IndexOptions{MinVectors: 1}})
+
+	events := make([]Event, 3)
+	for i := range events {
+		events[i] = mustEvent(t, []string{"evt_a", "evt_b", "evt_c"}[i], "tenant_1", "session_1", i, i+1, now)
+		events[i].Embeddings = [][]float32{{float32(i + 1), 1}}
+		events[i].Payload = &Payload{Text: "hi"}
+	}
+	if err := store.AppendMany(context.Background(), events); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	deletion, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", "evt_b")
+	if err != nil || deletion != (Deletion{Events: 1}) {
+		t.Fatalf("expected one deleted event, got %+v, %v", deletion, err)
+	}
+	if _, ok := store.Get("tenant_1", "session_1", "evt_b"); ok {
+		t.Fatalf("expected deleted event to be gone")
+	}
+	if got := store.ListBySession("tenant_1", "session_1"); len(got) != 2 || got[0].EventID != "evt_a" || got[1].EventID != "evt_c" {
+		t.Fatalf("expected [evt_a evt_c], got %#v", got)
+	}
+
+	found, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{2, 1}, Metric: SimilarityCosine, TopK: 3})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected index to hold the two remaining events, got %d", len(found))
+	}
+	for _, event := range found {
+		if event.EventID == "evt_b" {
+			t.Fatalf("expected deleted event to be unlinked from the index")
+		}
+	}
+
+	if got := store.Stats().Tenants["tenant_1"]; got != (TenantStats{Sessions: 1, Events: 2, PayloadBytes: 20}) {
+		t.Fatalf("expected stats {1 2 20}, got %+v", got)
+	}
+	if err := store.Append(context.Background(), events[1]); err != nil {
+		t.Fatalf("expected deleted event id to be reusable, got %v", err)
+	}
+
+	deletion, err = store.DeleteEvent(context.Background(), "tenant_1", "session_1", "missing")
+	if err != nil || deletion != (Deletion{}) {
+		t.Fatalf("expected no deletion for a missing event, got %+v, %v", deletion, err)
+	}
+}
+
+func TestStoreDeleteEventTombstonesIndexUntilCompaction(t *testing.T) {
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Index: IndexOptions{MinVectors: 1}})
+
+	events := make([]Event, 5)
+	for i := range events {
+		events[i] = mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i, i+1, now)
+		events[i].Embeddings = [][]float32{{float32(i + 1), 1}}
+	}
+	if err := store.AppendMany(context.Background(), events); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	session := store.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}]
+	index := session.index
+
+	// A single deletion leaves a tombstone instead of rebuilding the graph.
+	if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", "evt_4"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if session.index != index || index.tombstones != 1 || index.len() != 4 {
+		t.Fatalf("expected one tombstone in the same graph, got %d tombstones and %d live", index.tombstones, index.len())
+	}
+	found, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{5, 1}, Metric: SimilarityCosine, TopK: 5})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if ids := retrievedIDs(found); len(ids) != 4 || slices.Contains(ids, "evt_4") {
+		t.Fatalf("expected the four remaining events, got %v", ids)
+	}
+	snapshots := store.snapshotSessionsLocked()
+	if len(snapshots) != 1 || snapshots[0].Index != nil {
+		t.Fatalf("expected a graph with tombstones to be left out of snapshots")
+	}
+
+	// Once tombstones outnumber live vectors the graph is rebuilt without them.
+	for _, eventID := range []string{"evt_3", "evt_2"} {
+		if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", eventID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	}
+	if session.index == index || session.index.tombstones != 0 || session.index.len() != 2 {
+		t.Fatalf("expected a compacted graph of two vectors, got %d tombstones and %d live", session.index.tombstones, session.index.len())
+	}
+
+	for _, eventID := range []string{"evt_1", "evt_0"} {
+		if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", eventID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	}
+	if session.index != nil || session.embeddingDim != 0 {
+		t.Fatalf("expected the index to go with the last vector")
+	}
+}
+
+func TestStoreDeleteSessionAndPurgeTenant(t *testing.T) {
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Quotas: QuotaPolicy{Default: Quota{MaxSessionsPerTenant: 2}}})
+
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 1, 2, now),
+		mustEvent(t, "evt_1", "tenant_2", "session_1", 0, 1, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_2",
+		Surprise:       []float64{0.1, 0.9},
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+		Stream:         true,
+	}); err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+
+	deletion, err := store.DeleteSession(context.Background(), "tenant_1", "session_2")
+	if err != nil || deletion.Sessions != 1 {
+		t.Fatalf("expected one deleted session, got %+v, %v", deletion, err)
+	}
+	// The stream tail went with the session, so a plain segmentation is allowed
+	// and the freed session slot can be reused.
+	if _, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_3",
+		Surprise:       []float64{0.1},
+		Threshold:      0.5,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	}); err != nil {
+		t.Fatalf("segment after delete: %v", err)
+	}
+
+	deletion, err = store.PurgeTenant(context.Background(), "tenant_1")
+	if err != nil || deletion != (Deletion{Sessions: 2, Events: 3}) {
+		t.Fatalf("expected {2 3} deleted, got %+v, %v", deletion, err)
+	}
+	stats := store.Stats()
+	if _, ok := stats.Tenants["tenant_1"]; ok {
+		t.Fatalf("expected purged tenant to leave stats, got %+v", stats)
+	}
+	if got := stats.Tenants["tenant_2"]; got.Events != 1 {
+		t.Fatalf("expected other tenant untouched, got %+v", got)
+	}
+}
+
+func TestDurableStoreDeletionIsDurableAndErasedBySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	secret := "my-secret-phone-number"
+
+	store := mustOpenDurableStore(t, dir)
+	private := mustEvent(t, "evt_private", "tenant_1", "session_1", 0, 1, now)
+	private.Payload = &Payload{Text: secret}
+	if err := store.AppendMany(context.Background(), []Event{
+		private,
+		mustEvent(t, "evt_public", "tenant_1", "session_1", 1, 2, now),
+		mustEvent(t, "evt_other", "tenant_1", "session_2", 0, 1, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", "evt_private"); err != nil {
+		t.Fatalf("delete event: %v", err)
+	}
+	if _, err := store.DeleteSession(context.Background(), "tenant_1", "session_2"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	if _, ok := reopened.Get("tenant_1", "session_1", "evt_private"); ok {
+		t.Fatalf("expected tombstone to survive reopen")
+	}
+	if got := reopened.ListBySession("tenant_1", "session_2"); len(got) != 0 {
+		t.Fatalf("expected deleted session to stay deleted, got %#v", got)
+	}
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != 1 {
+		t.Fatalf("expected the remaining event to survive, got %#v", got)
+	}
+
+	// The deletions ask for a background snapshot, which may or may not have
+	// run before Close; an explicit one erases the secret either way.
+	if err := reopened.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if dataDirContains(t, dir, secret) {
+		t.Fatalf("expected compaction to erase the deleted event from disk")
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	final := mustOpenDurableStore(t, dir)
+	defer final.Close()
+	if got := final.ListBySession("tenant_1", "session_1"); len(got) != 1 || got[0].EventID != "evt_public" {
+		t.Fatalf("expected only evt_public after compaction, got %#v", got)
+	}
+}
+
+func dataDirContains(t *testing.T, dir, needle string) bool {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read data dir: %v", err)
+	}
+	for _, entry := range entries {
+		contents, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed by a concurrent compaction.
+			continue
+		}
+		if err != nil {
+			t.Fatalf("read %s: %v", entry.Name(), err)
+		}
+		if bytes.Contains(contents, []byte(needle)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDurableStoreDeletionTriggersEarlySnapshot(t *testing.T) {
+	now := time.Date(2026, 2, 22, 9, 0, 0, 0, time.UTC)
+	secret := "delete-me-soon"
+
+	// Without periodic snapshots a deletion must still reach the disk.
+	for _, interval := range []time.Duration{time.Hour, 0} {
+		dir := t.TempDir()
+		store, err := OpenDurableStore(dir, DurableOptions{SnapshotInterval: interval})
+		if err != nil {
+			t.Fatalf("open durable store: %v", err)
+		}
+
+		event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now)
+		event.Payload = &Payload{Text: secret}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		if _, err := store.PurgeTenant(context.Background(), "tenant_1"); err != nil {
+			t.Fatalf("purge: %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for dataDirContains(t, dir, secret) {
+			if time.Now().After(deadline) {
+				t.Fatalf("interval %v: expected the purge to be compacted away well before the snapshot interval", interval)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+}
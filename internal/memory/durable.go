@@ -0,0 +1,424 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultSnapshotRetain = 2
+
+var errDataDirRequired = errors.New("data directory is required")
+
+// DurableOptions tunes snapshotting for a DurableStore.
+type DurableOptions struct {
+	// Store configures the in-memory state the log is replayed into.
+	Store StoreOptions
+	// SnapshotInterval is how often a snapshot is taken in the background.
+	// Zero disables periodic snapshots; Snapshot can still be called directly.
+	// Either way, an accepted deletion triggers a background snapshot, which
+	// is what erases the deleted data from disk.
+	SnapshotInterval time.Duration
+	// SnapshotRetain is how many snapshots are kept on disk. Log segments are
+	// only deleted once the oldest retained snapshot covers them, so an older
+	// snapshot plus the log can stand in for a damaged newer one. Defaults to 2.
+	// The first snapshot after a deletion keeps only itself, so deleted data
+	// leaves the disk as soon as it is taken.
+	SnapshotRetain int
+	// OnSnapshotError receives failures from background snapshots.
+	OnSnapshotError func(error)
+}
+
+// DurableStore is an InMemoryStore whose accepted batches are first written to
+// an fsync'd write-ahead log in dataDir. On open the latest valid snapshot is
+// loaded and only the log tail behind it is replayed, so state survives
+// restarts and crashes while cold start stays bounded.
+type DurableStore struct {
+	mem     *InMemoryStore
+	dataDir string
+	opts    DurableOptions
+
+	logMu sync.Mutex
+	log   *writeAheadLog
+
+	// tombstoneSeq is the sequence number of the newest deletion record.
+	// It is guarded by logMu.
+	tombstoneSeq uint64
+
+	// snapshotMu serializes snapshots; snapshotSeq is the newest one on disk.
+	snapshotMu  sync.Mutex
+	snapshotSeq uint64
+
+	// compact asks the snapshot loop for an early snapshot after a deletion.
+	compact  chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ Store = (*DurableStore)(nil)
+
+func OpenDurableStore(dataDir string, opts DurableOptions) (*DurableStore, error) {
+	if dataDir == "" {
+		return nil, errDataDirRequired
+	}
+	if opts.SnapshotRetain <= 0 {
+		opts.SnapshotRetain = defaultSnapshotRetain
+	}
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	store := &DurableStore{
+		mem:     NewStoreWithOptions(opts.Store),
+		dataDir: dataDir,
+		opts:    opts,
+		compact: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	snapshot, ok, err := loadLatestSnapshot(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		store.mem.restore(snapshot.Sessions)
+		store.snapshotSeq = snapshot.Seq
+	}
+
+	log, err := openWriteAheadLog(dataDir, store.snapshotSeq, store.applyRecord)
+	if err != nil {
+		return nil, err
+	}
+	store.log = log
+
+	go store.snapshotLoop(opts.SnapshotInterval)
+
+	return store, nil
+}
+
+func (s *DurableStore) applyRecord(record walRecord) error {
+	switch record.Op {
+	case walOpAppend:
+		return s.mem.appendMany(context.Background(), record.Events, false, nil)
+	case walOpSegment:
+		return s.mem.replaySegment(record.TenantID, record.SessionID, record.Events, record.Surprise, record.Pending)
+	case walOpDeleteEvent:
+		_, err := s.mem.deleteEvent(context.Background(), record.TenantID, record.SessionID, record.EventID, nil)
+		s.tombstoneSeq = record.Seq
+		return err
+	case walOpDeleteSession:
+		_, err := s.mem.deleteSession(context.Background(), record.TenantID, record.SessionID, nil)
+		s.tombstoneSeq = record.Seq
+		return err
+	case walOpPurgeTenant:
+		_, err := s.mem.purgeTenant(context.Background(), record.TenantID, nil)
+		s.tombstoneSeq = record.Seq
+		return err
+	case walOpExpire:
+		s.mem.replayExpired(record.Expired)
+		s.tombstoneSeq = record.Seq
+		return nil
+	case walOpSetRetention:
+		var retention Retention
+		if record.Retention != nil {
+			retention = *record.Retention
+		}
+		err := s.mem.setSessionRetention(context.Background(), record.TenantID, record.SessionID, retention, false, nil)
+		if errors.Is(err, ErrSessionNotFound) {
+			// The session was deleted before the log was compacted.
+			return nil
+		}
+		return err
+	case walOpSetSpanPolicy:
+		return s.mem.setSessionSpanPolicy(context.Background(), record.TenantID, record.SessionID, record.SpanPolicy, false, nil)
+	case walOpSetLabels:
+		return s.mem.setSessionLabels(context.Background(), record.TenantID, record.SessionID, record.Labels, false, nil)
+	case walOpUpdateEvent:
+		if len(record.Events) != 1 {
+			return fmt.Errorf("%w: update_event must carry one event", ErrCorruptLog)
+		}
+		return s.mem.replayUpdate(record.Events[0])
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrCorruptLog, record.Op)
+	}
+}
+
+func (s *DurableStore) Append(ctx context.Context, event Event) error {
+	return s.AppendMany(ctx, []Event{event})
+}
+
+func (s *DurableStore) AppendMany(ctx context.Context, events []Event) error {
+	// The batch is logged while the in-memory write lock is held, so log order
+	// always matches apply order.
+	return s.mem.appendMany(ctx, events, true, func() error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(walRecord{Op: walOpAppend, Events: events})
+	})
+}
+
+func (s *DurableStore) Segment(ctx context.Context, req SegmentRequest) (Segmentation, error) {
+	return s.mem.segment(ctx, req, func(segmentation Segmentation, pending *pendingSegment) error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(walRecord{
+			Op:        walOpSegment,
+			Events:    segmentation.Events,
+			TenantID:  req.TenantID,
+			SessionID: req.SessionID,
+			Surprise:  req.Surprise,
+			Pending:   pending,
+		})
+	})
+}
+
+// UpdateEvent logs the whole updated event, so replay needs neither the patch
+// nor the version check.
+func (s *DurableStore) UpdateEvent(
+	ctx context.Context,
+	tenantID, sessionID, eventID string,
+	patch EventPatch,
+	ifVersion int64,
+) (Event, error) {
+	return s.mem.updateEvent(ctx, tenantID, sessionID, eventID, patch, ifVersion, func(updated Event) error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(walRecord{Op: walOpUpdateEvent, Events: []Event{updated}})
+	})
+}
+
+func (s *DurableStore) DeleteEvent(ctx context.Context, tenantID, sessionID, eventID string) (Deletion, error) {
+	return s.mem.deleteEvent(ctx, tenantID, sessionID, eventID, s.logTombstone(walRecord{
+		Op:        walOpDeleteEvent,
+		TenantID:  tenantID,
+		SessionID: sessionID,
+		EventID:   eventID,
+	}))
+}
+
+func (s *DurableStore) DeleteSession(ctx context.Context, tenantID, sessionID string) (Deletion, error) {
+	return s.mem.deleteSession(ctx, tenantID, sessionID, s.logTombstone(walRecord{
+		Op:        walOpDeleteSession,
+		TenantID:  tenantID,
+		SessionID: sessionID,
+	}))
+}
+
+func (s *DurableStore) PurgeTenant(ctx context.Context, tenantID string) (Deletion, error) {
+	return s.mem.purgeTenant(ctx, tenantID, s.logTombstone(walRecord{
+		Op:       walOpPurgeTenant,
+		TenantID: tenantID,
+	}))
+}
+
+// logTombstone returns a commit func that logs a deletion record and asks
+// for an early snapshot, which is what erases the deleted data from disk.
+func (s *DurableStore) logTombstone(record walRecord) func() error {
+	return func() error {
+		if err := s.appendTombstone(record); err != nil {
+			return err
+		}
+		select {
+		case s.compact <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+}
+
+// appendTombstone logs a record that removes data, so the next snapshot
+// erases it from disk.
+func (s *DurableStore) appendTombstone(record walRecord) error {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if err := s.log.append(record); err != nil {
+		return err
+	}
+	s.tombstoneSeq = s.log.lastSeq
+	return nil
+}
+
+func (s *DurableStore) SetSessionRetention(ctx context.Context, tenantID, sessionID string, retention Retention) error {
+	record := walRecord{Op: walOpSetRetention, TenantID: tenantID, SessionID: sessionID}
+	if retention != (Retention{}) {
+		record.Retention = &retention
+	}
+	return s.mem.setSessionRetention(ctx, tenantID, sessionID, retention, true, func() error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(record)
+	})
+}
+
+func (s *DurableStore) SetSessionSpanPolicy(ctx context.Context, tenantID, sessionID string, policy SpanPolicy) error {
+	return s.mem.setSessionSpanPolicy(ctx, tenantID, sessionID, policy, true, func() error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(walRecord{Op: walOpSetSpanPolicy, TenantID: tenantID, SessionID: sessionID, SpanPolicy: policy})
+	})
+}
+
+func (s *DurableStore) SetSessionLabels(ctx context.Context, tenantID, sessionID string, labels map[string]string) error {
+	return s.mem.setSessionLabels(ctx, tenantID, sessionID, labels, true, func() error {
+		s.logMu.Lock()
+		defer s.logMu.Unlock()
+
+		return s.log.append(walRecord{Op: walOpSetLabels, TenantID: tenantID, SessionID: sessionID, Labels: labels})
+	})
+}
+
+func (s *DurableStore) SpanReport(tenantID, sessionID string) (SpanReport, bool) {
+	return s.mem.SpanReport(tenantID, sessionID)
+}
+
+// Sweep logs what expired as one record. Unlike an explicit deletion it does
+// not ask for an early snapshot, since sweeps run continuously; expired data
+// leaves the disk at the next scheduled snapshot.
+func (s *DurableStore) Sweep(ctx context.Context) (Deletion, error) {
+	return s.mem.sweep(ctx, func(expired []expiredSession) error {
+		return s.appendTombstone(walRecord{Op: walOpExpire, Expired: expired})
+	})
+}
+
+func (s *DurableStore) Get(tenantID, sessionID, eventID string) (Event, bool) {
+	return s.mem.Get(tenantID, sessionID, eventID)
+}
+
+func (s *DurableStore) ListBySession(tenantID, sessionID string) []Event {
+	return s.mem.ListBySession(tenantID, sessionID)
+}
+
+func (s *DurableStore) ListEvents(ctx context.Context, query ListQuery) (EventPage, error) {
+	return s.mem.ListEvents(ctx, query)
+}
+
+func (s *DurableStore) GetSession(tenantID, sessionID string) (SessionSummary, bool) {
+	return s.mem.GetSession(tenantID, sessionID)
+}
+
+func (s *DurableStore) ListSessions(ctx context.Context, query SessionQuery) (SessionPage, error) {
+	return s.mem.ListSessions(ctx, query)
+}
+
+func (s *DurableStore) RetrieveByAnchors(
+	ctx context.Context,
+	tenantID, sessionID string,
+	anchorEventIDs []string,
+	topK, bufferBefore, bufferAfter int,
+) ([]RetrievedEvent, error) {
+	return s.mem.RetrieveByAnchors(ctx, tenantID, sessionID, anchorEventIDs, topK, bufferBefore, bufferAfter)
+}
+
+func (s *DurableStore) RetrieveBySimilarity(ctx context.Context, query SimilarityQuery) ([]RetrievedEvent, error) {
+	return s.mem.RetrieveBySimilarity(ctx, query)
+}
+
+func (s *DurableStore) RetrieveByText(ctx context.Context, query TextQuery) ([]RetrievedEvent, error) {
+	return s.mem.RetrieveByText(ctx, query)
+}
+
+func (s *DurableStore) RetrieveAcrossSessions(ctx context.Context, query CrossSessionQuery) ([]SessionResults, error) {
+	return s.mem.RetrieveAcrossSessions(ctx, query)
+}
+
+func (s *DurableStore) AssembleContext(ctx context.Context, req ContextRequest) (AssembledContext, error) {
+	return s.mem.AssembleContext(ctx, req)
+}
+
+func (s *DurableStore) Stats() StoreStats {
+	return s.mem.Stats()
+}
+
+// Snapshot writes a point-in-time copy of all sessions and compacts the log
+// behind it. It is a no-op when nothing was logged since the last snapshot.
+func (s *DurableStore) Snapshot() error {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	// Holding the read lock blocks appends, so the copied sessions match seq
+	// exactly. Rotating here starts a fresh segment for records after seq.
+	s.mem.mu.RLock()
+	s.logMu.Lock()
+	seq := s.log.lastSeq
+	erasing := s.tombstoneSeq > s.snapshotSeq
+	if seq == s.snapshotSeq {
+		s.logMu.Unlock()
+		s.mem.mu.RUnlock()
+		return nil
+	}
+	sessions := s.mem.snapshotSessionsLocked()
+	err := s.log.rotate()
+	s.logMu.Unlock()
+	s.mem.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := writeSnapshot(s.dataDir, storeSnapshot{Seq: seq, Sessions: sessions}); err != nil {
+		return err
+	}
+	s.snapshotSeq = seq
+
+	// Older snapshots and the log behind them may still hold deleted data,
+	// so a snapshot covering a deletion is kept as the only one.
+	retain := s.opts.SnapshotRetain
+	if erasing {
+		retain = 1
+	}
+	oldestSeq, err := pruneSnapshots(s.dataDir, retain)
+	if err != nil {
+		return err
+	}
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+	return s.log.removeCoveredSegments(oldestSeq)
+}
+
+// snapshotLoop takes a snapshot every interval, if it is positive, and after
+// every deletion.
+func (s *DurableStore) snapshotLoop(interval time.Duration) {
+	defer close(s.done)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tick:
+		case <-s.compact:
+		}
+		if err := s.Snapshot(); err != nil && s.opts.OnSnapshotError != nil {
+			s.opts.OnSnapshotError(err)
+		}
+	}
+}
+
+// Close stops background snapshots and releases the write-ahead log.
+// Appends after Close fail.
+func (s *DurableStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	return s.log.close()
+}
@@ -0,0 +1,444 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDurableStoreReplaysAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now),
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now),
+	}); err != nil {
+		t.Fatalf("append many: %v", err)
+	}
+	withPayload := mustEvent(t, "evt_1", "tenant_2", "session_1", 0, 2, now)
+	withPayload.Payload = &Payload{Text: "hi there", TokenIDs: []int{7, 8}, Role: "user"}
+	if err := store.Append(context.Background(), withPayload); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+
+	list := reopened.ListBySession("tenant_1", "session_1")
+	if len(list) != 2 || list[0].EventID != "evt_1" || list[1].EventID != "evt_2" {
+		t.Fatalf("unexpected replayed events: %#v", list)
+	}
+	if !list[0].CreatedAt.Equal(now) {
+		t.Fatalf("expected created_at %v, got %v", now, list[0].CreatedAt)
+	}
+	replayed, ok := reopened.Get("tenant_2", "session_1", "evt_1")
+	if !ok {
+		t.Fatalf("expected tenant_2 event to be replayed")
+	}
+	if !reflect.DeepEqual(replayed.Payload, withPayload.Payload) {
+		t.Fatalf("expected payload %#v, got %#v", withPayload.Payload, replayed.Payload)
+	}
+
+	err := reopened.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 20, 30, now))
+	if !errors.Is(err, ErrDuplicateEventID) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateEventID, err)
+	}
+}
+
+func TestDurableStoreDoesNotLogRejectedBatch(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now),
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 20, now),
+	})
+	if !errors.Is(err, ErrDuplicateEventID) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateEventID, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, walSegmentName(1)))
+	if err != nil {
+		t.Fatalf("stat log: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("expected empty log, got %d bytes", info.Size())
+	}
+}
+
+func TestDurableStoreDiscardsTornTail(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)); err != nil {
+		t.Fatalf("append first: %v", err)
+	}
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now),
+		mustEvent(t, "evt_3", "tenant_1", "session_1", 20, 30, now),
+	}); err != nil {
+		t.Fatalf("append second: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Simulate a crash halfway through writing the second batch.
+	path := filepath.Join(dir, walSegmentName(1))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat log: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-7); err != nil {
+		t.Fatalf("truncate log: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	list := reopened.ListBySession("tenant_1", "session_1")
+	if len(list) != 1 || list[0].EventID != "evt_1" {
+		t.Fatalf("expected only the first batch to survive, got %#v", list)
+	}
+
+	if err := reopened.Append(context.Background(), mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now)); err != nil {
+		t.Fatalf("append after recovery: %v", err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	again := mustOpenDurableStore(t, dir)
+	defer again.Close()
+	if got := again.ListBySession("tenant_1", "session_1"); len(got) != 2 {
+		t.Fatalf("expected 2 events after second replay, got %#v", got)
+	}
+}
+
+func TestDurableStoreRejectsCorruptRecordBeforeTail(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	for _, event := range []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now),
+	} {
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append %q: %v", event.EventID, err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	path := filepath.Join(dir, walSegmentName(1))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	data[walHeaderSize+2] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	_, err = OpenDurableStore(dir, DurableOptions{})
+	if !errors.Is(err, ErrCorruptLog) {
+		t.Fatalf("expected error %v, got %v", ErrCorruptLog, err)
+	}
+}
+
+func TestDurableStoreSnapshotThenReplaysTail(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := store.Append(context.Background(), mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now)); err != nil {
+		t.Fatalf("append after snapshot: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotName(1))); err != nil {
+		t.Fatalf("expected snapshot file: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+
+	list := reopened.ListBySession("tenant_1", "session_1")
+	if len(list) != 2 || list[0].EventID != "evt_1" || list[1].EventID != "evt_2" {
+		t.Fatalf("unexpected events after snapshot replay: %#v", list)
+	}
+	if _, ok := reopened.Get("tenant_1", "session_1", "evt_1"); !ok {
+		t.Fatalf("expected snapshot event to be indexed by id")
+	}
+}
+
+func TestDurableStorePersistsSimilarityIndex(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+	opts := DurableOptions{Store: StoreOptions{Index: IndexOptions{MinVectors: 1}}}
+
+	store, err := OpenDurableStore(dir, opts)
+	if err != nil {
+		t.Fatalf("open durable store: %v", err)
+	}
+	vectors := [][]float32{{1, 0}, {0, 1}, {0.6, 0.8}}
+	for i, vector := range vectors {
+		event := mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, (i+1)*10, now)
+		event.Embeddings = [][]float32{vector}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append %q: %v", event.EventID, err)
+		}
+		if i == 1 {
+			if err := store.Snapshot(); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	snapshot, ok, err := loadLatestSnapshot(dir)
+	if err != nil || !ok {
+		t.Fatalf("load snapshot: ok=%v err=%v", ok, err)
+	}
+	if len(snapshot.Sessions) != 1 || snapshot.Sessions[0].Index == nil || len(snapshot.Sessions[0].Index.Nodes) != 2 {
+		t.Fatalf("expected snapshot to include the session index, got %#v", snapshot.Sessions)
+	}
+
+	reopened, err := OpenDurableStore(dir, opts)
+	if err != nil {
+		t.Fatalf("reopen durable store: %v", err)
+	}
+	defer reopened.Close()
+
+	session := reopened.mem.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}]
+	if session.index == nil || session.index.len() != 3 {
+		t.Fatalf("expected restored index with replayed tail, got %#v", session.index)
+	}
+
+	events, err := reopened.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{0.6, 0.8}, Metric: SimilarityCosine, TopK: 1})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != "evt_2" {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+}
+
+func TestDurableStorePersistsSurpriseHistory(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+	key := sessionKey{tenantID: "tenant_1", sessionID: "session_1"}
+
+	store := mustOpenDurableStore(t, dir)
+	segmentation, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		Surprise:       []float64{0.1, 1.2, 0.1, 0.2},
+		Adaptive:       &AdaptiveThreshold{Window: 8, Gamma: 1},
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	})
+	if err != nil {
+		t.Fatalf("segment: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	want := []float64{0.1, 1.2, 0.1, 0.2}
+	if got := reopened.mem.sessions[key].surpriseHistory; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected replayed history %v, got %v", want, got)
+	}
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != len(segmentation.Events) {
+		t.Fatalf("expected %d replayed events, got %d", len(segmentation.Events), len(got))
+	}
+	if err := reopened.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	restored := mustOpenDurableStore(t, dir)
+	defer restored.Close()
+	if got := restored.mem.sessions[key].surpriseHistory; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected snapshot history %v, got %v", want, got)
+	}
+}
+
+func TestDurableStoreResumesStreamAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenDurableStore(t, dir)
+	if _, err := store.Segment(context.Background(), streamRequest(0, []float64{0.1, 0.2, 1.2}, "a")); err != nil {
+		t.Fatalf("segment first chunk: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+
+	segmentation, err := reopened.Segment(context.Background(), streamRequest(3, []float64{0.1, 0.1}, "b"))
+	if err != nil {
+		t.Fatalf("segment after reopen: %v", err)
+	}
+	if !reflect.DeepEqual(segmentation.Boundaries, []int{3}) {
+		t.Fatalf("expected the peak at the chunk edge to become boundary 3, got %v", segmentation.Boundaries)
+	}
+	if len(segmentation.Events) != 1 || segmentation.Events[0].StartToken != 0 || segmentation.Events[0].EndTokenExclusive != 3 {
+		t.Fatalf("unexpected events: %#v", segmentation.Events)
+	}
+}
+
+func TestDurableStoreCompactsLogBehindRetainedSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store, err := OpenDurableStore(dir, DurableOptions{SnapshotRetain: 1})
+	if err != nil {
+		t.Fatalf("open durable store: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		event := mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, (i+1)*10, now)
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append %q: %v", event.EventID, err)
+		}
+		if err := store.Snapshot(); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		t.Fatalf("list snapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].seq != 3 {
+		t.Fatalf("expected only snapshot 3 to remain, got %#v", snapshots)
+	}
+
+	segments, err := listWALSegments(dir)
+	if err != nil {
+		t.Fatalf("list segments: %v", err)
+	}
+	if len(segments) != 1 || segments[0].firstSeq != 4 {
+		t.Fatalf("expected only the active segment to remain, got %#v", segments)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != 3 {
+		t.Fatalf("expected 3 events, got %#v", got)
+	}
+}
+
+func TestDurableStoreSnapshotsInBackground(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store, err := OpenDurableStore(dir, DurableOptions{
+		SnapshotInterval: 5 * time.Millisecond,
+		OnSnapshotError: func(err error) {
+			t.Errorf("background snapshot: %v", err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("open durable store: %v", err)
+	}
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, snapshotName(1))); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected background snapshot to be written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestDurableStoreFallsBackToOlderSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	for i := 0; i < 2; i++ {
+		event := mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, (i+1)*10, now)
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append %q: %v", event.EventID, err)
+		}
+		if err := store.Snapshot(); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, snapshotName(2)), []byte("garbage"), 0o600); err != nil {
+		t.Fatalf("corrupt snapshot: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != 2 {
+		t.Fatalf("expected older snapshot plus log to restore 2 events, got %#v", got)
+	}
+}
+
+func TestOpenDurableStoreRequiresDataDir(t *testing.T) {
+	_, err := OpenDurableStore("", DurableOptions{})
+	if !errors.Is(err, errDataDirRequired) {
+		t.Fatalf("expected error %v, got %v", errDataDirRequired, err)
+	}
+}
+
+func mustOpenDurableStore(t *testing.T, dir string) *DurableStore {
+	t.Helper()
+
+	store, err := OpenDurableStore(dir, DurableOptions{})
+	if err != nil {
+		t.Fatalf("open durable store: %v", err)
+	}
+
+	return store
+}
@@ -1,17 +1,61 @@
 package memory
 
 import (
-	"errors"
+	"fmt"
+	"maps"
+	"math"
 	"time"
+	"unicode/utf8"
 )
 
+const (
+	maxPayloadTextBytes    = 64 << 10
+	maxPayloadTokenIDs     = 16384
+	maxPayloadRoleBytes    = 64
+	maxPayloadSpeakerBytes = 128
+
+	maxEventEmbeddings = 16
+	maxEmbeddingDim    = 4096
+
+	maxEventTags          = 64
+	maxTagBytes           = 128
+	maxMetadataEntries    = 64
+	maxMetadataKeyBytes   = 128
+	maxMetadataValueBytes = 1024
+)
+
+// FirstEventVersion is the version of a newly appended event. Every update
+// increments it.
+const FirstEventVersion int64 = 1
+
 var (
-	errEventIDRequired    = errors.New("event_id is required")
-	errTenantIDRequired   = errors.New("tenant_id is required")
-	errSessionIDRequired  = errors.New("session_id is required")
-	errStartTokenNegative = errors.New("start_token must be non-negative")
-	errInvalidTokenRange  = errors.New("end_token_exclusive must be greater than start_token")
-	errCreatedAtRequired  = errors.New("created_at is required")
+	errEventIDRequired    = invalidInput("event_id is required")
+	errTenantIDRequired   = invalidInput("tenant_id is required")
+	errSessionIDRequired  = invalidInput("session_id is required")
+	errStartTokenNegative = invalidInput("start_token must be non-negative")
+	errInvalidTokenRange  = invalidInput("end_token_exclusive must be greater than start_token")
+	errCreatedAtRequired  = invalidInput("created_at is required")
+
+	errPayloadTextTooLarge    = invalidInput("payload.text must be at most %d bytes", maxPayloadTextBytes)
+	errPayloadTextInvalid     = invalidInput("payload.text must be valid UTF-8")
+	errPayloadTokenIDsTooMany = invalidInput("payload.token_ids must contain at most %d values", maxPayloadTokenIDs)
+	errPayloadTokenIDsLength  = invalidInput("payload.token_ids must contain one id per token in the event span")
+	errPayloadTokenIDNegative = invalidInput("payload.token_ids must be non-negative")
+	errPayloadRoleTooLong     = invalidInput("payload.role must be at most %d bytes", maxPayloadRoleBytes)
+	errPayloadSpeakerTooLong  = invalidInput("payload.speaker must be at most %d bytes", maxPayloadSpeakerBytes)
+
+	errEmbeddingsTooMany   = invalidInput("embeddings must contain at most %d vectors", maxEventEmbeddings)
+	errEmbeddingDimension  = invalidInput("embedding vectors must have between 1 and %d dimensions", maxEmbeddingDim)
+	errEmbeddingDimsDiffer = invalidInput("embedding vectors must all have the same dimension")
+	errEmbeddingNotFinite  = invalidInput("embedding values must be finite")
+	errEmbeddingZeroVector = invalidInput("embedding vectors must be non-zero")
+
+	errTagsTooMany         = invalidInput("tags must contain at most %d values", maxEventTags)
+	errTagInvalid          = invalidInput("tags must be non-empty valid UTF-8 of at most %d bytes", maxTagBytes)
+	errTagDuplicate        = invalidInput("tags must be unique")
+	errMetadataTooMany     = invalidInput("metadata must contain at most %d entries", maxMetadataEntries)
+	errMetadataKeyInvalid  = invalidInput("metadata keys must be non-empty valid UTF-8 of at most %d bytes", maxMetadataKeyBytes)
+	errMetadataValueTooBig = fmt.Errorf("metadata values must be valid UTF-8 of at most %d bytes", maxMetadataValueBytes)
 )
 
 // Event represents one episodic memory segment in a tenant session.
@@ -23,6 +67,26 @@ type Event struct {
 	StartToken        int       `json:"start_token"`
 	EndTokenExclusive int       `json:"end_token_exclusive"`
 	CreatedAt         time.Time `json:"created_at"`
+	Payload           *Payload  `json:"payload,omitempty"`
+	// Embeddings are representative vectors used for similarity retrieval.
+	Embeddings [][]float32 `json:"embeddings,omitempty"`
+	// Metadata and Tags are free-form annotations that never affect
+	// segmentation or retrieval order.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	// Version starts at FirstEventVersion when the event is appended and
+	// increments on every update. The store assigns it; any value sent on
+	// append is ignored.
+	Version int64 `json:"version,omitempty"`
+}
+
+// Payload is the optional content of an event. When TokenIDs is set it holds
+// exactly one id per token in the event span.
+type Payload struct {
+	Text     string `json:"text,omitempty"`
+	TokenIDs []int  `json:"token_ids,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Speaker  string `json:"speaker,omitempty"`
 }
 
 func NewEvent(eventID, tenantID, sessionID string, startToken, endTokenExclusive int, createdAt time.Time) (Event, error) {
@@ -61,6 +125,160 @@ func validateEvent(event Event) error {
 	if event.CreatedAt.IsZero() {
 		return errCreatedAtRequired
 	}
+	if event.Payload != nil {
+		if err := validatePayload(*event.Payload, event.EndTokenExclusive-event.StartToken); err != nil {
+			return err
+		}
+	}
+	if err := validateEmbeddings(event.Embeddings); err != nil {
+		return err
+	}
+	if err := validateTags(event.Tags); err != nil {
+		return err
+	}
+	if err := validateMetadata(event.Metadata); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validatePayload(payload Payload, spanTokens int) error {
+	if len(payload.Text) > maxPayloadTextBytes {
+		return errPayloadTextTooLarge
+	}
+	if !utf8.ValidString(payload.Text) {
+		return errPayloadTextInvalid
+	}
+	if len(payload.TokenIDs) > 0 {
+		if len(payload.TokenIDs) > maxPayloadTokenIDs {
+			return errPayloadTokenIDsTooMany
+		}
+		if len(payload.TokenIDs) != spanTokens {
+			return errPayloadTokenIDsLength
+		}
+		for _, tokenID := range payload.TokenIDs {
+			if tokenID < 0 {
+				return errPayloadTokenIDNegative
+			}
+		}
+	}
+	if len(payload.Role) > maxPayloadRoleBytes {
+		return errPayloadRoleTooLong
+	}
+	if len(payload.Speaker) > maxPayloadSpeakerBytes {
+		return errPayloadSpeakerTooLong
+	}
+
+	return nil
+}
+
+func validateEmbeddings(embeddings [][]float32) error {
+	if len(embeddings) > maxEventEmbeddings {
+		return errEmbeddingsTooMany
+	}
+	for _, vector := range embeddings {
+		if len(vector) != len(embeddings[0]) {
+			return errEmbeddingDimsDiffer
+		}
+		if err := validateVector(vector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateVector(vector []float32) error {
+	if len(vector) == 0 || len(vector) > maxEmbeddingDim {
+		return errEmbeddingDimension
+	}
+
+	nonZero := false
+	for _, value := range vector {
+		v := float64(value)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errEmbeddingNotFinite
+		}
+		if value != 0 {
+			nonZero = true
+		}
+	}
+	if !nonZero {
+		return errEmbeddingZeroVector
+	}
+
+	return nil
+}
+
+func validateTags(tags []string) error {
+	if len(tags) > maxEventTags {
+		return errTagsTooMany
+	}
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		if tag == "" || len(tag) > maxTagBytes || !utf8.ValidString(tag) {
+			return errTagInvalid
+		}
+		if _, ok := seen[tag]; ok {
+			return errTagDuplicate
+		}
+		seen[tag] = struct{}{}
+	}
+
+	return nil
+}
+
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return errMetadataTooMany
+	}
+	for key, value := range metadata {
+		if key == "" || len(key) > maxMetadataKeyBytes || !utf8.ValidString(key) {
+			return errMetadataKeyInvalid
+		}
+		if len(value) > maxMetadataValueBytes || !utf8.ValidString(value) {
+			return errMetadataValueTooBig
+		}
+	}
 
 	return nil
 }
+
+// embeddingDim reports the dimension of the event's vectors, or 0 if it has none.
+func (event Event) embeddingDim() int {
+	if len(event.Embeddings) == 0 {
+		return 0
+	}
+	return len(event.Embeddings[0])
+}
+
+// tokenCount is the number of tokens the event spans.
+func (event Event) tokenCount() int {
+	return event.EndTokenExclusive - event.StartToken
+}
+
+// clone returns a copy that shares no mutable state with event.
+func (event Event) clone() Event {
+	if event.Payload != nil {
+		payload := *event.Payload
+		if payload.TokenIDs != nil {
+			payload.TokenIDs = append([]int(nil), payload.TokenIDs...)
+		}
+		event.Payload = &payload
+	}
+	if event.Embeddings != nil {
+		embeddings := make([][]float32, len(event.Embeddings))
+		for i, vector := range event.Embeddings {
+			embeddings[i] = append([]float32(nil), vector...)
+		}
+		event.Embeddings = embeddings
+	}
+	if event.Metadata != nil {
+		event.Metadata = maps.Clone(event.Metadata)
+	}
+	if event.Tags != nil {
+		event.Tags = append([]string(nil), event.Tags...)
+	}
+	return event
+}
@@ -3,6 +3,8 @@ package memory
 import (
 	"encoding/json"
 	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -162,3 +164,103 @@ func TestEventJSONRoundTrip(t *testing.T) {
 		t.Fatalf("expected created_at %v, got %v", event.CreatedAt, decoded.CreatedAt)
 	}
 }
+
+func TestValidateEventAcceptsPayload(t *testing.T) {
+	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 13, time.Now().UTC())
+	event.Payload = &Payload{
+		Text:     "hello there world",
+		TokenIDs: []int{15339, 1070, 1917},
+		Role:     "user",
+		Speaker:  "alice",
+	}
+
+	if err := validateEvent(event); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestValidateEventRejectsInvalidPayload(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload Payload
+		err     error
+	}{
+		{
+			name:    "text too large",
+			payload: Payload{Text: strings.Repeat("a", maxPayloadTextBytes+1)},
+			err:     errPayloadTextTooLarge,
+		},
+		{
+			name:    "text not utf8",
+			payload: Payload{Text: "\xff\xfe"},
+			err:     errPayloadTextInvalid,
+		},
+		{
+			name:    "token ids do not match span",
+			payload: Payload{TokenIDs: []int{1, 2}},
+			err:     errPayloadTokenIDsLength,
+		},
+		{
+			name:    "negative token id",
+			payload: Payload{TokenIDs: []int{1, -2, 3}},
+			err:     errPayloadTokenIDNegative,
+		},
+		{
+			name:    "role too long",
+			payload: Payload{Role: strings.Repeat("r", maxPayloadRoleBytes+1)},
+			err:     errPayloadRoleTooLong,
+		},
+		{
+			name:    "speaker too long",
+			payload: Payload{Speaker: strings.Repeat("s", maxPayloadSpeakerBytes+1)},
+			err:     errPayloadSpeakerTooLong,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 13, time.Now().UTC())
+			event.Payload = &tc.payload
+
+			err := validateEvent(event)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("expected %v to match ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestValidateEventRejectsInvalidEmbeddings(t *testing.T) {
+	tooMany := make([][]float32, maxEventEmbeddings+1)
+	for i := range tooMany {
+		tooMany[i] = []float32{1}
+	}
+
+	cases := []struct {
+		name       string
+		embeddings [][]float32
+		err        error
+	}{
+		{name: "too many vectors", embeddings: tooMany, err: errEmbeddingsTooMany},
+		{name: "empty vector", embeddings: [][]float32{{}}, err: errEmbeddingDimension},
+		{name: "too many dimensions", embeddings: [][]float32{make([]float32, maxEmbeddingDim+1)}, err: errEmbeddingDimension},
+		{name: "mixed dimensions", embeddings: [][]float32{{1, 0}, {1}}, err: errEmbeddingDimsDiffer},
+		{name: "not finite", embeddings: [][]float32{{float32(math.Inf(1)), 0}}, err: errEmbeddingNotFinite},
+		{name: "zero vector", embeddings: [][]float32{{0, 0}}, err: errEmbeddingZeroVector},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, time.Now().UTC())
+			event.Embeddings = tc.embeddings
+
+			err := validateEvent(event)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package memory
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+const (
+	defaultHNSWM              = 16
+	defaultHNSWEfConstruction = 200
+	defaultHNSWEfSearch       = 64
+	defaultHNSWMinVectors     = 1024
+	// Levels above this are vanishingly unlikely and only waste memory.
+	maxHNSWLevel = 16
+)
+
+// IndexOptions tunes the per-session HNSW index used for cosine similarity retrieval.
+// Zero fields fall back to defaults.
+type IndexOptions struct {
+	// M is the number of neighbours kept per node on upper layers (2*M on layer 0).
+	// Values below 2 fall back to the default.
+	M int
+	// EfConstruction is the candidate list size used while inserting.
+	EfConstruction int
+	// EfSearch is the minimum candidate list size used while querying.
+	EfSearch int
+	// MinVectors is the session size below which retrieval scans exhaustively,
+	// which is exact and cheaper than walking the graph for small sessions.
+	MinVectors int
+}
+
+func (o IndexOptions) withDefaults() IndexOptions {
+	// M below 2 would make the level distribution degenerate.
+	if o.M < 2 {
+		o.M = defaultHNSWM
+	}
+	if o.EfConstruction <= 0 {
+		o.EfConstruction = defaultHNSWEfConstruction
+	}
+	if o.EfSearch <= 0 {
+		o.EfSearch = defaultHNSWEfSearch
+	}
+	if o.MinVectors <= 0 {
+		o.MinVectors = defaultHNSWMinVectors
+	}
+	return o
+}
+
+// hnswIndex is a hierarchical navigable small world graph over unit-length
+// copies of event embeddings, so inner product equals cosine similarity.
+// Each node is one embedding vector; an event with several vectors has several nodes.
+// Removed nodes stay in the graph as tombstones, which searches route
+// through but never return, since unlinking a node would leave its
+// neighbours poorly connected.
+type hnswIndex struct {
+	m              int
+	efConstruction int
+	levelMult      float64
+
+	nodes    []hnswNode
+	entry    int32
+	maxLevel int
+	// byEvent holds the live node ids of each event.
+	byEvent    map[string][]int32
+	tombstones int
+}
+
+type hnswNode struct {
+	eventID string
+	// ordinal is the position of the vector in Event.Embeddings.
+	ordinal int
+	vector  []float32
+	// neighbors[layer] holds node ids; len(neighbors)-1 is the node's level.
+	neighbors [][]int32
+	deleted   bool
+}
+
+// hnswMatch is one event found by a search, scored by its best vector.
+type hnswMatch struct {
+	eventID string
+	score   float64
+}
+
+func newHNSWIndex(opts IndexOptions) *hnswIndex {
+	return &hnswIndex{
+		m:              opts.M,
+		efConstruction: opts.EfConstruction,
+		levelMult:      1 / math.Log(float64(opts.M)),
+		entry:          -1,
+		byEvent:        make(map[string][]int32),
+	}
+}
+
+// len is the number of live vectors.
+func (idx *hnswIndex) len() int {
+	return len(idx.nodes) - idx.tombstones
+}
+
+// insert adds one embedding of eventID to the graph.
+func (idx *hnswIndex) insert(eventID string, ordinal int, vector []float32) {
+	id := int32(len(idx.nodes))
+	level := idx.levelFor(eventID, ordinal)
+	idx.nodes = append(idx.nodes, hnswNode{
+		eventID:   eventID,
+		ordinal:   ordinal,
+		vector:    unitVector(vector),
+		neighbors: make([][]int32, level+1),
+	})
+	idx.byEvent[eventID] = append(idx.byEvent[eventID], id)
+
+	if idx.entry < 0 {
+		idx.entry = id
+		idx.maxLevel = level
+		return
+	}
+
+	query := idx.nodes[id].vector
+	entry := idx.entry
+	for layer := idx.maxLevel; layer > level; layer-- {
+		entry = idx.greedyClosest(query, entry, layer)
+	}
+
+	entries := []int32{entry}
+	for layer := min(level, idx.maxLevel); layer >= 0; layer-- {
+		candidates := idx.searchLayer(query, entries, idx.efConstruction, layer)
+		neighbors := idx.selectNeighbors(candidates, idx.m)
+		idx.nodes[id].neighbors[layer] = neighbors
+
+		for _, neighbor := range neighbors {
+			idx.link(neighbor, id, layer)
+		}
+
+		entries = entries[:0]
+		for _, candidate := range candidates {
+			entries = append(entries, candidate.id)
+		}
+	}
+
+	if level > idx.maxLevel {
+		idx.entry = id
+		idx.maxLevel = level
+	}
+}
+
+// remove turns every vector of eventID into a tombstone.
+func (idx *hnswIndex) remove(eventID string) {
+	for _, id := range idx.byEvent[eventID] {
+		idx.nodes[id].deleted = true
+		idx.tombstones++
+	}
+	delete(idx.byEvent, eventID)
+}
+
+// search returns up to k distinct events ordered by descending cosine similarity.
+// ef is widened until k events are found or the whole graph has been considered.
+func (idx *hnswIndex) search(query []float32, k, ef int) []hnswMatch {
+	if idx.entry < 0 || k <= 0 {
+		return []hnswMatch{}
+	}
+
+	unit := unitVector(query)
+	entry := idx.entry
+	for layer := idx.maxLevel; layer > 0; layer-- {
+		entry = idx.greedyClosest(unit, entry, layer)
+	}
+
+	ef = max(ef, k)
+	for {
+		candidates := idx.searchLayer(unit, []int32{entry}, ef, 0)
+
+		matches := make([]hnswMatch, 0, k)
+		seen := make(map[string]struct{}, k)
+		for _, candidate := range candidates {
+			node := &idx.nodes[candidate.id]
+			if node.deleted {
+				continue
+			}
+			eventID := node.eventID
+			if _, ok := seen[eventID]; ok {
+				continue
+			}
+			seen[eventID] = struct{}{}
+			matches = append(matches, hnswMatch{eventID: eventID, score: 1 - candidate.distance})
+			if len(matches) == k {
+				break
+			}
+		}
+
+		if len(matches) == k || ef >= len(idx.nodes) {
+			return matches
+		}
+		ef *= 2
+	}
+}
+
+// levelFor derives the node level from a hash of its identity instead of a
+// random source, so replaying the same inserts builds the same graph.
+func (idx *hnswIndex) levelFor(eventID string, ordinal int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(eventID))
+	_, _ = h.Write([]byte{byte(ordinal)})
+	u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+	return min(int(-math.Log(u)*idx.levelMult), maxHNSWLevel)
+}
+
+func (idx *hnswIndex) maxNeighbors(layer int) int {
+	if layer == 0 {
+		return 2 * idx.m
+	}
+	return idx.m
+}
+
+// link adds target to from's neighbour list, pruning it when it overflows.
+func (idx *hnswIndex) link(from, target int32, layer int) {
+	node := &idx.nodes[from]
+	node.neighbors[layer] = append(node.neighbors[layer], target)
+	if len(node.neighbors[layer]) <= idx.maxNeighbors(layer) {
+		return
+	}
+
+	candidates := make([]hnswCandidate, 0, len(node.neighbors[layer]))
+	for _, neighbor := range node.neighbors[layer] {
+		candidates = append(candidates, hnswCandidate{
+			id:       neighbor,
+			distance: cosineDistance(node.vector, idx.nodes[neighbor].vector),
+		})
+	}
+	sortCandidates(candidates)
+	node.neighbors[layer] = idx.selectNeighbors(candidates, idx.maxNeighbors(layer))
+}
+
+// selectNeighbors applies the HNSW neighbour heuristic: a candidate is kept
+// only if it is closer to the base than to any already selected neighbour,
+// which preserves links across clusters. Remaining slots are filled with the
+// closest pruned candidates. candidates must be sorted by ascending distance.
+func (idx *hnswIndex) selectNeighbors(candidates []hnswCandidate, limit int) []int32 {
+	selected := make([]int32, 0, limit)
+	pruned := make([]int32, 0)
+	for _, candidate := range candidates {
+		if len(selected) == limit {
+			break
+		}
+		keep := true
+		for _, chosen := range selected {
+			if cosineDistance(idx.nodes[candidate.id].vector, idx.nodes[chosen].vector) < candidate.distance {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, candidate.id)
+		} else {
+			pruned = append(pruned, candidate.id)
+		}
+	}
+
+	for _, id := range pruned {
+		if len(selected) == limit {
+			break
+		}
+		selected = append(selected, id)
+	}
+	return selected
+}
+
+func (idx *hnswIndex) greedyClosest(query []float32, entry int32, layer int) int32 {
+	best := entry
+	bestDistance := cosineDistance(query, idx.nodes[entry].vector)
+	for improved := true; improved; {
+		improved = false
+		for _, neighbor := range idx.nodes[best].neighbors[layer] {
+			if d := cosineDistance(query, idx.nodes[neighbor].vector); d < bestDistance {
+				best = neighbor
+				bestDistance = d
+				improved = true
+			}
+		}
+	}
+	return best
+}
+
+// searchLayer is the beam search from the HNSW paper. It returns up to ef
+// nodes sorted by ascending distance to query.
+func (idx *hnswIndex) searchLayer(query []float32, entries []int32, ef, layer int) []hnswCandidate {
+	visited := make([]uint64, (len(idx.nodes)+63)/64)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{farthestFirst: true}
+
+	for _, entry := range entries {
+		if visited[entry/64]&(1<<(entry%64)) != 0 {
+			continue
+		}
+		visited[entry/64] |= 1 << (entry % 64)
+		c := hnswCandidate{id: entry, distance: cosineDistance(query, idx.nodes[entry].vector)}
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && current.distance > results.items[0].distance {
+			break
+		}
+
+		for _, neighbor := range idx.nodes[current.id].neighbors[layer] {
+			if visited[neighbor/64]&(1<<(neighbor%64)) != 0 {
+				continue
+			}
+			visited[neighbor/64] |= 1 << (neighbor % 64)
+
+			d := cosineDistance(query, idx.nodes[neighbor].vector)
+			if results.Len() < ef || d < results.items[0].distance {
+				heap.Push(candidates, hnswCandidate{id: neighbor, distance: d})
+				heap.Push(results, hnswCandidate{id: neighbor, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := results.items
+	sortCandidates(found)
+	return found
+}
+
+type hnswCandidate struct {
+	id       int32
+	distance float64
+}
+
+func sortCandidates(candidates []hnswCandidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+}
+
+// candidateHeap is a min-heap on distance, or a max-heap when farthestFirst is set.
+type candidateHeap struct {
+	items         []hnswCandidate
+	farthestFirst bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+
+func (h candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].distance > h.items[j].distance
+	}
+	return h.items[i].distance < h.items[j].distance
+}
+
+func (h candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x any) { h.items = append(h.items, x.(hnswCandidate)) }
+
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func cosineDistance(a, b []float32) float64 {
+	var dot float64
+	for i, value := range a {
+		dot += float64(value) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+func unitVector(vector []float32) []float32 {
+	norm := vectorNorm(vector)
+	unit := make([]float32, len(vector))
+	for i, value := range vector {
+		unit[i] = float32(float64(value) / norm)
+	}
+	return unit
+}
+
+// hnswSnapshot persists the graph topology. Vectors are not stored; they are
+// re-derived from the events' embeddings on restore, so a graph holding
+// tombstones cannot be snapshotted.
+type hnswSnapshot struct {
+	M              int                `json:"m"`
+	EfConstruction int                `json:"ef_construction"`
+	Entry          int32              `json:"entry"`
+	MaxLevel       int                `json:"max_level"`
+	Nodes          []hnswNodeSnapshot `json:"nodes"`
+}
+
+type hnswNodeSnapshot struct {
+	EventID   string    `json:"event_id"`
+	Ordinal   int       `json:"ordinal"`
+	Neighbors [][]int32 `json:"neighbors"`
+}
+
+func (idx *hnswIndex) snapshot() *hnswSnapshot {
+	nodes := make([]hnswNodeSnapshot, len(idx.nodes))
+	for i, node := range idx.nodes {
+		neighbors := make([][]int32, len(node.neighbors))
+		for layer, ids := range node.neighbors {
+			neighbors[layer] = append([]int32(nil), ids...)
+		}
+		nodes[i] = hnswNodeSnapshot{EventID: node.eventID, Ordinal: node.ordinal, Neighbors: neighbors}
+	}
+
+	return &hnswSnapshot{
+		M:              idx.m,
+		EfConstruction: idx.efConstruction,
+		Entry:          idx.entry,
+		MaxLevel:       idx.maxLevel,
+		Nodes:          nodes,
+	}
+}
+
+// restoreHNSWIndex rebuilds an index from its snapshot. It reports false when
+// the snapshot was built with other parameters or does not match the events,
+// in which case the caller rebuilds the index by reinserting vectors.
+func restoreHNSWIndex(snapshot *hnswSnapshot, opts IndexOptions, byID map[string]Event) (*hnswIndex, bool) {
+	if snapshot == nil || snapshot.M != opts.M || snapshot.EfConstruction != opts.EfConstruction {
+		return nil, false
+	}
+
+	idx := newHNSWIndex(opts)
+	idx.entry = snapshot.Entry
+	idx.maxLevel = snapshot.MaxLevel
+	idx.nodes = make([]hnswNode, len(snapshot.Nodes))
+	for i, node := range snapshot.Nodes {
+		event, ok := byID[node.EventID]
+		if !ok || node.Ordinal < 0 || node.Ordinal >= len(event.Embeddings) || len(node.Neighbors) == 0 {
+			return nil, false
+		}
+		for layer, ids := range node.Neighbors {
+			for _, id := range ids {
+				if id < 0 || int(id) >= len(snapshot.Nodes) || len(snapshot.Nodes[id].Neighbors) <= layer {
+					return nil, false
+				}
+			}
+		}
+		idx.nodes[i] = hnswNode{
+			eventID:   node.EventID,
+			ordinal:   node.Ordinal,
+			vector:    unitVector(event.Embeddings[node.Ordinal]),
+			neighbors: node.Neighbors,
+		}
+		idx.byEvent[node.EventID] = append(idx.byEvent[node.EventID], int32(i))
+	}
+	if len(idx.nodes) > 0 && (idx.entry < 0 || int(idx.entry) >= len(idx.nodes)) {
+		return nil, false
+	}
+
+	return idx, true
+}
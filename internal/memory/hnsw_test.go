@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHNSWRecallAgainstBruteForce(t *testing.T) {
+	t.Parallel()
+
+	const (
+		dim     = 32
+		count   = 3000
+		queries = 50
+		k       = 10
+	)
+
+	rng := rand.New(rand.NewSource(7))
+	vectors := randomVectors(rng, count, dim)
+	index := newHNSWIndex(IndexOptions{}.withDefaults())
+	for i, vector := range vectors {
+		index.insert(fmt.Sprintf("evt_%d", i), 0, vector)
+	}
+
+	hits := 0
+	for q := 0; q < queries; q++ {
+		query := randomVectors(rng, 1, dim)[0]
+		want := bruteForceTopK(vectors, query, k)
+
+		got := index.search(query, k, defaultHNSWEfSearch)
+		if len(got) != k {
+			t.Fatalf("expected %d matches, got %d", k, len(got))
+		}
+		for _, match := range got {
+			if _, ok := want[match.eventID]; ok {
+				hits++
+			}
+		}
+	}
+
+	recall := float64(hits) / float64(queries*k)
+	if recall < 0.95 {
+		t.Fatalf("expected recall@%d >= 0.95, got %.3f", k, recall)
+	}
+}
+
+func TestHNSWSearchReturnsDistinctEvents(t *testing.T) {
+	t.Parallel()
+
+	index := newHNSWIndex(IndexOptions{}.withDefaults())
+	index.insert("evt_a", 0, []float32{1, 0})
+	index.insert("evt_a", 1, []float32{0.99, 0.1})
+	index.insert("evt_b", 0, []float32{0.9, 0.3})
+	index.insert("evt_c", 0, []float32{-1, 0})
+
+	got := index.search([]float32{1, 0}, 2, 1)
+	if len(got) != 2 || got[0].eventID != "evt_a" || got[1].eventID != "evt_b" {
+		t.Fatalf("unexpected matches: %#v", got)
+	}
+	if got[0].score < 0.999 {
+		t.Fatalf("expected near-perfect score for exact match, got %v", got[0].score)
+	}
+}
+
+func TestHNSWSnapshotRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	opts := IndexOptions{}.withDefaults()
+	rng := rand.New(rand.NewSource(11))
+	vectors := randomVectors(rng, 200, 8)
+	index := newHNSWIndex(opts)
+	byID := make(map[string]Event, len(vectors))
+	for i, vector := range vectors {
+		eventID := fmt.Sprintf("evt_%d", i)
+		index.insert(eventID, 0, vector)
+		byID[eventID] = Event{EventID: eventID, Embeddings: [][]float32{vector}}
+	}
+
+	restored, ok := restoreHNSWIndex(index.snapshot(), opts, byID)
+	if !ok {
+		t.Fatalf("expected snapshot to restore")
+	}
+
+	query := randomVectors(rng, 1, 8)[0]
+	want := index.search(query, 5, opts.EfSearch)
+	got := restored.search(query, 5, opts.EfSearch)
+	for i := range want {
+		if got[i].eventID != want[i].eventID {
+			t.Fatalf("expected restored search %v, got %v", want, got)
+		}
+	}
+
+	other := opts
+	other.M = opts.M * 2
+	if _, ok := restoreHNSWIndex(index.snapshot(), other, byID); ok {
+		t.Fatalf("expected snapshot with different parameters to be rejected")
+	}
+}
+
+func BenchmarkHNSWSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(rng, 20000, 64)
+	index := newHNSWIndex(IndexOptions{}.withDefaults())
+	for i, vector := range vectors {
+		index.insert(fmt.Sprintf("evt_%d", i), 0, vector)
+	}
+	query := randomVectors(rng, 1, 64)[0]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.search(query, 10, defaultHNSWEfSearch)
+	}
+}
+
+func BenchmarkBruteForceSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(rng, 20000, 64)
+	query := randomVectors(rng, 1, 64)[0]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceTopK(vectors, query, 10)
+	}
+}
+
+func randomVectors(rng *rand.Rand, count, dim int) [][]float32 {
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		vector := make([]float32, dim)
+		for j := range vector {
+			vector[j] = float32(rng.NormFloat64())
+		}
+		vectors[i] = vector
+	}
+	return vectors
+}
+
+func bruteForceTopK(vectors [][]float32, query []float32, k int) map[string]struct{} {
+	type scored struct {
+		index int
+		score float64
+	}
+
+	queryNorm := vectorNorm(query)
+	scores := make([]scored, len(vectors))
+	for i, vector := range vectors {
+		scores[i] = scored{index: i, score: similarity(SimilarityCosine, query, queryNorm, vector)}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	top := make(map[string]struct{}, k)
+	for _, s := range scores[:k] {
+		top[fmt.Sprintf("evt_%d", s.index)] = struct{}{}
+	}
+	return top
+}
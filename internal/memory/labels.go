@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"unicode/utf8"
+)
+
+const (
+	maxSessionLabels   = 64
+	maxLabelKeyBytes   = 128
+	maxLabelValueBytes = 256
+)
+
+var (
+	errLabelsTooMany    = invalidInput("labels must contain at most %d entries", maxSessionLabels)
+	errLabelKeyInvalid  = invalidInput("label keys must be non-empty valid UTF-8 of at most %d bytes", maxLabelKeyBytes)
+	errLabelValueTooBig = invalidInput("label values must be valid UTF-8 of at most %d bytes", maxLabelValueBytes)
+)
+
+// validateLabels reports whether labels can be set on a session, or used to
+// select sessions.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxSessionLabels {
+		return errLabelsTooMany
+	}
+	for key, value := range labels {
+		if key == "" || len(key) > maxLabelKeyBytes || !utf8.ValidString(key) {
+			return errLabelKeyInvalid
+		}
+		if len(value) > maxLabelValueBytes || !utf8.ValidString(value) {
+			return errLabelValueTooBig
+		}
+	}
+	return nil
+}
+
+// matchesLabels reports whether labels carry every pair in selector. An empty
+// selector matches every session.
+func matchesLabels(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if got, ok := labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *InMemoryStore) SetSessionLabels(ctx context.Context, tenantID, sessionID string, labels map[string]string) error {
+	return s.setSessionLabels(ctx, tenantID, sessionID, labels, true, nil)
+}
+
+// setSessionLabels replaces a session's labels; empty labels remove them all.
+// Like setSessionSpanPolicy it opens a missing session, counting against the
+// session quota unless enforceQuotas is false.
+func (s *InMemoryStore) setSessionLabels(
+	ctx context.Context,
+	tenantID, sessionID string,
+	labels map[string]string,
+	enforceQuotas bool,
+	commit func() error,
+) (err error) {
+	_, span := tracer.Start(ctx, "memory.SetSessionLabels")
+	defer func() { endSpan(span, err) }()
+
+	if tenantID == "" {
+		return errTenantIDRequired
+	}
+	if sessionID == "" {
+		return errSessionIDRequired
+	}
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	if enforceQuotas {
+		if err := s.checkQuotasLocked(nil, key); err != nil {
+			return err
+		}
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	session := s.ensureSession(key)
+	session.labels = nil
+	if len(labels) > 0 {
+		session.labels = maps.Clone(labels)
+	}
+	session.touch(s.now())
+	return nil
+}
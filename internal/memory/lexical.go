@@ -0,0 +1,299 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Okapi BM25 term frequency saturation and length normalization.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// rrfK damps the weight of top ranks in reciprocal rank fusion; 60 is
+	// the value from the original paper.
+	rrfK = 60
+	// rrfCandidates is how deep each ranking is read before fusion, so an
+	// event ranked just below top_k by one signal can still be lifted by
+	// the other.
+	rrfCandidates = 100
+
+	maxQueryTextBytes = 4 << 10
+)
+
+var (
+	errQueryTextNoTerms  = invalidInput("query_text must contain at least one letter or digit")
+	errQueryTextTooLarge = invalidInput("query_text must be at most %d bytes", maxQueryTextBytes)
+)
+
+// TextQuery ranks a session's events by the BM25 relevance of their payload
+// text to Text. When QueryEmbedding is set too, the BM25 ranking and the
+// similarity ranking are fused with reciprocal rank fusion; Recency then
+// applies to the similarity ranking, and needs QueryEmbedding.
+type TextQuery struct {
+	TenantID       string
+	SessionID      string
+	Text           string
+	QueryEmbedding []float32
+	Metric         SimilarityMetric
+	TopK           int
+	BufferBefore   int
+	BufferAfter    int
+	Recency        *Recency
+}
+
+func (query TextQuery) validate() error {
+	if query.TopK <= 0 {
+		return errRetrieveTopKNonPositive
+	}
+	if query.BufferBefore < 0 || query.BufferAfter < 0 {
+		return errRetrieveBufferNegative
+	}
+	if err := validateQueryText(query.Text); err != nil {
+		return err
+	}
+	if len(query.QueryEmbedding) > 0 {
+		if err := query.Metric.validate(); err != nil {
+			return err
+		}
+		if err := validateVector(query.QueryEmbedding); err != nil {
+			return fmt.Errorf("query_embedding: %w", err)
+		}
+	} else if query.Recency != nil {
+		return errRecencyNeedsVector
+	}
+	return query.Recency.validate(query.Metric)
+}
+
+func validateQueryText(text string) error {
+	if len(text) > maxQueryTextBytes {
+		return errQueryTextTooLarge
+	}
+	if len(tokenizeText(text)) == 0 {
+		return errQueryTextNoTerms
+	}
+	return nil
+}
+
+// tokenizeText lowercases text and splits it into runs of letters, digits
+// and underscores, so identifiers and error codes stay whole terms.
+func tokenizeText(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// textDoc names one event in a tenant text index.
+type textDoc struct {
+	sessionID string
+	eventID   string
+}
+
+// textIndex is an inverted index over the payload text of one tenant's
+// events. Term statistics are tenant wide, so scores do not depend on how
+// events are spread over sessions.
+type textIndex struct {
+	// postings maps each term to the term frequency in every event that
+	// contains it.
+	postings    map[string]map[textDoc]int
+	lengths     map[textDoc]int
+	totalLength int
+}
+
+func newTextIndex() *textIndex {
+	return &textIndex{
+		postings: make(map[string]map[textDoc]int),
+		lengths:  make(map[textDoc]int),
+	}
+}
+
+func (idx *textIndex) add(doc textDoc, terms []string) {
+	for _, term := range terms {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = make(map[textDoc]int)
+			idx.postings[term] = postings
+		}
+		postings[doc]++
+	}
+	idx.lengths[doc] = len(terms)
+	idx.totalLength += len(terms)
+}
+
+func (idx *textIndex) remove(doc textDoc, terms []string) {
+	for _, term := range terms {
+		postings := idx.postings[term]
+		delete(postings, doc)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= idx.lengths[doc]
+	delete(idx.lengths, doc)
+}
+
+// score returns the BM25 score of every event of sessionID that contains at
+// least one of terms, by event id.
+func (idx *textIndex) score(sessionID string, terms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	docs := float64(len(idx.lengths))
+	avgLength := float64(idx.totalLength) / docs
+	seen := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+
+		postings := idx.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (docs-df+0.5)/(df+0.5))
+		for doc, tf := range postings {
+			if doc.sessionID != sessionID {
+				continue
+			}
+			f := float64(tf)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(idx.lengths[doc])/avgLength)
+			scores[doc.eventID] += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+	}
+	return scores
+}
+
+func eventText(event Event) string {
+	if event.Payload == nil {
+		return ""
+	}
+	return event.Payload.Text
+}
+
+// indexTextLocked adds the payload text of a stored event to its tenant's
+// text index. The caller must hold s.mu.
+func (s *InMemoryStore) indexTextLocked(event Event) {
+	terms := tokenizeText(eventText(event))
+	if len(terms) == 0 {
+		return
+	}
+	idx, ok := s.text[event.TenantID]
+	if !ok {
+		idx = newTextIndex()
+		s.text[event.TenantID] = idx
+	}
+	idx.add(textDoc{sessionID: event.SessionID, eventID: event.EventID}, terms)
+}
+
+// unindexTextLocked removes a stored event from its tenant's text index and
+// drops the index once it is empty. The caller must hold s.mu.
+func (s *InMemoryStore) unindexTextLocked(event Event) {
+	terms := tokenizeText(eventText(event))
+	idx, ok := s.text[event.TenantID]
+	if len(terms) == 0 || !ok {
+		return
+	}
+	idx.remove(textDoc{sessionID: event.SessionID, eventID: event.EventID}, terms)
+	if len(idx.lengths) == 0 {
+		delete(s.text, event.TenantID)
+	}
+}
+
+// RetrieveByText picks the TopK events that best match the query, by BM25
+// alone or fused with similarity, and expands them with the same contiguity
+// buffers as RetrieveByAnchors. The provenance score of an anchor is its
+// BM25 score, or its fused score for a hybrid query.
+func (s *InMemoryStore) RetrieveByText(ctx context.Context, query TextQuery) (_ []RetrievedEvent, err error) {
+	_, span := tracer.Start(ctx, "memory.RetrieveByText", trace.WithAttributes(
+		attribute.Int("top_k", query.TopK),
+		attribute.Bool("hybrid", len(query.QueryEmbedding) > 0),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: query.TenantID, sessionID: query.SessionID}
+	session, ok := s.sessions[key]
+	if !ok || len(session.ordered) == 0 {
+		return []RetrievedEvent{}, nil
+	}
+	session.touch(s.now())
+
+	anchors, scores, err := s.textAnchors(key, session, query.Text, query.QueryEmbedding, query.Metric, query.TopK, query.Recency)
+	if err != nil {
+		return nil, err
+	}
+	return s.expandObserved(query.TenantID, query.TopK, session.ordered, anchors, scores, query.BufferBefore, query.BufferAfter), nil
+}
+
+// textAnchors returns the positions in session order of the topK events that
+// best match text, best first, with their scores. With a query embedding the
+// BM25 and similarity rankings are fused, recency weighting the latter. The
+// caller must hold s.mu.
+func (s *InMemoryStore) textAnchors(
+	key sessionKey,
+	session *sessionEvents,
+	text string,
+	embedding []float32,
+	metric SimilarityMetric,
+	topK int,
+	recency *Recency,
+) ([]int, []float64, error) {
+	lexical := s.lexicalRanking(key, session, text)
+	if len(embedding) == 0 {
+		anchors, scores := splitRanking(lexical, topK)
+		return anchors, scores, nil
+	}
+
+	var vector []int
+	if session.embeddingDim != 0 {
+		if len(embedding) != session.embeddingDim {
+			return nil, nil, errQueryEmbeddingDimMismatch
+		}
+		vector, _, _ = s.similarAnchors(session, embedding, metric, max(topK, rrfCandidates), recency)
+	}
+
+	fused := make(map[int]float64)
+	for rank, ranked := range lexical[:min(rrfCandidates, len(lexical))] {
+		fused[ranked.index] += 1 / float64(rrfK+rank+1)
+	}
+	for rank, index := range vector {
+		fused[index] += 1 / float64(rrfK+rank+1)
+	}
+	ranking := make([]rankedIndex, 0, len(fused))
+	for index, score := range fused {
+		ranking = append(ranking, rankedIndex{index: index, score: score})
+	}
+	sortRanking(ranking)
+
+	anchors, scores := splitRanking(ranking, topK)
+	return anchors, scores, nil
+}
+
+// lexicalRanking scores the session's events against text with BM25, best
+// first. Events that share no term with text are left out. The caller must
+// hold s.mu.
+func (s *InMemoryStore) lexicalRanking(key sessionKey, session *sessionEvents, text string) []rankedIndex {
+	idx, ok := s.text[key.tenantID]
+	if !ok {
+		return nil
+	}
+	scores := idx.score(key.sessionID, tokenizeText(text))
+	ranking := make([]rankedIndex, 0, len(scores))
+	for eventID, score := range scores {
+		if index, ok := session.orderedIndex(eventID); ok {
+			ranking = append(ranking, rankedIndex{index: index, score: score})
+		}
+	}
+	sortRanking(ranking)
+	return ranking
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTokenizeText(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{text: "Connection reset: ERR_CONN_RESET (code 104)", want: []string{"connection", "reset", "err_conn_reset", "code", "104"}},
+		{text: "Grüße, Zoë!", want: []string{"grüße", "zoë"}},
+		{text: " -- ", want: nil},
+	}
+
+	for _, tc := range cases {
+		if got := tokenizeText(tc.text); !slices.Equal(got, tc.want) {
+			t.Fatalf("expected %q to tokenize to %q, got %q", tc.text, tc.want, got)
+		}
+	}
+}
+
+func appendTextEvents(t *testing.T, store Store, tenantID, sessionID string, texts ...string) {
+	t.Helper()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := make([]Event, len(texts))
+	for i, text := range texts {
+		events[i] = mustEvent(t, fmt.Sprintf("evt_%d", i), tenantID, sessionID, i*10, i*10+10, now)
+		events[i].Payload = &Payload{Text: text}
+	}
+	if err := store.AppendMany(context.Background(), events); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+}
+
+// textRanks maps each anchor a text retrieval returned to its rank.
+func textRanks(t *testing.T, store Store, query TextQuery) map[string]int {
+	t.Helper()
+
+	results, err := store.RetrieveByText(context.Background(), query)
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	ranks := make(map[string]int)
+	for _, result := range results {
+		if result.Retrieval.Role == RetrievalAnchor {
+			ranks[result.EventID] = result.Retrieval.Rank
+		}
+	}
+	return ranks
+}
+
+func TestRetrieveByTextRanksByBM25(t *testing.T) {
+	store := NewStore()
+	appendTextEvents(t, store, "tenant_1", "session_1",
+		"connection reset by peer: ERR_CONN_RESET",
+		"user alice logged in",
+		"alice saw ERR_CONN_RESET again, ERR_CONN_RESET twice",
+		"nothing relevant here",
+	)
+	// The same text elsewhere in the tenant or in another tenant never leaks
+	// into the session's results.
+	appendTextEvents(t, store, "tenant_1", "session_2", "ERR_CONN_RESET ERR_CONN_RESET ERR_CONN_RESET")
+	appendTextEvents(t, store, "tenant_2", "session_1", "ERR_CONN_RESET ERR_CONN_RESET ERR_CONN_RESET")
+
+	cases := []struct {
+		text string
+		want map[string]int
+	}{
+		{text: "err_conn_reset", want: map[string]int{"evt_2": 1, "evt_0": 2}},
+		{text: "Alice", want: map[string]int{"evt_1": 1, "evt_2": 2}},
+		{text: "unknown term", want: map[string]int{}},
+	}
+
+	for _, tc := range cases {
+		got := textRanks(t, store, TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: tc.text, TopK: 5})
+		if !maps.Equal(got, tc.want) {
+			t.Fatalf("expected %q to rank %v, got %v", tc.text, tc.want, got)
+		}
+	}
+
+	results, err := store.RetrieveByText(context.Background(), TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: "alice", TopK: 1, BufferAfter: 1})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if ids := retrievedIDs(results); !slices.Equal(ids, []string{"evt_1", "evt_2"}) {
+		t.Fatalf("expected evt_1 with its buffer, got %v", ids)
+	}
+	if score := results[0].Retrieval.Score; score == nil || *score <= 0 {
+		t.Fatalf("expected a positive BM25 score on the anchor, got %v", score)
+	}
+}
+
+func TestRetrieveByTextFusesWithSimilarity(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	events := []Event{
+		mustEvent(t, "evt_0", "tenant_1", "session_1", 0, 10, now),
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 20, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 20, 30, now),
+	}
+	// evt_0 only matches the text, evt_1 only the vector and evt_2 is second
+	// on both, which fusion puts first.
+	events[0].Payload = &Payload{Text: "alpha alpha"}
+	events[1].Payload, events[1].Embeddings = &Payload{Text: "beta"}, [][]float32{{1, 0}}
+	events[2].Payload, events[2].Embeddings = &Payload{Text: "alpha beta gamma delta"}, [][]float32{{0.6, 0.8}}
+	if err := store.AppendMany(context.Background(), events); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	got := textRanks(t, store, TextQuery{
+		TenantID: "tenant_1", SessionID: "session_1", Text: "alpha",
+		QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 3,
+	})
+	if want := map[string]int{"evt_2": 1, "evt_0": 2, "evt_1": 3}; !maps.Equal(got, want) {
+		t.Fatalf("expected fused ranks %v, got %v", want, got)
+	}
+
+	if _, err := store.RetrieveByText(context.Background(), TextQuery{
+		TenantID: "tenant_1", SessionID: "session_1", Text: "alpha",
+		QueryEmbedding: []float32{1, 0, 0}, Metric: SimilarityCosine, TopK: 3,
+	}); err == nil {
+		t.Fatalf("expected error for a query embedding of another dimension")
+	}
+}
+
+func TestTextIndexFollowsUpdatesAndDeletes(t *testing.T) {
+	store := NewStore()
+	appendTextEvents(t, store, "tenant_1", "session_1", "alpha", "beta")
+	appendTextEvents(t, store, "tenant_1", "session_2", "alpha")
+	query := TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: "alpha beta gamma", TopK: 5}
+
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_0", EventPatch{Payload: &Payload{Text: "gamma"}}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := textRanks(t, store, TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: "alpha", TopK: 5}); len(got) != 0 {
+		t.Fatalf("expected the old text to be unindexed, got %v", got)
+	}
+	if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", "evt_1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := textRanks(t, store, query); !maps.Equal(got, map[string]int{"evt_0": 1}) {
+		t.Fatalf("expected only evt_0 to match, got %v", got)
+	}
+
+	if _, err := store.DeleteSession(context.Background(), "tenant_1", "session_1"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if idx := store.text["tenant_1"]; idx == nil || len(idx.lengths) != 1 || idx.totalLength != 1 {
+		t.Fatalf("expected only session_2 to stay indexed, got %+v", idx)
+	}
+	if _, err := store.PurgeTenant(context.Background(), "tenant_1"); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if _, ok := store.text["tenant_1"]; ok {
+		t.Fatalf("expected the tenant text index to be dropped")
+	}
+}
+
+func TestDurableStoreRebuildsTextIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenDurableStore(t, dir)
+	appendTextEvents(t, store, "tenant_1", "session_1", "alpha", "beta")
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	for _, snapshot := range []bool{false, true} {
+		reopened := mustOpenDurableStore(t, dir)
+		got := textRanks(t, reopened, TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: "beta", TopK: 1})
+		if !maps.Equal(got, map[string]int{"evt_1": 1}) {
+			t.Fatalf("snapshot=%v: expected evt_1 after a restart, got %v", snapshot, got)
+		}
+		if err := reopened.Snapshot(); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+}
+
+func TestRetrieveByTextRejectsInvalidQueries(t *testing.T) {
+	store := NewStore()
+
+	for name, query := range map[string]TextQuery{
+		"no terms":       {Text: "--", TopK: 1},
+		"too long":       {Text: string(make([]byte, maxQueryTextBytes+1)), TopK: 1},
+		"no top_k":       {Text: "alpha"},
+		"unknown metric": {Text: "alpha", QueryEmbedding: []float32{1}, Metric: "l2", TopK: 1},
+	} {
+		query.TenantID, query.SessionID = "tenant_1", "session_1"
+		if _, err := store.RetrieveByText(context.Background(), query); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidCursor reports a cursor that was not issued by ListEvents or
+// ListSessions.
+var ErrInvalidCursor = invalidInput("invalid cursor")
+
+var (
+	errLimitNegative       = invalidInput("limit must be >= 0")
+	errTokenRangeNegative  = invalidInput("token range bounds must be >= 0")
+	errTokenRangeEmpty     = invalidInput("to_token must be greater than from_token")
+	errCreatedRangeInvalid = invalidInput("created_after must be before created_before")
+)
+
+// ListQuery selects one page of a session's events.
+type ListQuery struct {
+	TenantID  string
+	SessionID string
+	// Limit caps the page size; zero returns every matching event.
+	Limit int
+	// Cursor continues after the last event of a previous page. It names a
+	// position in session order rather than an offset, so events appended or
+	// deleted between pages never cause skips or repeats.
+	Cursor string
+	// Reverse walks the session from its last event to its first.
+	Reverse bool
+	// FromToken and ToToken keep events whose span overlaps
+	// [FromToken, ToToken). A zero ToToken leaves the range open-ended.
+	FromToken int
+	ToToken   int
+	// CreatedAfter and CreatedBefore keep events created in
+	// [CreatedAfter, CreatedBefore). Zero values are unbounded. Unlike the
+	// token range, which binary searches session order, this window has no
+	// index: CreatedAt need not follow session order, so events are scanned
+	// from the cursor until the page fills.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// EventPage is one page of ListEvents results.
+type EventPage struct {
+	Events []Event
+	// NextCursor continues after Events; it is empty on the last page.
+	NextCursor string
+}
+
+func (q ListQuery) validate() error {
+	if q.Limit < 0 {
+		return errLimitNegative
+	}
+	if q.FromToken < 0 || q.ToToken < 0 {
+		return errTokenRangeNegative
+	}
+	if q.ToToken != 0 && q.ToToken <= q.FromToken {
+		return errTokenRangeEmpty
+	}
+	if !q.CreatedAfter.IsZero() && !q.CreatedBefore.IsZero() && !q.CreatedAfter.Before(q.CreatedBefore) {
+		return errCreatedRangeInvalid
+	}
+	return nil
+}
+
+// matches applies the filters that the index range cannot narrow exactly.
+func (q ListQuery) matches(event Event) bool {
+	if event.EndTokenExclusive <= q.FromToken {
+		return false
+	}
+	if q.ToToken != 0 && event.StartToken >= q.ToToken {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && event.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !event.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// listCursor is the session order key of the last event on a page.
+type listCursor struct {
+	StartToken int       `json:"s"`
+	CreatedAt  time.Time `json:"c"`
+	EventID    string    `json:"e"`
+}
+
+func encodeListCursor(event Event) string {
+	data, _ := json.Marshal(listCursor{StartToken: event.StartToken, CreatedAt: event.CreatedAt, EventID: event.EventID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(cursor string) (Event, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Event{}, ErrInvalidCursor
+	}
+	var position listCursor
+	if err := json.Unmarshal(data, &position); err != nil || position.EventID == "" {
+		return Event{}, ErrInvalidCursor
+	}
+	return Event{StartToken: position.StartToken, CreatedAt: position.CreatedAt, EventID: position.EventID}, nil
+}
+
+func (s *InMemoryStore) ListEvents(ctx context.Context, query ListQuery) (page EventPage, err error) {
+	_, span := tracer.Start(ctx, "memory.ListEvents")
+	defer func() {
+		span.SetAttributes(attribute.Int("events", len(page.Events)))
+		endSpan(span, err)
+	}()
+
+	if err := query.validate(); err != nil {
+		return EventPage{}, err
+	}
+	var cursor Event
+	if query.Cursor != "" {
+		if cursor, err = decodeListCursor(query.Cursor); err != nil {
+			return EventPage{}, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
+
+	page.Events = []Event{}
+	session, ok := s.sessions[sessionKey{tenantID: query.TenantID, sessionID: query.SessionID}]
+	if !ok {
+		return page, nil
+	}
+	session.touch(s.now())
+
+	lo, hi := session.listRange(query, cursor)
+	next := func(i int) int { return i + 1 }
+	i := lo
+	if query.Reverse {
+		next = func(i int) int { return i - 1 }
+		i = hi - 1
+	}
+	for ; i >= lo && i < hi; i = next(i) {
+		event := session.ordered[i]
+		if !query.matches(event) {
+			continue
+		}
+		if query.Limit != 0 && len(page.Events) == query.Limit {
+			page.NextCursor = encodeListCursor(page.Events[len(page.Events)-1])
+			break
+		}
+		page.Events = append(page.Events, event.clone())
+	}
+	return page, nil
+}
+
+// listRange narrows session.ordered to [lo, hi) by binary search on the
+// token range and the cursor position. Events in the range still need
+// query.matches.
+func (session *sessionEvents) listRange(query ListQuery, cursor Event) (lo, hi int) {
+	ordered := session.ordered
+	hi = len(ordered)
+	if query.FromToken > 0 {
+		// No event spans more than maxSpan tokens, so one starting at or
+		// before FromToken-maxSpan ends before the range begins.
+		lo = sort.Search(len(ordered), func(i int) bool {
+			return ordered[i].StartToken > query.FromToken-session.maxSpan
+		})
+	}
+	if query.ToToken > 0 {
+		hi = sort.Search(len(ordered), func(i int) bool {
+			return ordered[i].StartToken >= query.ToToken
+		})
+	}
+	if cursor.EventID == "" {
+		return lo, hi
+	}
+	if query.Reverse {
+		hi = min(hi, sort.Search(len(ordered), func(i int) bool {
+			return !eventLess(ordered[i], cursor)
+		}))
+	} else {
+		lo = max(lo, sort.Search(len(ordered), func(i int) bool {
+			return eventLess(cursor, ordered[i])
+		}))
+	}
+	return lo, max(lo, hi)
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listEvents is a session with a shared start token and a long event, one
+// minute apart.
+func listEvents(t *testing.T, now time.Time) []Event {
+	t.Helper()
+
+	return []Event{
+		mustEvent(t, "evt_a", "tenant_1", "session_1", 0, 10, now),
+		mustEvent(t, "evt_b", "tenant_1", "session_1", 10, 20, now.Add(time.Minute)),
+		mustEvent(t, "evt_c", "tenant_1", "session_1", 10, 20, now.Add(2*time.Minute)),
+		mustEvent(t, "evt_d", "tenant_1", "session_1", 20, 60, now.Add(3*time.Minute)),
+		mustEvent(t, "evt_e", "tenant_1", "session_1", 60, 70, now.Add(4*time.Minute)),
+	}
+}
+
+func TestListEventsFilters(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := mustStore(t, listEvents(t, now)...)
+
+	cases := []struct {
+		name  string
+		query ListQuery
+		want  []string
+	}{
+		{name: "all", query: ListQuery{}, want: []string{"evt_a", "evt_b", "evt_c", "evt_d", "evt_e"}},
+		{name: "reverse", query: ListQuery{Reverse: true}, want: []string{"evt_e", "evt_d", "evt_c", "evt_b", "evt_a"}},
+		{name: "token overlap", query: ListQuery{FromToken: 15, ToToken: 25}, want: []string{"evt_b", "evt_c", "evt_d"}},
+		{name: "long event overlaps late range", query: ListQuery{FromToken: 55, ToToken: 61}, want: []string{"evt_d", "evt_e"}},
+		{name: "open-ended token range", query: ListQuery{FromToken: 60}, want: []string{"evt_e"}},
+		{name: "created window", query: ListQuery{CreatedAfter: now.Add(time.Minute), CreatedBefore: now.Add(3 * time.Minute)}, want: []string{"evt_b", "evt_c"}},
+		{name: "limit", query: ListQuery{Limit: 2, Reverse: true}, want: []string{"evt_e", "evt_d"}},
+	}
+
+	for _, tc := range cases {
+		tc.query.TenantID, tc.query.SessionID = "tenant_1", "session_1"
+		page, err := store.ListEvents(context.Background(), tc.query)
+		if err != nil {
+			t.Fatalf("%s: list: %v", tc.name, err)
+		}
+		if got := eventIDs(page.Events); !slices.Equal(got, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestListEventsPagesWithStableCursors(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, reverse := range []bool{false, true} {
+		store := mustStore(t, listEvents(t, now)...)
+		query := ListQuery{TenantID: "tenant_1", SessionID: "session_1", Limit: 2, Reverse: reverse}
+
+		first, err := store.ListEvents(context.Background(), query)
+		if err != nil || first.NextCursor == "" {
+			t.Fatalf("expected a first page with a cursor, got %+v, %v", first, err)
+		}
+
+		// Removing an event already returned must not shift the next page.
+		if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", first.Events[0].EventID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+
+		var got []string
+		got = append(got, eventIDs(first.Events)...)
+		query.Cursor = first.NextCursor
+		for query.Cursor != "" {
+			page, err := store.ListEvents(context.Background(), query)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			got = append(got, eventIDs(page.Events)...)
+			query.Cursor = page.NextCursor
+		}
+
+		want := []string{"evt_a", "evt_b", "evt_c", "evt_d", "evt_e"}
+		if reverse {
+			want = []string{"evt_e", "evt_d", "evt_c", "evt_b", "evt_a"}
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("reverse=%v: expected %v, got %v", reverse, want, got)
+		}
+	}
+}
+
+func TestListEventsRejectsInvalidQueries(t *testing.T) {
+	store := NewStore()
+
+	for _, query := range []ListQuery{
+		{Limit: -1},
+		{FromToken: 10, ToToken: 10},
+		{CreatedAfter: time.Unix(10, 0), CreatedBefore: time.Unix(5, 0)},
+	} {
+		query.TenantID, query.SessionID = "tenant_1", "session_1"
+		if _, err := store.ListEvents(context.Background(), query); err == nil {
+			t.Fatalf("expected error for %+v", query)
+		}
+	}
+
+	_, err := store.ListEvents(context.Background(), ListQuery{TenantID: "tenant_1", SessionID: "session_1", Cursor: "not-a-cursor"})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidCursor, err)
+	}
+
+	page, err := store.ListEvents(context.Background(), ListQuery{TenantID: "tenant_1", SessionID: "missing"})
+	if err != nil || len(page.Events) != 0 || page.Events == nil {
+		t.Fatalf("expected an empty page for a missing session, got %+v, %v", page, err)
+	}
+}
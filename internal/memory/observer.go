@@ -0,0 +1,29 @@
+package memory
+
+// Observer receives statistics about accepted store operations, for metrics.
+// Methods are called while the store lock is held, so they must be fast and
+// must not call back into the store.
+type Observer interface {
+	// ObserveSegmentation reports one accepted segmentation: how many
+	// boundaries it finalized and the token length of every event it appended.
+	ObserveSegmentation(tenantID string, boundaries int, eventLengths []int)
+	// ObserveRetrieval reports one retrieval: how many anchors were asked for,
+	// how many were found in the session, and how many events the contiguity
+	// buffers expanded them to.
+	ObserveRetrieval(tenantID string, requested, found, returned int)
+	// ObserveExpiry reports what one sweep removed from a tenant for reason.
+	ObserveExpiry(tenantID string, reason ExpiryReason, sessions, events int)
+}
+
+// StoreStats is a point-in-time summary of what a store holds.
+type StoreStats struct {
+	Tenants map[string]TenantStats
+}
+
+// TenantStats summarizes one tenant's stored data.
+type TenantStats struct {
+	Sessions int
+	Events   int
+	// PayloadBytes is measured the same way as Quota.MaxPayloadBytes.
+	PayloadBytes int64
+}
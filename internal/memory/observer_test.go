@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	segmentations [][]int
+	boundaries    []int
+	retrievals    [][3]int
+	expired       map[ExpiryReason]Deletion
+}
+
+func (o *recordingObserver) ObserveSegmentation(_ string, boundaries int, eventLengths []int) {
+	o.boundaries = append(o.boundaries, boundaries)
+	o.segmentations = append(o.segmentations, eventLengths)
+}
+
+func (o *recordingObserver) ObserveRetrieval(_ string, requested, found, returned int) {
+	o.retrievals = append(o.retrievals, [3]int{requested, found, returned})
+}
+
+func (o *recordingObserver) ObserveExpiry(_ string, reason ExpiryReason, sessions, events int) {
+	if o.expired == nil {
+		o.expired = make(map[ExpiryReason]Deletion)
+	}
+	counted := o.expired[reason]
+	counted.Sessions += sessions
+	counted.Events += events
+	o.expired[reason] = counted
+}
+
+func TestStoreReportsToObserver(t *testing.T) {
+	now := time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+	observer := &recordingObserver{}
+	store := NewStoreWithOptions(StoreOptions{Observer: observer})
+
+	if _, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		Surprise:       []float64{0.1, 0.9, 0.1, 0.1, 0.9, 0.1},
+		Threshold:      0.5,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	}); err != nil {
+		t.Fatalf("segment: %v", err)
+	}
+	if len(observer.boundaries) != 1 || observer.boundaries[0] != 2 {
+		t.Fatalf("expected one segmentation with 2 boundaries, got %v", observer.boundaries)
+	}
+	if got := observer.segmentations[0]; len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 1 {
+		t.Fatalf("expected event lengths [2 3 1], got %v", got)
+	}
+
+	if _, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"seg_1", "missing"}, 2, 1, 0); err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(observer.retrievals) != 1 || observer.retrievals[0] != [3]int{2, 1, 2} {
+		t.Fatalf("expected retrieval [2 1 2], got %v", observer.retrievals)
+	}
+}
+
+func TestStoreStatsTracksTenants(t *testing.T) {
+	now := time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+	store := NewStore()
+
+	withPayload := mustEvent(t, "evt_2", "tenant_1", "session_2", 0, 1, now)
+	withPayload.Payload = &Payload{Text: "hi"}
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now),
+		withPayload,
+		mustEvent(t, "evt_1", "tenant_2", "session_1", 0, 1, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	stats := store.Stats()
+	if got := stats.Tenants["tenant_1"]; got != (TenantStats{Sessions: 2, Events: 2, PayloadBytes: 2}) {
+		t.Fatalf("expected tenant_1 stats {2 2 2}, got %+v", got)
+	}
+	if got := stats.Tenants["tenant_2"]; got != (TenantStats{Sessions: 1, Events: 1}) {
+		t.Fatalf("expected tenant_2 stats {1 1 0}, got %+v", got)
+	}
+}
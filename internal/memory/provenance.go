@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+)
+
+// RetrievalRole says how a retrieved event relates to the anchor it was
+// retrieved for.
+type RetrievalRole string
+
+const (
+	RetrievalAnchor       RetrievalRole = "anchor"
+	RetrievalBeforeBuffer RetrievalRole = "before-buffer"
+	RetrievalAfterBuffer  RetrievalRole = "after-buffer"
+)
+
+// Provenance explains why an event was retrieved. An event inside several
+// anchor windows is attributed to its nearest anchor, the better-ranked one
+// on a tie; an anchor is always attributed to itself.
+type Provenance struct {
+	Role RetrievalRole `json:"role"`
+	// Rank is the 1-based rank of the attributed anchor.
+	Rank int `json:"rank"`
+	// Distance is how many events away the attributed anchor is; 0 for
+	// anchors.
+	Distance int `json:"distance"`
+	// Anchors lists every anchor whose window includes the event, best
+	// ranked first.
+	Anchors []string `json:"anchors"`
+	// Score is the similarity of an anchor to the query. It is set only on
+	// anchors of a similarity retrieval.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// RetrievedEvent is an event returned by retrieval with its provenance.
+type RetrievedEvent struct {
+	Event
+	Retrieval Provenance `json:"retrieval"`
+}
+
+// expandAnchors returns the anchors, given best first, plus
+// bufferBefore/bufferAfter neighbours of each, in session order and without
+// duplicates. scores, when not nil, holds the similarity of each anchor.
+func expandAnchors(ordered []Event, anchorIndexes []int, scores []float64, bufferBefore, bufferAfter int) []RetrievedEvent {
+	if len(anchorIndexes) == 0 {
+		return []RetrievedEvent{}
+	}
+
+	// Walking anchors in rank order keeps each Anchors list best first and
+	// lets the strict distance check keep the better rank on a tie.
+	provenance := make(map[int]*Provenance)
+	for rank, anchor := range anchorIndexes {
+		anchorID := ordered[anchor].EventID
+		start := max(0, anchor-bufferBefore)
+		end := min(len(ordered)-1, anchor+bufferAfter)
+		for i := start; i <= end; i++ {
+			distance := max(anchor-i, i-anchor)
+			p, ok := provenance[i]
+			if !ok {
+				p = &Provenance{}
+				provenance[i] = p
+			}
+			p.Anchors = append(p.Anchors, anchorID)
+			if ok && distance >= p.Distance {
+				continue
+			}
+
+			p.Rank = rank + 1
+			p.Distance = distance
+			switch {
+			case i < anchor:
+				p.Role = RetrievalBeforeBuffer
+			case i > anchor:
+				p.Role = RetrievalAfterBuffer
+			default:
+				p.Role = RetrievalAnchor
+				if scores != nil {
+					p.Score = &scores[rank]
+				}
+			}
+		}
+	}
+
+	result := make([]RetrievedEvent, 0, len(provenance))
+	for _, i := range slices.Sorted(maps.Keys(provenance)) {
+		result = append(result, RetrievedEvent{Event: ordered[i].clone(), Retrieval: *provenance[i]})
+	}
+
+	return result
+}
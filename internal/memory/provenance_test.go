@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetrieveByAnchorsAnnotatesProvenance(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	for i := range 6 {
+		if err := store.Append(context.Background(), mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, i*10+10, now)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		anchors []string
+		buffer  int
+		want    map[string]Provenance
+	}{
+		{
+			name:    "tie goes to the better rank",
+			anchors: []string{"evt_3", "evt_1"},
+			buffer:  1,
+			want: map[string]Provenance{
+				"evt_0": {Role: RetrievalBeforeBuffer, Rank: 2, Distance: 1, Anchors: []string{"evt_1"}},
+				"evt_1": {Role: RetrievalAnchor, Rank: 2, Distance: 0, Anchors: []string{"evt_1"}},
+				"evt_2": {Role: RetrievalBeforeBuffer, Rank: 1, Distance: 1, Anchors: []string{"evt_3", "evt_1"}},
+				"evt_3": {Role: RetrievalAnchor, Rank: 1, Distance: 0, Anchors: []string{"evt_3"}},
+				"evt_4": {Role: RetrievalAfterBuffer, Rank: 1, Distance: 1, Anchors: []string{"evt_3"}},
+			},
+		},
+		{
+			name:    "nearer anchor wins",
+			anchors: []string{"evt_5", "evt_2"},
+			buffer:  2,
+			want: map[string]Provenance{
+				"evt_0": {Role: RetrievalBeforeBuffer, Rank: 2, Distance: 2, Anchors: []string{"evt_2"}},
+				"evt_1": {Role: RetrievalBeforeBuffer, Rank: 2, Distance: 1, Anchors: []string{"evt_2"}},
+				"evt_2": {Role: RetrievalAnchor, Rank: 2, Distance: 0, Anchors: []string{"evt_2"}},
+				"evt_3": {Role: RetrievalAfterBuffer, Rank: 2, Distance: 1, Anchors: []string{"evt_5", "evt_2"}},
+				"evt_4": {Role: RetrievalBeforeBuffer, Rank: 1, Distance: 1, Anchors: []string{"evt_5", "evt_2"}},
+				"evt_5": {Role: RetrievalAnchor, Rank: 1, Distance: 0, Anchors: []string{"evt_5"}},
+			},
+		},
+		{
+			name:    "anchor inside another window",
+			anchors: []string{"evt_5", "evt_4"},
+			buffer:  1,
+			want: map[string]Provenance{
+				"evt_3": {Role: RetrievalBeforeBuffer, Rank: 2, Distance: 1, Anchors: []string{"evt_4"}},
+				"evt_4": {Role: RetrievalAnchor, Rank: 2, Distance: 0, Anchors: []string{"evt_5", "evt_4"}},
+				"evt_5": {Role: RetrievalAnchor, Rank: 1, Distance: 0, Anchors: []string{"evt_5", "evt_4"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", tc.anchors, len(tc.anchors), tc.buffer, tc.buffer)
+		if err != nil {
+			t.Fatalf("%s: retrieve: %v", tc.name, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: expected %d events, got %v", tc.name, len(tc.want), retrievedIDs(got))
+		}
+		for _, event := range got {
+			if want := tc.want[event.EventID]; !reflect.DeepEqual(event.Retrieval, want) {
+				t.Fatalf("%s: expected %s provenance %+v, got %+v", tc.name, event.EventID, want, event.Retrieval)
+			}
+		}
+	}
+}
+
+func TestRetrieveBySimilarityScoresAnchors(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	for i, vector := range [][]float32{{1, 0}, {0, 1}, {1, 1}} {
+		event := mustEvent(t, fmt.Sprintf("evt_%d", i), "tenant_1", "session_1", i*10, i*10+10, now)
+		event.Embeddings = [][]float32{vector}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	got, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{0, 2}, Metric: SimilarityDot, TopK: 1, BufferBefore: 1, BufferAfter: 1})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %v", retrievedIDs(got))
+	}
+	anchor := got[1].Retrieval
+	if anchor.Role != RetrievalAnchor || anchor.Score == nil || *anchor.Score != 2 {
+		t.Fatalf("expected evt_1 anchor with score 2, got %+v", anchor)
+	}
+	for _, buffer := range []RetrievedEvent{got[0], got[2]} {
+		if buffer.Retrieval.Score != nil {
+			t.Fatalf("expected no score on buffer %s, got %v", buffer.EventID, *buffer.Retrieval.Score)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrQuotaExceeded reports a write that would take a tenant past one of its
+// storage quotas. Nothing from the rejected batch is stored.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Quota bounds what one tenant may store. Zero fields are unlimited.
+type Quota struct {
+	MaxEventsPerSession  int
+	MaxSessionsPerTenant int
+	// MaxPayloadBytes bounds the tenant's total payload size: text, role,
+	// speaker, metadata and tag bytes plus 4 bytes per token id and per
+	// embedding value.
+	MaxPayloadBytes int64
+}
+
+// QuotaPolicy holds the default quota and per-tenant overrides. A zero field
+// in an override inherits the default.
+type QuotaPolicy struct {
+	Default Quota
+	Tenants map[string]Quota
+}
+
+func (p QuotaPolicy) forTenant(tenantID string) Quota {
+	quota := p.Default
+	override, ok := p.Tenants[tenantID]
+	if !ok {
+		return quota
+	}
+	if override.MaxEventsPerSession != 0 {
+		quota.MaxEventsPerSession = override.MaxEventsPerSession
+	}
+	if override.MaxSessionsPerTenant != 0 {
+		quota.MaxSessionsPerTenant = override.MaxSessionsPerTenant
+	}
+	if override.MaxPayloadBytes != 0 {
+		quota.MaxPayloadBytes = override.MaxPayloadBytes
+	}
+	return quota
+}
+
+// tenantUsage tracks what a tenant stores, for quota checks and Stats.
+type tenantUsage struct {
+	sessions     int
+	events       int
+	payloadBytes int64
+}
+
+func (e Event) payloadBytes() int64 {
+	var size int64
+	if e.Payload != nil {
+		size += int64(len(e.Payload.Text) + len(e.Payload.Role) + len(e.Payload.Speaker))
+		size += 4 * int64(len(e.Payload.TokenIDs))
+	}
+	for _, vector := range e.Embeddings {
+		size += 4 * int64(len(vector))
+	}
+	for key, value := range e.Metadata {
+		size += int64(len(key) + len(value))
+	}
+	for _, tag := range e.Tags {
+		size += int64(len(tag))
+	}
+	return size
+}
+
+// checkQuotasLocked reports whether storing events, and opening the sessions
+// in newSessions, keeps every tenant within its quota. Log replay skips this
+// check so lowering a quota never makes stored data unreadable. The caller
+// must hold s.mu.
+func (s *InMemoryStore) checkQuotasLocked(events []Event, newSessions ...sessionKey) error {
+	type tenantDelta struct {
+		sessions     map[sessionKey]struct{}
+		payloadBytes int64
+	}
+
+	deltas := make(map[string]*tenantDelta)
+	sessionEvents := make(map[sessionKey]int)
+	delta := func(tenantID string) *tenantDelta {
+		d, ok := deltas[tenantID]
+		if !ok {
+			d = &tenantDelta{sessions: make(map[sessionKey]struct{})}
+			deltas[tenantID] = d
+		}
+		return d
+	}
+	for _, key := range newSessions {
+		if _, exists := s.sessions[key]; !exists {
+			delta(key.tenantID).sessions[key] = struct{}{}
+		}
+	}
+	for _, event := range events {
+		key := sessionKey{tenantID: event.TenantID, sessionID: event.SessionID}
+		d := delta(event.TenantID)
+		if _, exists := s.sessions[key]; !exists {
+			d.sessions[key] = struct{}{}
+		}
+		d.payloadBytes += event.payloadBytes()
+		sessionEvents[key]++
+	}
+
+	for key, added := range sessionEvents {
+		quota := s.opts.Quotas.forTenant(key.tenantID)
+		if quota.MaxEventsPerSession == 0 {
+			continue
+		}
+		existing := 0
+		if session, ok := s.sessions[key]; ok {
+			existing = len(session.ordered)
+		}
+		if existing+added > quota.MaxEventsPerSession {
+			return fmt.Errorf("%w: at most %d events per session", ErrQuotaExceeded, quota.MaxEventsPerSession)
+		}
+	}
+
+	for tenantID, d := range deltas {
+		quota := s.opts.Quotas.forTenant(tenantID)
+		usage := s.usage[tenantID]
+		if usage == nil {
+			usage = &tenantUsage{}
+		}
+		if quota.MaxSessionsPerTenant != 0 && usage.sessions+len(d.sessions) > quota.MaxSessionsPerTenant {
+			return fmt.Errorf("%w: at most %d sessions per tenant", ErrQuotaExceeded, quota.MaxSessionsPerTenant)
+		}
+		if quota.MaxPayloadBytes != 0 && usage.payloadBytes+d.payloadBytes > quota.MaxPayloadBytes {
+			return fmt.Errorf("%w: at most %d payload bytes per tenant", ErrQuotaExceeded, quota.MaxPayloadBytes)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreEnforcesQuotas(t *testing.T) {
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Quotas: QuotaPolicy{
+		Default: Quota{MaxEventsPerSession: 2, MaxSessionsPerTenant: 2, MaxPayloadBytes: 10},
+		Tenants: map[string]Quota{"tenant_big": {MaxEventsPerSession: 3}},
+	}})
+
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 1, 2, now),
+	}); err != nil {
+		t.Fatalf("append within quota: %v", err)
+	}
+	err := store.Append(context.Background(), mustEvent(t, "evt_3", "tenant_1", "session_1", 2, 3, now))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected events per session error %v, got %v", ErrQuotaExceeded, err)
+	}
+
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_2", 0, 1, now)); err != nil {
+		t.Fatalf("append second session: %v", err)
+	}
+	err = store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_3", 0, 1, now))
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "sessions") {
+		t.Fatalf("expected sessions per tenant error, got %v", err)
+	}
+
+	// Opening a stream claims a session even before any event is final.
+	_, err = store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_3",
+		Surprise:       []float64{0.1},
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+		Stream:         true,
+	})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected stream to respect the session quota, got %v", err)
+	}
+
+	withPayload := mustEvent(t, "evt_p", "tenant_2", "session_1", 0, 3, now)
+	withPayload.Payload = &Payload{Text: "hello", TokenIDs: []int{1, 2, 3}}
+	err = store.Append(context.Background(), withPayload)
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "payload") {
+		t.Fatalf("expected payload bytes error for 17 bytes, got %v", err)
+	}
+	if got := store.ListBySession("tenant_2", "session_1"); len(got) != 0 {
+		t.Fatalf("expected rejected batch to store nothing, got %#v", got)
+	}
+
+	// The override raises one limit and inherits the others.
+	for i, id := range []string{"evt_1", "evt_2", "evt_3"} {
+		if err := store.Append(context.Background(), mustEvent(t, id, "tenant_big", "session_1", i, i+1, now)); err != nil {
+			t.Fatalf("append %q under override: %v", id, err)
+		}
+	}
+	err = store.Append(context.Background(), mustEvent(t, "evt_4", "tenant_big", "session_1", 3, 4, now))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected override limit to apply, got %v", err)
+	}
+}
+
+func TestDurableStoreReplayIgnoresLoweredQuota(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 20, 9, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 1, 2, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened, err := OpenDurableStore(dir, DurableOptions{Store: StoreOptions{Quotas: QuotaPolicy{
+		Default: Quota{MaxEventsPerSession: 1},
+	}}})
+	if err != nil {
+		t.Fatalf("reopen with lower quota: %v", err)
+	}
+	defer reopened.Close()
+
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != 2 {
+		t.Fatalf("expected both events to be replayed, got %d", len(got))
+	}
+	err = reopened.Append(context.Background(), mustEvent(t, "evt_3", "tenant_1", "session_1", 2, 3, now))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected new writes to respect the quota, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"math"
+	"time"
+)
+
+// recencyCandidates is how many index matches are rescored when recency
+// reweights an HNSW search, so recent events just outside the top_k by
+// similarity can still move up.
+const recencyCandidates = 100
+
+var (
+	errUnknownRecencyBasis  = invalidInput(`recency basis must be "created_at" or "tokens"`)
+	errRecencyHalfLife      = invalidInput("recency half_life must be positive")
+	errRecencyHalfLifeToken = invalidInput("recency half_life_tokens must be positive")
+	errRecencyWeight        = invalidInput("recency weight must be greater than 0 and at most 1")
+	errRecencyNeedsVector   = invalidInput("recency requires query_embedding")
+	errRecencyNeedsCosine   = invalidInput(`recency requires the "cosine" metric`)
+)
+
+// RecencyBasis says how the age of an event is measured.
+type RecencyBasis string
+
+const (
+	// RecencyCreatedAt ages events by the time from CreatedAt to the store
+	// clock, so recent sessions rank above old ones.
+	RecencyCreatedAt RecencyBasis = "created_at"
+	// RecencyTokens ages events by how many tokens separate their end from
+	// the end of their session. Token positions are per session, so across
+	// sessions every head counts as equally recent.
+	RecencyTokens RecencyBasis = "tokens"
+)
+
+// Recency blends how recent an event is into its similarity score:
+//
+//	score = (1-Weight)*similarity + Weight*0.5^(age/half-life)
+//
+// The decay term lies in (0, 1], which is only commensurate with a bounded
+// similarity, so recency needs the cosine metric.
+type Recency struct {
+	Basis RecencyBasis
+	// HalfLife applies to RecencyCreatedAt and HalfLifeTokens to
+	// RecencyTokens.
+	HalfLife       time.Duration
+	HalfLifeTokens int
+	Weight         float64
+}
+
+func (r *Recency) validate(metric SimilarityMetric) error {
+	if r == nil {
+		return nil
+	}
+	if metric != SimilarityCosine {
+		return errRecencyNeedsCosine
+	}
+	switch r.Basis {
+	case RecencyCreatedAt:
+		if r.HalfLife <= 0 {
+			return errRecencyHalfLife
+		}
+	case RecencyTokens:
+		if r.HalfLifeTokens <= 0 {
+			return errRecencyHalfLifeToken
+		}
+	default:
+		return errUnknownRecencyBasis
+	}
+	// Written to reject NaN as well.
+	if !(r.Weight > 0 && r.Weight <= 1) {
+		return errRecencyWeight
+	}
+	return nil
+}
+
+// decay is 1 for an event created now, or at the end of its session, and
+// halves every half-life behind it.
+func (r *Recency) decay(now time.Time, session *sessionEvents, event Event) float64 {
+	var halfLives float64
+	switch r.Basis {
+	case RecencyCreatedAt:
+		age := max(0, now.Sub(event.CreatedAt))
+		halfLives = float64(age) / float64(r.HalfLife)
+	case RecencyTokens:
+		distance := max(0, session.endToken-event.EndTokenExclusive)
+		halfLives = float64(distance) / float64(r.HalfLifeTokens)
+	}
+	return math.Exp2(-halfLives)
+}
+
+// apply returns the recency-weighted score of an event; a nil Recency leaves
+// the similarity unchanged.
+func (r *Recency) apply(now time.Time, session *sessionEvents, event Event, similarity float64) float64 {
+	if r == nil {
+		return similarity
+	}
+	return (1-r.Weight)*similarity + r.Weight*r.decay(now, session, event)
+}
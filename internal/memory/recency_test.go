@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetrieveBySimilarityWeighsRecency(t *testing.T) {
+	head := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// evt_0 and evt_1 match the query equally and evt_2, the session head,
+	// less well; evt_0 is the oldest.
+	events := []Event{
+		mustEvent(t, "evt_0", "tenant_1", "session_1", 0, 10, head.Add(-48*time.Hour)),
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 20, head.Add(-24*time.Hour)),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 20, 30, head),
+	}
+	events[0].Embeddings = [][]float32{{1, 0}}
+	events[1].Embeddings = [][]float32{{1, 0}}
+	events[2].Embeddings = [][]float32{{0.8, 0.6}}
+
+	cases := []struct {
+		name    string
+		recency *Recency
+		anchor  string
+		score   float64
+	}{
+		{name: "no recency", anchor: "evt_0", score: 1},
+		{name: "created_at breaks the tie", recency: &Recency{Basis: RecencyCreatedAt, HalfLife: 24 * time.Hour, Weight: 0.1}, anchor: "evt_1", score: 0.9 + 0.1*0.5},
+		{name: "created_at outweighs similarity", recency: &Recency{Basis: RecencyCreatedAt, HalfLife: 24 * time.Hour, Weight: 0.5}, anchor: "evt_2", score: 0.5*0.8 + 0.5},
+		{name: "tokens breaks the tie", recency: &Recency{Basis: RecencyTokens, HalfLifeTokens: 10, Weight: 0.1}, anchor: "evt_1", score: 0.9 + 0.1*0.5},
+		{name: "tokens outweighs similarity", recency: &Recency{Basis: RecencyTokens, HalfLifeTokens: 10, Weight: 0.5}, anchor: "evt_2", score: 0.5*0.8 + 0.5},
+	}
+
+	for _, minVectors := range []int{1 << 20, 1} {
+		store := NewStoreWithOptions(StoreOptions{Index: IndexOptions{MinVectors: minVectors}})
+		store.now = func() time.Time { return head }
+		if err := store.AppendMany(context.Background(), events); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+
+		for _, tc := range cases {
+			got, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{
+				TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0},
+				Metric: SimilarityCosine, TopK: 1, Recency: tc.recency,
+			})
+			if err != nil {
+				t.Fatalf("%s: retrieve: %v", tc.name, err)
+			}
+			if len(got) != 1 || got[0].EventID != tc.anchor {
+				t.Fatalf("%s (min vectors %d): expected %s, got %v", tc.name, minVectors, tc.anchor, retrievedIDs(got))
+			}
+			if score := got[0].Retrieval.Score; score == nil || math.Abs(*score-tc.score) > 1e-6 {
+				t.Fatalf("%s: expected score %v, got %v", tc.name, tc.score, score)
+			}
+		}
+	}
+}
+
+func TestRecencyValidation(t *testing.T) {
+	cases := []struct {
+		name    string
+		recency Recency
+		err     error
+	}{
+		{name: "unknown basis", recency: Recency{Basis: "wall_clock", HalfLife: time.Hour, Weight: 0.5}, err: errUnknownRecencyBasis},
+		{name: "no half-life", recency: Recency{Basis: RecencyCreatedAt, HalfLifeTokens: 10, Weight: 0.5}, err: errRecencyHalfLife},
+		{name: "no token half-life", recency: Recency{Basis: RecencyTokens, HalfLife: time.Hour, Weight: 0.5}, err: errRecencyHalfLifeToken},
+		{name: "zero weight", recency: Recency{Basis: RecencyCreatedAt, HalfLife: time.Hour}, err: errRecencyWeight},
+		{name: "weight above one", recency: Recency{Basis: RecencyCreatedAt, HalfLife: time.Hour, Weight: 1.5}, err: errRecencyWeight},
+		{name: "NaN weight", recency: Recency{Basis: RecencyCreatedAt, HalfLife: time.Hour, Weight: math.NaN()}, err: errRecencyWeight},
+		{name: "valid", recency: Recency{Basis: RecencyTokens, HalfLifeTokens: 1, Weight: 1}},
+	}
+
+	for _, tc := range cases {
+		if err := tc.recency.validate(SimilarityCosine); !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.err, err)
+		}
+	}
+
+	recency := &Recency{Basis: RecencyCreatedAt, HalfLife: time.Hour, Weight: 0.5}
+	if err := recency.validate(SimilarityDot); !errors.Is(err, errRecencyNeedsCosine) {
+		t.Fatalf("expected error %v, got %v", errRecencyNeedsCosine, err)
+	}
+
+	store := NewStore()
+	if _, err := store.RetrieveByText(context.Background(), TextQuery{TenantID: "tenant_1", SessionID: "session_1", Text: "alpha", TopK: 1, Recency: recency}); !errors.Is(err, errRecencyNeedsVector) {
+		t.Fatalf("expected error %v, got %v", errRecencyNeedsVector, err)
+	}
+	if _, err := store.AssembleContext(context.Background(), ContextRequest{TenantID: "tenant_1", SessionID: "session_1", AnchorEventIDs: []string{"evt_1"}, TopK: 1, TokenBudget: 10, Recency: recency}); !errors.Is(err, errRecencyNeedsVector) {
+		t.Fatalf("expected error %v, got %v", errRecencyNeedsVector, err)
+	}
+}
+
+func TestRetrieveAcrossSessionsWeighsRecencyByClock(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	// Both sessions match equally; session_old went quiet months ago.
+	old := mustEvent(t, "evt_old", "tenant_1", "session_old", 0, 10, now.Add(-90*24*time.Hour))
+	old.Embeddings = [][]float32{{1, 0}}
+	recent := mustEvent(t, "evt_recent", "tenant_1", "session_recent", 0, 10, now.Add(-time.Hour))
+	recent.Embeddings = [][]float32{{1, 0}}
+	if err := store.AppendMany(context.Background(), []Event{old, recent}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	results, err := store.RetrieveAcrossSessions(context.Background(), CrossSessionQuery{
+		TenantID: "tenant_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 2,
+		Recency: &Recency{Basis: RecencyCreatedAt, HalfLife: 7 * 24 * time.Hour, Weight: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(results) != 2 || results[0].SessionID != "session_recent" || results[1].SessionID != "session_old" {
+		t.Fatalf("expected session_recent ahead of session_old, got %+v", results)
+	}
+	if got := *results[1].Events[0].Retrieval.Score; got >= 0.81 {
+		t.Fatalf("expected the old session to have decayed, got score %v", got)
+	}
+}
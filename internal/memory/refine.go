@@ -0,0 +1,215 @@
+package memory
+
+import "math"
+
+// RefinementObjective selects the graph metric optimized when moving boundaries.
+type RefinementObjective string
+
+const (
+	// RefinementModularity maximizes modularity of the two events adjacent to a boundary.
+	RefinementModularity RefinementObjective = "modularity"
+	// RefinementConductance minimizes conductance of the cut between them.
+	RefinementConductance RefinementObjective = "conductance"
+)
+
+var (
+	errUnknownRefinementObjective = invalidInput("refinement objective must be one of: modularity, conductance")
+	errRefinementRadiusNegative   = invalidInput("refinement radius must be non-negative")
+	errSimilarityNotSquare        = invalidInput("similarity must be a square matrix")
+	errSimilaritySizeMismatch     = invalidInput("similarity must have one row per surprise value")
+	errSimilarityNotFinite        = invalidInput("similarity values must be finite")
+	errKeysRequired               = invalidInput("keys must contain at least one vector")
+	errKeysDimsDiffer             = invalidInput("key vectors must all have the same non-zero dimension")
+	errKeysNotFinite              = invalidInput("key values must be finite")
+)
+
+// TokenSimilarity is a symmetric token-by-token adjacency matrix for one segment.
+type TokenSimilarity interface {
+	Len() int
+	At(i, j int) float64
+}
+
+// Refinement configures boundary refinement.
+type Refinement struct {
+	Objective RefinementObjective
+	// Radius bounds how far a boundary may move; 0 allows any position between
+	// its neighbouring boundaries.
+	Radius     int
+	Similarity TokenSimilarity
+}
+
+type matrixSimilarity [][]float64
+
+// NewSimilarityMatrix wraps an explicit n×n similarity matrix.
+func NewSimilarityMatrix(matrix [][]float64) (TokenSimilarity, error) {
+	for _, row := range matrix {
+		if len(row) != len(matrix) {
+			return nil, errSimilarityNotSquare
+		}
+		for _, value := range row {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, errSimilarityNotFinite
+			}
+		}
+	}
+	return matrixSimilarity(matrix), nil
+}
+
+func (m matrixSimilarity) Len() int { return len(m) }
+
+// At symmetrizes the matrix so callers need not supply an exactly symmetric one.
+func (m matrixSimilarity) At(i, j int) float64 { return (m[i][j] + m[j][i]) / 2 }
+
+type keySimilarity [][]float64
+
+// NewKeySimilarity derives token similarity from per-token attention key
+// vectors as their dot product.
+func NewKeySimilarity(keys [][]float64) (TokenSimilarity, error) {
+	if len(keys) == 0 {
+		return nil, errKeysRequired
+	}
+	for _, key := range keys {
+		if len(key) == 0 || len(key) != len(keys[0]) {
+			return nil, errKeysDimsDiffer
+		}
+		for _, value := range key {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, errKeysNotFinite
+			}
+		}
+	}
+	return keySimilarity(keys), nil
+}
+
+func (k keySimilarity) Len() int { return len(k) }
+
+func (k keySimilarity) At(i, j int) float64 {
+	var dot float64
+	for d, value := range k[i] {
+		dot += value * k[j][d]
+	}
+	return dot
+}
+
+func (r Refinement) validate(tokens int) error {
+	switch r.Objective {
+	case RefinementModularity, RefinementConductance:
+	default:
+		return errUnknownRefinementObjective
+	}
+	if r.Radius < 0 {
+		return errRefinementRadiusNegative
+	}
+	if r.Similarity == nil || r.Similarity.Len() != tokens {
+		return errSimilaritySizeMismatch
+	}
+	return nil
+}
+
+// RefineBoundaries moves each boundary, left to right, to the position within
+// its neighbourhood that optimizes the objective on the graph formed by the
+// two events it separates. Boundaries are relative token offsets in (0, n)
+// where n is the similarity size. Negative similarities are treated as zero
+// so the graph has non-negative edge weights.
+func RefineBoundaries(boundaries []int, refinement Refinement) ([]int, error) {
+	tokens := 0
+	if refinement.Similarity != nil {
+		tokens = refinement.Similarity.Len()
+	}
+	if err := refinement.validate(tokens); err != nil {
+		return nil, err
+	}
+
+	refined := make([]int, len(boundaries))
+	copy(refined, boundaries)
+	for i, boundary := range refined {
+		if boundary <= 0 || boundary >= tokens {
+			return nil, errInvalidBoundary
+		}
+
+		prev := 0
+		if i > 0 {
+			prev = refined[i-1]
+		}
+		next := tokens
+		if i+1 < len(refined) {
+			next = refined[i+1]
+		}
+
+		lo, hi := prev+1, next-1
+		if refinement.Radius > 0 {
+			lo = max(lo, boundary-refinement.Radius)
+			hi = min(hi, boundary+refinement.Radius)
+		}
+		if lo >= hi {
+			continue
+		}
+
+		refined[i] = bestSplit(refinement.Similarity, refinement.Objective, prev, next, boundary, lo, hi)
+	}
+
+	return refined, nil
+}
+
+// bestSplit scores every split p in [lo, hi] of the token range [start, end)
+// into [start, p) and [p, end). Block sums come from a 2D prefix sum over the
+// local adjacency, so each candidate costs O(1).
+func bestSplit(sim TokenSimilarity, objective RefinementObjective, start, end, current, lo, hi int) int {
+	n := end - start
+	// prefix[i][j] = sum of A over local rows [0, i) and columns [0, j).
+	prefix := make([][]float64, n+1)
+	for i := range prefix {
+		prefix[i] = make([]float64, n+1)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			weight := max(0, sim.At(start+i, start+j))
+			prefix[i+1][j+1] = weight + prefix[i][j+1] + prefix[i+1][j] - prefix[i][j]
+		}
+	}
+	block := func(r0, r1, c0, c1 int) float64 {
+		return prefix[r1][c1] - prefix[r0][c1] - prefix[r1][c0] + prefix[r0][c0]
+	}
+
+	total := block(0, n, 0, n)
+	if total == 0 {
+		return current
+	}
+
+	best := current
+	bestScore := math.Inf(-1)
+	for p := lo; p <= hi; p++ {
+		split := p - start
+		inLeft := block(0, split, 0, split)
+		inRight := block(split, n, split, n)
+		volLeft := block(0, split, 0, n)
+		volRight := block(split, n, 0, n)
+
+		var score float64
+		switch objective {
+		case RefinementModularity:
+			score = (inLeft + inRight - (volLeft*volLeft+volRight*volRight)/total) / total
+		case RefinementConductance:
+			smaller := min(volLeft, volRight)
+			if smaller == 0 {
+				continue
+			}
+			// Negated so that higher is better for both objectives.
+			score = -(volLeft - inLeft) / smaller
+		}
+
+		if score > bestScore || (score == bestScore && abs(p-current) < abs(best-current)) {
+			best = p
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
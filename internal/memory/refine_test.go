@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// blockSimilarity builds a similarity matrix whose tokens form contiguous
+// clusters starting at the given offsets.
+func blockSimilarity(t *testing.T, tokens int, clusterStarts ...int) TokenSimilarity {
+	t.Helper()
+
+	cluster := make([]int, tokens)
+	for i := range cluster {
+		for c, start := range clusterStarts {
+			if i >= start {
+				cluster[i] = c
+			}
+		}
+	}
+
+	matrix := make([][]float64, tokens)
+	for i := range matrix {
+		matrix[i] = make([]float64, tokens)
+		for j := range matrix[i] {
+			matrix[i][j] = 0.05
+			if cluster[i] == cluster[j] {
+				matrix[i][j] = 1
+			}
+		}
+	}
+
+	sim, err := NewSimilarityMatrix(matrix)
+	if err != nil {
+		t.Fatalf("new similarity matrix: %v", err)
+	}
+	return sim
+}
+
+func TestRefineBoundariesMovesToClusterEdges(t *testing.T) {
+	t.Parallel()
+
+	sim := blockSimilarity(t, 16, 0, 6, 11)
+	for _, objective := range []RefinementObjective{RefinementModularity, RefinementConductance} {
+		got, err := RefineBoundaries([]int{4, 12}, Refinement{Objective: objective, Similarity: sim})
+		if err != nil {
+			t.Fatalf("refine with %s: %v", objective, err)
+		}
+
+		want := []int{6, 11}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %s refinement %v, got %v", objective, want, got)
+		}
+	}
+}
+
+func TestRefineBoundariesHonorsRadius(t *testing.T) {
+	t.Parallel()
+
+	sim := blockSimilarity(t, 16, 0, 8)
+	got, err := RefineBoundaries([]int{3}, Refinement{Objective: RefinementModularity, Radius: 2, Similarity: sim})
+	if err != nil {
+		t.Fatalf("refine: %v", err)
+	}
+
+	want := []int{5}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected boundaries %v, got %v", want, got)
+	}
+}
+
+func TestRefineBoundariesFromKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := [][]float64{{1, 0}, {1, 0.1}, {0.9, 0}, {1, 0}, {0, 1}, {0.1, 1}, {0, 0.9}}
+	sim, err := NewKeySimilarity(keys)
+	if err != nil {
+		t.Fatalf("new key similarity: %v", err)
+	}
+
+	got, err := RefineBoundaries([]int{2}, Refinement{Objective: RefinementModularity, Similarity: sim})
+	if err != nil {
+		t.Fatalf("refine: %v", err)
+	}
+
+	want := []int{4}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected boundaries %v, got %v", want, got)
+	}
+}
+
+func TestRefineBoundariesValidation(t *testing.T) {
+	t.Parallel()
+
+	sim := blockSimilarity(t, 4, 0)
+	cases := []struct {
+		name       string
+		boundaries []int
+		refinement Refinement
+		err        error
+	}{
+		{
+			name:       "unknown objective",
+			refinement: Refinement{Objective: "entropy", Similarity: sim},
+			err:        errUnknownRefinementObjective,
+		},
+		{
+			name:       "negative radius",
+			refinement: Refinement{Objective: RefinementModularity, Radius: -1, Similarity: sim},
+			err:        errRefinementRadiusNegative,
+		},
+		{
+			name:       "missing similarity",
+			refinement: Refinement{Objective: RefinementModularity},
+			err:        errSimilaritySizeMismatch,
+		},
+		{
+			name:       "boundary out of range",
+			boundaries: []int{4},
+			refinement: Refinement{Objective: RefinementModularity, Similarity: sim},
+			err:        errInvalidBoundary,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := RefineBoundaries(tc.boundaries, tc.refinement)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestNewSimilarityInputsRejectInvalidShapes(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewSimilarityMatrix([][]float64{{1, 0}, {0}}); !errors.Is(err, errSimilarityNotSquare) {
+		t.Fatalf("expected error %v, got %v", errSimilarityNotSquare, err)
+	}
+	if _, err := NewKeySimilarity(nil); !errors.Is(err, errKeysRequired) {
+		t.Fatalf("expected error %v, got %v", errKeysRequired, err)
+	}
+	if _, err := NewKeySimilarity([][]float64{{1, 0}, {1}}); !errors.Is(err, errKeysDimsDiffer) {
+		t.Fatalf("expected error %v, got %v", errKeysDimsDiffer, err)
+	}
+}
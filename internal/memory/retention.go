@@ -0,0 +1,304 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrSessionNotFound reports an operation on a session that does not exist.
+var ErrSessionNotFound = errors.New("session not found")
+
+var (
+	errRetentionNegative = invalidInput("retention values must be non-negative")
+	errRetentionDisabled = invalidInput("retention is disabled; configure a retention policy before overriding it per session")
+)
+
+// Retention bounds how long stored data is kept. Zero fields keep data forever.
+type Retention struct {
+	// EventTTL expires events whose CreatedAt is older than this.
+	EventTTL time.Duration `json:"event_ttl,omitempty"`
+	// SessionIdleTTL deletes whole sessions that were neither read nor
+	// written for this long. Last access is kept in snapshots but reads are
+	// not logged, so after a restart the idle clock of a session restarts
+	// from the last snapshot, or from the restart if it was written since.
+	SessionIdleTTL time.Duration `json:"session_idle_ttl,omitempty"`
+	// RetainEvents keeps only the newest events of a session in session
+	// order, expiring the oldest beyond it. Unlike Quota.MaxEventsPerSession
+	// it never rejects a write.
+	RetainEvents int `json:"retain_events,omitempty"`
+}
+
+func (r Retention) validate() error {
+	if r.EventTTL < 0 || r.SessionIdleTTL < 0 || r.RetainEvents < 0 {
+		return errRetentionNegative
+	}
+	return nil
+}
+
+// withOverride returns r with the non-zero fields of override applied.
+func (r Retention) withOverride(override Retention) Retention {
+	if override.EventTTL != 0 {
+		r.EventTTL = override.EventTTL
+	}
+	if override.SessionIdleTTL != 0 {
+		r.SessionIdleTTL = override.SessionIdleTTL
+	}
+	if override.RetainEvents != 0 {
+		r.RetainEvents = override.RetainEvents
+	}
+	return r
+}
+
+// RetentionPolicy holds the default retention and per-tenant overrides.
+// Sessions can override further with SetSessionRetention. A zero field in an
+// override inherits the level above.
+type RetentionPolicy struct {
+	Default Retention
+	Tenants map[string]Retention
+}
+
+// Enabled reports whether the policy expires anything. Without a policy,
+// sessions cannot override retention and sweeps remove nothing.
+func (p RetentionPolicy) Enabled() bool {
+	if p.Default != (Retention{}) {
+		return true
+	}
+	for _, retention := range p.Tenants {
+		if retention != (Retention{}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetentionPolicy) forSession(tenantID string, session *Retention) Retention {
+	retention := p.Default.withOverride(p.Tenants[tenantID])
+	if session != nil {
+		retention = retention.withOverride(*session)
+	}
+	return retention
+}
+
+// ExpiryReason says which retention rule removed data.
+type ExpiryReason string
+
+const (
+	ExpiryEventTTL     ExpiryReason = "event_ttl"
+	ExpirySessionIdle  ExpiryReason = "session_idle"
+	ExpiryRetainEvents ExpiryReason = "retain_events"
+)
+
+// expiredSession is what one sweep removes from one session: the whole
+// session, or the listed events.
+type expiredSession struct {
+	TenantID  string   `json:"tenant_id"`
+	SessionID string   `json:"session_id"`
+	Whole     bool     `json:"whole,omitempty"`
+	EventIDs  []string `json:"event_ids,omitempty"`
+}
+
+func (s *InMemoryStore) SetSessionRetention(ctx context.Context, tenantID, sessionID string, retention Retention) error {
+	return s.setSessionRetention(ctx, tenantID, sessionID, retention, true, nil)
+}
+
+// setSessionRetention replaces a session's retention override; a zero
+// Retention removes it. requireEnabled rejects an override while the store
+// has no retention policy; replay skips it so logged overrides survive a
+// restart without one.
+func (s *InMemoryStore) setSessionRetention(
+	ctx context.Context,
+	tenantID, sessionID string,
+	retention Retention,
+	requireEnabled bool,
+	commit func() error,
+) (err error) {
+	_, span := tracer.Start(ctx, "memory.SetSessionRetention")
+	defer func() { endSpan(span, err) }()
+
+	if err := retention.validate(); err != nil {
+		return err
+	}
+	if requireEnabled && retention != (Retention{}) && !s.opts.Retention.Enabled() {
+		return errRetentionDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	session, ok := s.sessions[sessionKey{tenantID: tenantID, sessionID: sessionID}]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	session.retention = nil
+	if retention != (Retention{}) {
+		session.retention = &retention
+	}
+	session.touch(s.now())
+	return nil
+}
+
+func (s *InMemoryStore) Sweep(ctx context.Context) (Deletion, error) {
+	return s.sweep(ctx, nil)
+}
+
+// sweep expires whatever the retention policy no longer allows. Like
+// appendMany, commit runs once the expired set is known and before any state
+// changes; it is skipped when nothing expired.
+func (s *InMemoryStore) sweep(ctx context.Context, commit func([]expiredSession) error) (_ Deletion, err error) {
+	_, span := tracer.Start(ctx, "memory.Sweep")
+	defer func() { endSpan(span, err) }()
+
+	if !s.opts.Retention.Enabled() {
+		return Deletion{}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	type tally struct {
+		tenantID string
+		reason   ExpiryReason
+	}
+	counts := make(map[tally]*Deletion)
+	count := func(tenantID string, reason ExpiryReason, sessions, events int) {
+		key := tally{tenantID: tenantID, reason: reason}
+		if counts[key] == nil {
+			counts[key] = &Deletion{}
+		}
+		counts[key].Sessions += sessions
+		counts[key].Events += events
+	}
+
+	now := s.now()
+	var expired []expiredSession
+	for key, session := range s.sessions {
+		retention := s.opts.Retention.forSession(key.tenantID, session.retention)
+		if retention.SessionIdleTTL > 0 && now.Sub(session.lastAccessTime()) > retention.SessionIdleTTL {
+			expired = append(expired, expiredSession{TenantID: key.tenantID, SessionID: key.sessionID, Whole: true})
+			count(key.tenantID, ExpirySessionIdle, 1, len(session.ordered))
+			continue
+		}
+		if retention.EventTTL == 0 && retention.RetainEvents == 0 {
+			continue
+		}
+
+		cutoff := now.Add(-retention.EventTTL)
+		tooOld := func(event Event) bool {
+			return retention.EventTTL > 0 && event.CreatedAt.Before(cutoff)
+		}
+		live := 0
+		for _, event := range session.ordered {
+			if !tooOld(event) {
+				live++
+			}
+		}
+		excess := 0
+		if retention.RetainEvents > 0 {
+			excess = max(0, live-retention.RetainEvents)
+		}
+
+		var eventIDs []string
+		for _, event := range session.ordered {
+			switch {
+			case tooOld(event):
+				count(key.tenantID, ExpiryEventTTL, 0, 1)
+			case excess > 0:
+				excess--
+				count(key.tenantID, ExpiryRetainEvents, 0, 1)
+			default:
+				continue
+			}
+			eventIDs = append(eventIDs, event.EventID)
+		}
+		if len(eventIDs) > 0 {
+			expired = append(expired, expiredSession{TenantID: key.tenantID, SessionID: key.sessionID, EventIDs: eventIDs})
+		}
+	}
+	if len(expired) == 0 {
+		return Deletion{}, nil
+	}
+
+	// Sort so the logged record does not depend on map iteration order.
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].TenantID != expired[j].TenantID {
+			return expired[i].TenantID < expired[j].TenantID
+		}
+		return expired[i].SessionID < expired[j].SessionID
+	})
+
+	if commit != nil {
+		if err := commit(expired); err != nil {
+			return Deletion{}, err
+		}
+	}
+
+	deletion := s.applyExpiredLocked(expired)
+	span.SetAttributes(attribute.Int("sessions", deletion.Sessions), attribute.Int("events", deletion.Events))
+	if s.opts.Observer != nil {
+		for key, counted := range counts {
+			s.opts.Observer.ObserveExpiry(key.tenantID, key.reason, counted.Sessions, counted.Events)
+		}
+	}
+	return deletion, nil
+}
+
+// replayExpired re-applies a logged sweep without re-evaluating the policy.
+func (s *InMemoryStore) replayExpired(expired []expiredSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.applyExpiredLocked(expired)
+}
+
+// applyExpiredLocked removes what a sweep selected. Sessions or events that
+// no longer exist are skipped. The caller must hold s.mu.
+func (s *InMemoryStore) applyExpiredLocked(expired []expiredSession) Deletion {
+	var deletion Deletion
+	for _, entry := range expired {
+		key := sessionKey{tenantID: entry.TenantID, sessionID: entry.SessionID}
+		session, ok := s.sessions[key]
+		if !ok {
+			continue
+		}
+		if entry.Whole {
+			removed := s.removeSessionsLocked([]sessionKey{key})
+			deletion.Sessions += removed.Sessions
+			deletion.Events += removed.Events
+			continue
+		}
+		deletion.Events += s.removeEventsLocked(key, session, entry.EventIDs)
+	}
+	return deletion
+}
+
+// RunSweeper calls store.Sweep every interval until ctx is done. It blocks,
+// so callers run it in its own goroutine and wait for it to return before
+// closing the store.
+func RunSweeper(ctx context.Context, store Store, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.Sweep(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
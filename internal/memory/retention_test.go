@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSweepExpiresByEventTTLAndRetainEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	observer := &recordingObserver{}
+	store := NewStoreWithOptions(StoreOptions{
+		Retention: RetentionPolicy{
+			Default: Retention{EventTTL: 24 * time.Hour},
+			Tenants: map[string]Retention{"tenant_2": {RetainEvents: 2}},
+		},
+		Observer: observer,
+	})
+	store.now = func() time.Time { return now }
+
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_old", "tenant_1", "session_1", 0, 1, now.Add(-48*time.Hour)),
+		mustEvent(t, "evt_new", "tenant_1", "session_1", 1, 2, now.Add(-time.Hour)),
+		mustEvent(t, "evt_1", "tenant_2", "session_1", 0, 1, now),
+		mustEvent(t, "evt_2", "tenant_2", "session_1", 1, 2, now),
+		mustEvent(t, "evt_3", "tenant_2", "session_1", 2, 3, now),
+		mustEvent(t, "evt_ancient", "tenant_2", "session_1", 3, 4, now.Add(-72*time.Hour)),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	deletion, err := store.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if deletion != (Deletion{Events: 3}) {
+		t.Fatalf("expected 3 expired events, got %+v", deletion)
+	}
+	if got := store.ListBySession("tenant_1", "session_1"); len(got) != 1 || got[0].EventID != "evt_new" {
+		t.Fatalf("expected only evt_new to survive the ttl, got %#v", got)
+	}
+	// The tenant override inherits the default ttl and keeps the newest two
+	// of the remaining events in session order.
+	if got := store.ListBySession("tenant_2", "session_1"); len(got) != 2 || got[0].EventID != "evt_2" || got[1].EventID != "evt_3" {
+		t.Fatalf("expected [evt_2 evt_3], got %#v", got)
+	}
+	if observer.expired[ExpiryEventTTL] != (Deletion{Events: 2}) || observer.expired[ExpiryRetainEvents] != (Deletion{Events: 1}) {
+		t.Fatalf("expected 2 ttl and 1 retain expiries, got %+v", observer.expired)
+	}
+	if got := store.Stats().Tenants["tenant_2"]; got.Events != 2 {
+		t.Fatalf("expected usage to follow expiry, got %+v", got)
+	}
+
+	if deletion, err := store.Sweep(context.Background()); err != nil || deletion != (Deletion{}) {
+		t.Fatalf("expected a second sweep to find nothing, got %+v, %v", deletion, err)
+	}
+}
+
+func TestSweepExpiresIdleSessions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Retention: RetentionPolicy{Default: Retention{SessionIdleTTL: time.Hour}}})
+	store.now = func() time.Time { return now }
+
+	for _, sessionID := range []string{"session_read", "session_idle"} {
+		if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", sessionID, 0, 1, now)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	now = now.Add(50 * time.Minute)
+	store.ListBySession("tenant_1", "session_read")
+	now = now.Add(20 * time.Minute)
+
+	deletion, err := store.Sweep(context.Background())
+	if err != nil || deletion != (Deletion{Sessions: 1, Events: 1}) {
+		t.Fatalf("expected the idle session to expire, got %+v, %v", deletion, err)
+	}
+	if got := store.ListBySession("tenant_1", "session_read"); len(got) != 1 {
+		t.Fatalf("expected a read to keep the session alive, got %#v", got)
+	}
+	if got := store.Stats().Tenants["tenant_1"]; got.Sessions != 1 {
+		t.Fatalf("expected one session left, got %+v", got)
+	}
+}
+
+func TestSessionRetentionOverride(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Retention: RetentionPolicy{Default: Retention{EventTTL: time.Hour}}})
+	store.now = func() time.Time { return now }
+
+	err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{RetainEvents: 1})
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrSessionNotFound, err)
+	}
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 1, 2, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{EventTTL: -time.Second}); err == nil {
+		t.Fatalf("expected negative retention to be rejected")
+	}
+	if err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{RetainEvents: 1}); err != nil {
+		t.Fatalf("set retention: %v", err)
+	}
+
+	if deletion, err := store.Sweep(context.Background()); err != nil || deletion.Events != 1 {
+		t.Fatalf("expected the session override to expire one event, got %+v, %v", deletion, err)
+	}
+	if got := store.ListBySession("tenant_1", "session_1"); len(got) != 1 || got[0].EventID != "evt_2" {
+		t.Fatalf("expected evt_2 to remain, got %#v", got)
+	}
+}
+
+func TestDurableStoreReplaysSweepsAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := OpenDurableStore(dir, DurableOptions{Store: StoreOptions{Retention: RetentionPolicy{
+		Default: Retention{EventTTL: time.Hour},
+	}}})
+	if err != nil {
+		t.Fatalf("open durable store: %v", err)
+	}
+	store.mem.now = func() time.Time { return now }
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_old", "tenant_1", "session_1", 0, 1, now.Add(-2*time.Hour)),
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 1, 2, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 2, 3, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{RetainEvents: 5}); err != nil {
+		t.Fatalf("set retention: %v", err)
+	}
+	if deletion, err := store.Sweep(context.Background()); err != nil || deletion.Events != 1 {
+		t.Fatalf("expected one expired event, got %+v, %v", deletion, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Reopening without a policy must not resurrect expired events, and the
+	// session override must survive both replay and a snapshot.
+	reopened := mustOpenDurableStore(t, dir)
+	if got := reopened.ListBySession("tenant_1", "session_1"); len(got) != 2 {
+		t.Fatalf("expected the sweep to be replayed, got %#v", got)
+	}
+	if err := reopened.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	final := mustOpenDurableStore(t, dir)
+	defer final.Close()
+	session := final.mem.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}]
+	if session.retention == nil || session.retention.RetainEvents != 5 {
+		t.Fatalf("expected the session override to be restored, got %+v", session.retention)
+	}
+}
+
+func TestRunSweeperStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunSweeper(ctx, NewStore(), time.Millisecond, nil)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the sweeper to stop after cancel")
+	}
+}
+
+func TestRetentionWithoutPolicyIsDisabled(t *testing.T) {
+	store := NewStore()
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{EventTTL: time.Hour})
+	if !errors.Is(err, errRetentionDisabled) || !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected error %v, got %v", errRetentionDisabled, err)
+	}
+	if err := store.SetSessionRetention(context.Background(), "tenant_1", "session_1", Retention{}); err != nil {
+		t.Fatalf("expected clearing an override to succeed, got %v", err)
+	}
+	if deletion, err := store.Sweep(context.Background()); err != nil || deletion != (Deletion{}) {
+		t.Fatalf("expected a sweep without a policy to remove nothing, got %+v, %v", deletion, err)
+	}
+}
@@ -1,18 +1,51 @@
 package memory
 
 import (
-	"errors"
 	"fmt"
 	"time"
 )
 
 var (
-	errSegmentStartTokenNegative   = errors.New("start_token must be non-negative")
-	errSegmentSurpriseRequired     = errors.New("surprise must contain at least one value")
-	errSegmentEventIDPrefixMissing = errors.New("event id prefix is required")
-	errInvalidBoundary             = errors.New("detected boundary is outside valid token range")
+	errSegmentStartTokenNegative   = invalidInput("start_token must be non-negative")
+	errSegmentSurpriseRequired     = invalidInput("surprise must contain at least one value")
+	errSegmentEventIDPrefixMissing = invalidInput("event id prefix is required")
+	errInvalidBoundary             = invalidInput("detected boundary is outside valid token range")
 )
 
+// SegmentRequest describes one slice of per-token surprise to cut into events.
+type SegmentRequest struct {
+	TenantID   string
+	SessionID  string
+	StartToken int
+	Surprise   []float64
+	// Threshold is the fixed surprise threshold; it is ignored when Adaptive is set.
+	Threshold float64
+	// Adaptive, if set, replaces Threshold with a rolling mean + gamma*std threshold.
+	Adaptive       *AdaptiveThreshold
+	MinBoundaryGap int
+	CreatedAt      time.Time
+	EventIDPrefix  string
+	// Refinement, if set, moves the surprise boundaries using token similarity.
+	Refinement *Refinement
+	// Stream continues the session's pending tail instead of segmenting the
+	// slice in isolation; see Store.Segment. Flush closes the tail.
+	Stream bool
+	Flush  bool
+}
+
+// Segmentation is the outcome of segmenting one surprise slice. Boundaries
+// are absolute token positions; RawBoundaries are the surprise peaks before
+// refinement and equal Boundaries when no refinement was requested.
+// Thresholds holds the threshold each raw boundary's peak exceeded.
+type Segmentation struct {
+	Events        []Event
+	RawBoundaries []int
+	Boundaries    []int
+	Thresholds    []float64
+	// Pending is the tail a streaming segmentation left open; nil otherwise.
+	Pending *PendingTail
+}
+
 func BuildEventsFromSurprise(
 	tenantID string,
 	sessionID string,
@@ -23,22 +56,105 @@ func BuildEventsFromSurprise(
 	createdAt time.Time,
 	eventIDPrefix string,
 ) ([]Event, []int, error) {
-	if startToken < 0 {
-		return nil, nil, errSegmentStartTokenNegative
+	segmentation, err := BuildSegmentation(SegmentRequest{
+		TenantID:       tenantID,
+		SessionID:      sessionID,
+		StartToken:     startToken,
+		Surprise:       surprise,
+		Threshold:      threshold,
+		MinBoundaryGap: minBoundaryGap,
+		CreatedAt:      createdAt,
+		EventIDPrefix:  eventIDPrefix,
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return segmentation.Events, segmentation.Boundaries, nil
+}
+
+// BuildSegmentation detects, optionally refines and cuts the boundaries of
+// req. history holds earlier surprise values of the session, oldest first,
+// and only affects adaptive thresholds.
+func BuildSegmentation(req SegmentRequest, history []float64) (Segmentation, error) {
+	if req.StartToken < 0 {
+		return Segmentation{}, errSegmentStartTokenNegative
 	}
-	if len(surprise) == 0 {
-		return nil, nil, errSegmentSurpriseRequired
+	if len(req.Surprise) == 0 {
+		return Segmentation{}, errSegmentSurpriseRequired
 	}
-	if eventIDPrefix == "" {
-		return nil, nil, errSegmentEventIDPrefixMissing
+	if req.EventIDPrefix == "" {
+		return Segmentation{}, errSegmentEventIDPrefixMissing
+	}
+	if req.Refinement != nil {
+		if err := req.Refinement.validate(len(req.Surprise)); err != nil {
+			return Segmentation{}, err
+		}
 	}
 
-	boundariesRelative, err := DetectBoundaries(surprise, threshold, minBoundaryGap)
+	var (
+		rawRelative []int
+		thresholds  []float64
+		err         error
+	)
+	if req.Adaptive != nil {
+		rawRelative, thresholds, err = DetectBoundariesAdaptive(req.Surprise, history, *req.Adaptive, req.MinBoundaryGap)
+	} else {
+		rawRelative, err = DetectBoundaries(req.Surprise, req.Threshold, req.MinBoundaryGap)
+		thresholds = make([]float64, len(rawRelative))
+		for i := range thresholds {
+			thresholds[i] = req.Threshold
+		}
+	}
 	if err != nil {
-		return nil, nil, err
+		return Segmentation{}, err
 	}
 
-	endToken := startToken + len(surprise)
+	refinedRelative := rawRelative
+	if req.Refinement != nil {
+		refinedRelative, err = RefineBoundaries(rawRelative, *req.Refinement)
+		if err != nil {
+			return Segmentation{}, err
+		}
+	}
+
+	events, boundaries, err := buildEventsFromBoundaries(
+		req.TenantID,
+		req.SessionID,
+		req.StartToken,
+		len(req.Surprise),
+		refinedRelative,
+		req.CreatedAt,
+		req.EventIDPrefix,
+	)
+	if err != nil {
+		return Segmentation{}, err
+	}
+
+	rawBoundaries := make([]int, len(rawRelative))
+	for i, boundary := range rawRelative {
+		rawBoundaries[i] = req.StartToken + boundary
+	}
+
+	return Segmentation{
+		Events:        events,
+		RawBoundaries: rawBoundaries,
+		Boundaries:    boundaries,
+		Thresholds:    thresholds,
+	}, nil
+}
+
+// buildEventsFromBoundaries cuts tokenCount tokens starting at startToken into
+// contiguous events at the given relative boundaries.
+func buildEventsFromBoundaries(
+	tenantID string,
+	sessionID string,
+	startToken int,
+	tokenCount int,
+	boundariesRelative []int,
+	createdAt time.Time,
+	eventIDPrefix string,
+) ([]Event, []int, error) {
+	endToken := startToken + tokenCount
 	boundaries := make([]int, len(boundariesRelative))
 	for i, boundary := range boundariesRelative {
 		absoluteBoundary := startToken + boundary
@@ -47,6 +47,58 @@ func TestBuildEventsFromSurpriseBuildsContiguousEvents(t *testing.T) {
 	}
 }
 
+func TestBuildSegmentationReportsRawAndRefined(t *testing.T) {
+	t.Parallel()
+
+	surprise := []float64{0.1, 0.2, 0.1, 1.4, 0.1, 0.2, 0.1, 0.1, 0.1, 0.1}
+	segmentation, err := BuildSegmentation(SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     100,
+		Surprise:       surprise,
+		Threshold:      0.8,
+		MinBoundaryGap: 1,
+		CreatedAt:      time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC),
+		EventIDPrefix:  "seg",
+		Refinement: &Refinement{
+			Objective:  RefinementModularity,
+			Similarity: blockSimilarity(t, len(surprise), 0, 6),
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("build segmentation: %v", err)
+	}
+
+	if !reflect.DeepEqual(segmentation.RawBoundaries, []int{104}) {
+		t.Fatalf("expected raw boundaries %v, got %v", []int{104}, segmentation.RawBoundaries)
+	}
+	if !reflect.DeepEqual(segmentation.Boundaries, []int{106}) {
+		t.Fatalf("expected refined boundaries %v, got %v", []int{106}, segmentation.Boundaries)
+	}
+	events := segmentation.Events
+	if len(events) != 2 || events[0].EndTokenExclusive != 106 || events[1].StartToken != 106 || events[1].EndTokenExclusive != 110 {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+}
+
+func TestBuildSegmentationRejectsRefinementSizeMismatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := BuildSegmentation(SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		Surprise:       []float64{0.1, 1, 0.1},
+		Threshold:      0.5,
+		MinBoundaryGap: 1,
+		CreatedAt:      time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC),
+		EventIDPrefix:  "seg",
+		Refinement:     &Refinement{Objective: RefinementModularity, Similarity: blockSimilarity(t, 2, 0)},
+	}, nil)
+	if !errors.Is(err, errSimilaritySizeMismatch) {
+		t.Fatalf("expected error %v, got %v", errSimilaritySizeMismatch, err)
+	}
+}
+
 func TestBuildEventsFromSurpriseRejectsInvalidInput(t *testing.T) {
 	t.Parallel()
 
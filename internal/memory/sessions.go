@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SessionSummary describes one session without its events.
+type SessionSummary struct {
+	TenantID  string `json:"tenant_id"`
+	SessionID string `json:"session_id"`
+	Events    int    `json:"events"`
+	// StartToken and EndTokenExclusive bound the token span of all events.
+	StartToken        int       `json:"start_token"`
+	EndTokenExclusive int       `json:"end_token_exclusive"`
+	FirstCreatedAt    time.Time `json:"first_created_at,omitzero"`
+	LastCreatedAt     time.Time `json:"last_created_at,omitzero"`
+	// LastAccessedAt is when the session was last read or written, which is
+	// what the session idle TTL counts from.
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	// Labels select the session for cross-session retrieval.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SessionQuery selects one page of a tenant's sessions, ordered by session id.
+type SessionQuery struct {
+	TenantID string
+	// Limit caps the page size; zero returns every session.
+	Limit int
+	// Cursor continues after the last session of a previous page.
+	Cursor string
+}
+
+// SessionPage is one page of ListSessions results.
+type SessionPage struct {
+	Sessions []SessionSummary
+	// NextCursor continues after Sessions; it is empty on the last page.
+	NextCursor string
+}
+
+// noteEvent folds a newly stored event into the session's running extents.
+func (session *sessionEvents) noteEvent(event Event) {
+	session.maxSpan = max(session.maxSpan, event.tokenCount())
+	session.endToken = max(session.endToken, event.EndTokenExclusive)
+	if session.firstCreatedAt.IsZero() || event.CreatedAt.Before(session.firstCreatedAt) {
+		session.firstCreatedAt = event.CreatedAt
+	}
+	if event.CreatedAt.After(session.lastCreatedAt) {
+		session.lastCreatedAt = event.CreatedAt
+	}
+}
+
+// recomputeExtents rebuilds the extents after events were removed. maxSpan
+// is left alone, since it only has to be an upper bound.
+func (session *sessionEvents) recomputeExtents() {
+	session.endToken = 0
+	session.firstCreatedAt = time.Time{}
+	session.lastCreatedAt = time.Time{}
+	for _, event := range session.ordered {
+		session.noteEvent(event)
+	}
+}
+
+func (session *sessionEvents) summary(key sessionKey) SessionSummary {
+	summary := SessionSummary{
+		TenantID:          key.tenantID,
+		SessionID:         key.sessionID,
+		Events:            len(session.ordered),
+		EndTokenExclusive: session.endToken,
+		FirstCreatedAt:    session.firstCreatedAt,
+		LastCreatedAt:     session.lastCreatedAt,
+		LastAccessedAt:    session.lastAccessTime(),
+		Labels:            maps.Clone(session.labels),
+	}
+	if len(session.ordered) > 0 {
+		summary.StartToken = session.ordered[0].StartToken
+	}
+	return summary
+}
+
+// GetSession summarizes one session. Unlike reading its events, it does not
+// count as an access for the session idle TTL.
+func (s *InMemoryStore) GetSession(tenantID, sessionID string) (SessionSummary, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	session, ok := s.sessions[key]
+	if !ok {
+		return SessionSummary{}, false
+	}
+	return session.summary(key), true
+}
+
+// ListSessions pages through a tenant's sessions by session id. Like
+// GetSession it does not count as an access.
+func (s *InMemoryStore) ListSessions(ctx context.Context, query SessionQuery) (page SessionPage, err error) {
+	_, span := tracer.Start(ctx, "memory.ListSessions")
+	defer func() {
+		span.SetAttributes(attribute.Int("sessions", len(page.Sessions)))
+		endSpan(span, err)
+	}()
+
+	if query.Limit < 0 {
+		return SessionPage{}, errLimitNegative
+	}
+	var after string
+	if query.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil || len(data) == 0 {
+			return SessionPage{}, ErrInvalidCursor
+		}
+		after = string(data)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
+
+	sessionIDs := s.tenantSessions[query.TenantID]
+	start, found := slices.BinarySearch(sessionIDs, after)
+	if found {
+		start++
+	}
+	sessionIDs = sessionIDs[start:]
+
+	if query.Limit != 0 && len(sessionIDs) > query.Limit {
+		sessionIDs = sessionIDs[:query.Limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(sessionIDs[len(sessionIDs)-1]))
+	}
+	page.Sessions = make([]SessionSummary, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		key := sessionKey{tenantID: query.TenantID, sessionID: sessionID}
+		page.Sessions[i] = s.sessions[key].summary(key)
+	}
+	return page, nil
+}
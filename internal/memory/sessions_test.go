@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetSessionSummarizesEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	store.now = func() time.Time { return now }
+
+	if _, ok := store.GetSession("tenant_1", "session_1"); ok {
+		t.Fatalf("expected no summary for a missing session")
+	}
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 5, 50, now.Add(time.Hour)),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now.Add(-time.Hour)),
+		mustEvent(t, "evt_3", "tenant_1", "session_1", 30, 40, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	want := SessionSummary{
+		TenantID:          "tenant_1",
+		SessionID:         "session_1",
+		Events:            3,
+		StartToken:        5,
+		EndTokenExclusive: 50,
+		FirstCreatedAt:    now.Add(-time.Hour),
+		LastCreatedAt:     now.Add(time.Hour),
+		LastAccessedAt:    now,
+	}
+	if got, ok := store.GetSession("tenant_1", "session_1"); !ok || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	// Summaries follow deletions.
+	if _, err := store.DeleteEvent(context.Background(), "tenant_1", "session_1", "evt_1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	want.Events, want.StartToken, want.EndTokenExclusive, want.LastCreatedAt = 2, 10, 40, now
+	if got, _ := store.GetSession("tenant_1", "session_1"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v after delete, got %+v", want, got)
+	}
+}
+
+func TestListSessionsPagesByID(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	for _, key := range []sessionKey{
+		{"tenant_1", "session_c"},
+		{"tenant_1", "session_a"},
+		{"tenant_2", "session_b"},
+		{"tenant_1", "session_b"},
+	} {
+		if err := store.Append(context.Background(), mustEvent(t, "evt_1", key.tenantID, key.sessionID, 0, 1, now)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	query := SessionQuery{TenantID: "tenant_1", Limit: 2}
+	var got []string
+	for {
+		page, err := store.ListSessions(context.Background(), query)
+		if err != nil {
+			t.Fatalf("list sessions: %v", err)
+		}
+		for _, summary := range page.Sessions {
+			got = append(got, summary.SessionID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if want := []string{"session_a", "session_b", "session_c"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if _, err := store.ListSessions(context.Background(), SessionQuery{TenantID: "tenant_1", Cursor: "!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected error %v, got %v", ErrInvalidCursor, err)
+	}
+	page, err := store.ListSessions(context.Background(), SessionQuery{TenantID: "tenant_3"})
+	if err != nil || len(page.Sessions) != 0 || page.Sessions == nil {
+		t.Fatalf("expected an empty page, got %+v, %v", page, err)
+	}
+}
+
+func TestListSessionsFollowsDeletionAndRestore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := mustStore(t,
+		mustEvent(t, "evt_1", "tenant_1", "session_b", 0, 1, now),
+		mustEvent(t, "evt_1", "tenant_1", "session_a", 0, 1, now),
+		mustEvent(t, "evt_1", "tenant_1", "session_c", 0, 1, now),
+		mustEvent(t, "evt_1", "tenant_2", "session_a", 0, 1, now),
+	)
+	sessionIDs := func() []string {
+		t.Helper()
+		page, err := store.ListSessions(context.Background(), SessionQuery{TenantID: "tenant_1"})
+		if err != nil {
+			t.Fatalf("list sessions: %v", err)
+		}
+		var ids []string
+		for _, summary := range page.Sessions {
+			ids = append(ids, summary.SessionID)
+		}
+		return ids
+	}
+
+	if _, err := store.DeleteSession(context.Background(), "tenant_1", "session_b"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if got, want := sessionIDs(), []string{"session_a", "session_c"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v after delete, got %v", want, got)
+	}
+
+	store.restore(store.snapshotSessionsLocked())
+	if got, want := sessionIDs(), []string{"session_a", "session_c"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v after restore, got %v", want, got)
+	}
+
+	if _, err := store.PurgeTenant(context.Background(), "tenant_1"); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if got := sessionIDs(); len(got) != 0 {
+		t.Fatalf("expected no sessions after purge, got %v", got)
+	}
+	if _, ok := store.tenantSessions["tenant_1"]; ok {
+		t.Fatalf("expected the purged tenant to leave the session index")
+	}
+	if got := store.tenantSessions["tenant_2"]; !slices.Equal(got, []string{"session_a"}) {
+		t.Fatalf("expected the other tenant untouched, got %v", got)
+	}
+}
@@ -0,0 +1,45 @@
+package memory
+
+import "math"
+
+// SimilarityMetric selects how query and event embeddings are compared.
+type SimilarityMetric string
+
+const (
+	SimilarityCosine SimilarityMetric = "cosine"
+	SimilarityDot    SimilarityMetric = "dot"
+)
+
+var errUnknownSimilarityMetric = invalidInput("metric must be one of: cosine, dot")
+
+func (m SimilarityMetric) validate() error {
+	switch m {
+	case SimilarityCosine, SimilarityDot:
+		return nil
+	default:
+		return errUnknownSimilarityMetric
+	}
+}
+
+// similarity scores one vector against the query. queryNorm is only used for cosine.
+func similarity(metric SimilarityMetric, query []float32, queryNorm float64, vector []float32) float64 {
+	var dot, norm float64
+	for i, value := range vector {
+		v := float64(value)
+		dot += float64(query[i]) * v
+		norm += v * v
+	}
+
+	if metric == SimilarityDot {
+		return dot
+	}
+	return dot / (queryNorm * math.Sqrt(norm))
+}
+
+func vectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	return math.Sqrt(sum)
+}
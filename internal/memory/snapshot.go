@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidSnapshot = errors.New("snapshot is invalid")
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".snap"
+	snapshotTemp   = ".tmp"
+)
+
+// storeSnapshot is a point-in-time copy of every session covering all log
+// records up to and including Seq.
+type storeSnapshot struct {
+	Seq      uint64            `json:"seq"`
+	Sessions []sessionSnapshot `json:"sessions"`
+}
+
+// sessionSnapshot stores the ordered events of one session; byID is rebuilt on load.
+type sessionSnapshot struct {
+	TenantID        string            `json:"tenant_id"`
+	SessionID       string            `json:"session_id"`
+	Events          []Event           `json:"events"`
+	Index           *hnswSnapshot     `json:"index,omitempty"`
+	SurpriseHistory []float64         `json:"surprise_history,omitempty"`
+	Pending         *pendingSegment   `json:"pending,omitempty"`
+	Retention       *Retention        `json:"retention,omitempty"`
+	SpanPolicy      SpanPolicy        `json:"span_policy,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	LastAccess      time.Time         `json:"last_access,omitempty"`
+}
+
+type snapshotFile struct {
+	seq  uint64
+	path string
+}
+
+// writeSnapshot stores the snapshot with the same framing as log records and
+// atomically renames it into place.
+func writeSnapshot(dir string, snapshot storeSnapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, snapshotName(snapshot.Seq))
+	tempPath := path + snapshotTemp
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+
+	if _, err := file.Write(encodeFrame(payload)); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("install snapshot: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+func readSnapshot(path string) (storeSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return storeSnapshot{}, fmt.Errorf("read snapshot: %w", err)
+	}
+	if len(data) < walHeaderSize {
+		return storeSnapshot{}, errInvalidSnapshot
+	}
+
+	length := binary.LittleEndian.Uint32(data[0:4])
+	checksum := binary.LittleEndian.Uint32(data[4:8])
+	payload := data[walHeaderSize:]
+	if int64(length) != int64(len(payload)) || crc32.Checksum(payload, walChecksumTable) != checksum {
+		return storeSnapshot{}, errInvalidSnapshot
+	}
+
+	var snapshot storeSnapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return storeSnapshot{}, errInvalidSnapshot
+	}
+	return snapshot, nil
+}
+
+// loadLatestSnapshot returns the newest snapshot that passes its checksum.
+// Unreadable snapshots are skipped in favour of older ones.
+func loadLatestSnapshot(dir string) (storeSnapshot, bool, error) {
+	files, err := listSnapshots(dir)
+	if err != nil {
+		return storeSnapshot{}, false, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		snapshot, err := readSnapshot(files[i].path)
+		if err != nil {
+			continue
+		}
+		if snapshot.Seq != files[i].seq {
+			continue
+		}
+		return snapshot, true, nil
+	}
+
+	return storeSnapshot{}, false, nil
+}
+
+// pruneSnapshots keeps the newest retain snapshots and returns the sequence
+// number of the oldest one kept.
+func pruneSnapshots(dir string, retain int) (uint64, error) {
+	files, err := listSnapshots(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+
+	keepFrom := max(0, len(files)-retain)
+	for _, file := range files[:keepFrom] {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("remove snapshot: %w", err)
+		}
+	}
+
+	return files[keepFrom].seq, syncDir(dir)
+}
+
+func snapshotName(seq uint64) string {
+	return fmt.Sprintf("%s%020d%s", snapshotPrefix, seq, snapshotSuffix)
+}
+
+func listSnapshots(dir string) ([]snapshotFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	files := make([]snapshotFile, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, snapshotFile{seq: seq, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].seq < files[j].seq
+	})
+	return files, nil
+}
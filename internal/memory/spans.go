@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ErrSpanConflict reports an append whose token spans break the session's
+// span policy. Nothing from the rejected batch is stored.
+var ErrSpanConflict = errors.New("event span conflicts with session span policy")
+
+var errUnknownSpanPolicy = invalidInput(`span_policy must be "allow", "reject-overlap" or "require-contiguous"`)
+
+// maxSpanReportEntries bounds the gaps and overlaps one SpanReport lists.
+const maxSpanReportEntries = 1000
+
+// SpanPolicy says how the token spans of a session's events may relate.
+type SpanPolicy string
+
+const (
+	// SpanPolicyAllow accepts any spans. It is the default.
+	SpanPolicyAllow SpanPolicy = "allow"
+	// SpanPolicyRejectOverlap rejects events that overlap a stored event or
+	// another event in the same batch.
+	SpanPolicyRejectOverlap SpanPolicy = "reject-overlap"
+	// SpanPolicyRequireContiguous additionally requires every append to
+	// extend the session without a gap, either before its first token or
+	// after its last.
+	SpanPolicyRequireContiguous SpanPolicy = "require-contiguous"
+)
+
+// Validate reports whether p is a known policy. The empty policy inherits
+// the level above.
+func (p SpanPolicy) Validate() error {
+	switch p {
+	case "", SpanPolicyAllow, SpanPolicyRejectOverlap, SpanPolicyRequireContiguous:
+		return nil
+	default:
+		return errUnknownSpanPolicy
+	}
+}
+
+// SpanPolicies holds the default span policy and per-tenant overrides.
+// Sessions can override further with SetSessionSpanPolicy. An empty policy
+// inherits the level above.
+type SpanPolicies struct {
+	Default SpanPolicy
+	Tenants map[string]SpanPolicy
+}
+
+func (p SpanPolicies) forSession(tenantID string, session SpanPolicy) SpanPolicy {
+	for _, policy := range []SpanPolicy{session, p.Tenants[tenantID], p.Default} {
+		if policy != "" {
+			return policy
+		}
+	}
+	return SpanPolicyAllow
+}
+
+// TokenRange is a half-open token interval [StartToken, EndTokenExclusive).
+type TokenRange struct {
+	StartToken        int `json:"start_token"`
+	EndTokenExclusive int `json:"end_token_exclusive"`
+}
+
+// SpanOverlap is a token range covered by two events.
+type SpanOverlap struct {
+	TokenRange
+	EventIDs [2]string `json:"event_ids"`
+}
+
+// SpanReport lists where a session's events leave holes or cover the same
+// tokens twice. Each list holds at most maxSpanReportEntries entries;
+// Truncated reports that more were found.
+type SpanReport struct {
+	Policy    SpanPolicy    `json:"span_policy"`
+	Extent    TokenRange    `json:"extent"`
+	Gaps      []TokenRange  `json:"gaps"`
+	Overlaps  []SpanOverlap `json:"overlaps"`
+	Truncated bool          `json:"truncated"`
+}
+
+func (s *InMemoryStore) SetSessionSpanPolicy(ctx context.Context, tenantID, sessionID string, policy SpanPolicy) error {
+	return s.setSessionSpanPolicy(ctx, tenantID, sessionID, policy, true, nil)
+}
+
+// setSessionSpanPolicy replaces a session's span policy override; an empty
+// policy removes it. The session is opened if it does not exist yet, so a
+// policy can be in place before the first append; opening one counts against
+// the session quota unless enforceQuotas is false.
+func (s *InMemoryStore) setSessionSpanPolicy(
+	ctx context.Context,
+	tenantID, sessionID string,
+	policy SpanPolicy,
+	enforceQuotas bool,
+	commit func() error,
+) (err error) {
+	_, span := tracer.Start(ctx, "memory.SetSessionSpanPolicy")
+	defer func() { endSpan(span, err) }()
+
+	if tenantID == "" {
+		return errTenantIDRequired
+	}
+	if sessionID == "" {
+		return errSessionIDRequired
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	if enforceQuotas {
+		if err := s.checkQuotasLocked(nil, key); err != nil {
+			return err
+		}
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	session := s.ensureSession(key)
+	session.spanPolicy = policy
+	session.touch(s.now())
+	return nil
+}
+
+// checkSpanPoliciesLocked reports whether events keep every session they
+// touch within its span policy. The caller must hold s.mu.
+func (s *InMemoryStore) checkSpanPoliciesLocked(events []Event) error {
+	batches := make(map[sessionKey][]Event)
+	for _, event := range events {
+		key := sessionKey{tenantID: event.TenantID, sessionID: event.SessionID}
+		batches[key] = append(batches[key], event)
+	}
+
+	for key, batch := range batches {
+		session := s.sessions[key]
+		var override SpanPolicy
+		if session != nil {
+			override = session.spanPolicy
+		}
+		policy := s.opts.SpanPolicies.forSession(key.tenantID, override)
+		if policy == SpanPolicyAllow {
+			continue
+		}
+
+		batch = slices.Clone(batch)
+		sort.Slice(batch, func(i, j int) bool { return eventLess(batch[i], batch[j]) })
+		for i := 1; i < len(batch); i++ {
+			if batch[i].StartToken < batch[i-1].EndTokenExclusive {
+				return fmt.Errorf("%w: events %q and %q overlap", ErrSpanConflict, batch[i-1].EventID, batch[i].EventID)
+			}
+			if policy == SpanPolicyRequireContiguous && batch[i].StartToken != batch[i-1].EndTokenExclusive {
+				return fmt.Errorf("%w: events %q and %q leave a gap", ErrSpanConflict, batch[i-1].EventID, batch[i].EventID)
+			}
+		}
+		if session == nil || len(session.ordered) == 0 {
+			continue
+		}
+
+		switch policy {
+		case SpanPolicyRejectOverlap:
+			for _, event := range batch {
+				if existing, ok := session.overlapping(event.StartToken, event.EndTokenExclusive); ok {
+					return fmt.Errorf("%w: event %q overlaps stored event %q", ErrSpanConflict, event.EventID, existing.EventID)
+				}
+			}
+		case SpanPolicyRequireContiguous:
+			first, last := batch[0], batch[len(batch)-1]
+			if first.StartToken != session.endToken && last.EndTokenExclusive != session.ordered[0].StartToken {
+				return fmt.Errorf(
+					"%w: events must start at token %d or end at token %d",
+					ErrSpanConflict, session.endToken, session.ordered[0].StartToken,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// overlapping returns a stored event whose span overlaps [start, end), by
+// binary search on StartToken bounded by maxSpan.
+func (session *sessionEvents) overlapping(start, end int) (Event, bool) {
+	ordered := session.ordered
+	i := sort.Search(len(ordered), func(i int) bool {
+		return ordered[i].StartToken > start-session.maxSpan
+	})
+	for ; i < len(ordered) && ordered[i].StartToken < end; i++ {
+		if ordered[i].EndTokenExclusive > start {
+			return ordered[i], true
+		}
+	}
+	return Event{}, false
+}
+
+// SpanReport lists the gaps and overlaps in one session for diagnostics.
+// Like GetSession it does not count as an access.
+func (s *InMemoryStore) SpanReport(tenantID, sessionID string) (SpanReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionKey{tenantID: tenantID, sessionID: sessionID}]
+	if !ok {
+		return SpanReport{}, false
+	}
+
+	report := SpanReport{
+		Policy:   s.opts.SpanPolicies.forSession(tenantID, session.spanPolicy),
+		Gaps:     []TokenRange{},
+		Overlaps: []SpanOverlap{},
+	}
+	if len(session.ordered) == 0 {
+		return report, true
+	}
+	report.Extent = TokenRange{StartToken: session.ordered[0].StartToken, EndTokenExclusive: session.endToken}
+
+	// Walk in session order, tracking the event that reaches furthest so far.
+	reach := session.ordered[0]
+	for _, event := range session.ordered[1:] {
+		switch {
+		case event.StartToken > reach.EndTokenExclusive:
+			if len(report.Gaps) == maxSpanReportEntries {
+				report.Truncated = true
+			} else {
+				report.Gaps = append(report.Gaps, TokenRange{StartToken: reach.EndTokenExclusive, EndTokenExclusive: event.StartToken})
+			}
+		case event.StartToken < reach.EndTokenExclusive:
+			if len(report.Overlaps) == maxSpanReportEntries {
+				report.Truncated = true
+			} else {
+				report.Overlaps = append(report.Overlaps, SpanOverlap{
+					TokenRange: TokenRange{
+						StartToken:        event.StartToken,
+						EndTokenExclusive: min(event.EndTokenExclusive, reach.EndTokenExclusive),
+					},
+					EventIDs: [2]string{reach.EventID, event.EventID},
+				})
+			}
+		}
+		if event.EndTokenExclusive > reach.EndTokenExclusive {
+			reach = event
+		}
+	}
+	return report, true
+}
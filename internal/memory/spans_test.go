@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAppendEnforcesSpanPolicies(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	span := func(eventID string, start, end int) Event {
+		return mustEvent(t, eventID, "tenant_1", "session_1", start, end, now)
+	}
+	// Stored spans are [10, 20) and [30, 60), or [10, 30) and [30, 60) for
+	// sessions that must stay contiguous.
+	holey := []Event{span("evt_a", 10, 20), span("evt_b", 30, 60)}
+	contiguous := []Event{span("evt_a", 10, 30), span("evt_b", 30, 60)}
+
+	cases := []struct {
+		name   string
+		policy SpanPolicy
+		stored []Event
+		batch  []Event
+		err    error
+	}{
+		{name: "allow overlap", policy: SpanPolicyAllow, stored: holey, batch: []Event{span("evt_new", 15, 25)}},
+		{name: "overlap stored", policy: SpanPolicyRejectOverlap, stored: holey, batch: []Event{span("evt_new", 15, 25)}, err: ErrSpanConflict},
+		{name: "overlap long stored event", policy: SpanPolicyRejectOverlap, stored: holey, batch: []Event{span("evt_new", 45, 46)}, err: ErrSpanConflict},
+		{name: "overlap within batch", policy: SpanPolicyRejectOverlap, stored: holey, batch: []Event{span("evt_x", 100, 110), span("evt_y", 105, 120)}, err: ErrSpanConflict},
+		{name: "gap allowed without contiguity", policy: SpanPolicyRejectOverlap, stored: holey, batch: []Event{span("evt_new", 100, 110)}},
+		{name: "fill hole", policy: SpanPolicyRejectOverlap, stored: holey, batch: []Event{span("evt_new", 20, 30)}},
+		{name: "append at end", policy: SpanPolicyRequireContiguous, stored: contiguous, batch: []Event{span("evt_x", 60, 70), span("evt_y", 70, 80)}},
+		{name: "prepend at start", policy: SpanPolicyRequireContiguous, stored: contiguous, batch: []Event{span("evt_new", 5, 10)}},
+		{name: "gap after end", policy: SpanPolicyRequireContiguous, stored: contiguous, batch: []Event{span("evt_new", 61, 70)}, err: ErrSpanConflict},
+		{name: "gap within batch", policy: SpanPolicyRequireContiguous, stored: contiguous, batch: []Event{span("evt_x", 60, 70), span("evt_y", 71, 80)}, err: ErrSpanConflict},
+	}
+
+	for _, tc := range cases {
+		store := NewStoreWithOptions(StoreOptions{SpanPolicies: SpanPolicies{Tenants: map[string]SpanPolicy{"tenant_1": tc.policy}}})
+		if err := store.AppendMany(context.Background(), tc.stored); err != nil {
+			t.Fatalf("%s: append: %v", tc.name, err)
+		}
+
+		err := store.AppendMany(context.Background(), tc.batch)
+		if tc.err == nil && err != nil || !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.err, err)
+		}
+		want := len(tc.stored) + len(tc.batch)
+		if tc.err != nil {
+			want = len(tc.stored)
+		}
+		if got := len(store.ListBySession("tenant_1", "session_1")); got != want {
+			t.Fatalf("%s: expected %d stored events, got %d", tc.name, want, got)
+		}
+	}
+}
+
+func TestSessionSpanPolicyOverride(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{
+		SpanPolicies: SpanPolicies{Default: SpanPolicyRejectOverlap},
+		Quotas:       QuotaPolicy{Default: Quota{MaxSessionsPerTenant: 1}},
+	})
+
+	if err := store.SetSessionSpanPolicy(context.Background(), "tenant_1", "session_1", "strict"); err == nil {
+		t.Fatalf("expected unknown policy to be rejected")
+	}
+	// Setting a policy opens the session, so it applies from the first append.
+	if err := store.SetSessionSpanPolicy(context.Background(), "tenant_1", "session_1", SpanPolicyRequireContiguous); err != nil {
+		t.Fatalf("set span policy: %v", err)
+	}
+	if err := store.SetSessionSpanPolicy(context.Background(), "tenant_1", "session_2", SpanPolicyAllow); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected error %v, got %v", ErrQuotaExceeded, err)
+	}
+
+	err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now),
+		mustEvent(t, "evt_2", "tenant_1", "session_1", 12, 20, now),
+	})
+	if !errors.Is(err, ErrSpanConflict) {
+		t.Fatalf("expected error %v, got %v", ErrSpanConflict, err)
+	}
+
+	// Clearing the override falls back to the store default.
+	if err := store.SetSessionSpanPolicy(context.Background(), "tenant_1", "session_1", ""); err != nil {
+		t.Fatalf("clear span policy: %v", err)
+	}
+	if report, ok := store.SpanReport("tenant_1", "session_1"); !ok || report.Policy != SpanPolicyRejectOverlap {
+		t.Fatalf("expected the default policy, got %+v", report)
+	}
+}
+
+func TestSegmentEnforcesSpanPolicy(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{SpanPolicies: SpanPolicies{Default: SpanPolicyRequireContiguous}})
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	req := SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     20,
+		Surprise:       []float64{0.1, 0.2},
+		Threshold:      1,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	}
+	if _, err := store.Segment(context.Background(), req); !errors.Is(err, ErrSpanConflict) {
+		t.Fatalf("expected error %v, got %v", ErrSpanConflict, err)
+	}
+	req.StartToken = 10
+	if _, err := store.Segment(context.Background(), req); err != nil {
+		t.Fatalf("segment: %v", err)
+	}
+}
+
+func TestSpanReportListsGapsAndOverlaps(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	if _, ok := store.SpanReport("tenant_1", "session_1"); ok {
+		t.Fatalf("expected no report for a missing session")
+	}
+	if err := store.AppendMany(context.Background(), []Event{
+		mustEvent(t, "evt_a", "tenant_1", "session_1", 0, 50, now),
+		mustEvent(t, "evt_b", "tenant_1", "session_1", 10, 20, now),
+		mustEvent(t, "evt_c", "tenant_1", "session_1", 40, 60, now),
+		mustEvent(t, "evt_d", "tenant_1", "session_1", 70, 80, now),
+	}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	report, ok := store.SpanReport("tenant_1", "session_1")
+	if !ok {
+		t.Fatalf("expected a report")
+	}
+	if report.Policy != SpanPolicyAllow || report.Extent != (TokenRange{StartToken: 0, EndTokenExclusive: 80}) || report.Truncated {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Gaps) != 1 || report.Gaps[0] != (TokenRange{StartToken: 60, EndTokenExclusive: 70}) {
+		t.Fatalf("expected gap [60, 70), got %+v", report.Gaps)
+	}
+	want := []SpanOverlap{
+		{TokenRange: TokenRange{StartToken: 10, EndTokenExclusive: 20}, EventIDs: [2]string{"evt_a", "evt_b"}},
+		{TokenRange: TokenRange{StartToken: 40, EndTokenExclusive: 50}, EventIDs: [2]string{"evt_a", "evt_c"}},
+	}
+	if len(report.Overlaps) != len(want) || report.Overlaps[0] != want[0] || report.Overlaps[1] != want[1] {
+		t.Fatalf("expected overlaps %+v, got %+v", want, report.Overlaps)
+	}
+}
+
+func TestDurableStoreReplaysSpanPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.SetSessionSpanPolicy(context.Background(), "tenant_1", "session_1", SpanPolicyRejectOverlap); err != nil {
+		t.Fatalf("set span policy: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	for _, snapshot := range []bool{false, true} {
+		reopened := mustOpenDurableStore(t, dir)
+		if report, ok := reopened.SpanReport("tenant_1", "session_1"); !ok || report.Policy != SpanPolicyRejectOverlap {
+			t.Fatalf("snapshot=%v: expected the policy to survive a restart, got %+v", snapshot, report)
+		}
+		if err := reopened.Snapshot(); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		if err := reopened.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+}
@@ -1,21 +1,140 @@
 package memory
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrDuplicateEventID = errors.New("event_id already exists in tenant session")
 
+// ErrInvalidInput matches every error the store returns for an event, query or
+// segmentation request it rejects as malformed, as opposed to a storage failure.
+// Such errors keep their own message.
+var ErrInvalidInput = errors.New("invalid input")
+
+type inputError struct{ msg string }
+
+func (e *inputError) Error() string { return e.msg }
+
+func (e *inputError) Is(target error) bool { return target == ErrInvalidInput }
+
+func invalidInput(format string, args ...any) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
+
 var (
-	errRetrieveTopKNonPositive = errors.New("top_k must be positive")
-	errRetrieveBufferNegative  = errors.New("buffer_before and buffer_after must be non-negative")
+	errRetrieveTopKNonPositive     = invalidInput("top_k must be positive")
+	errRetrieveBufferNegative      = invalidInput("buffer_before and buffer_after must be non-negative")
+	errSessionEmbeddingDimMismatch = invalidInput("embedding dimension does not match other events in the session")
+	errQueryEmbeddingDimMismatch   = invalidInput("query_embedding dimension does not match session embeddings")
 )
 
-type Store struct {
+// Store persists episodic events per tenant session and serves retrieval over them.
+// Implementations must keep AppendMany all-or-nothing. Methods that take a
+// context use it only to parent trace spans.
+type Store interface {
+	Append(ctx context.Context, event Event) error
+	AppendMany(ctx context.Context, events []Event) error
+	Get(tenantID, sessionID, eventID string) (Event, bool)
+	ListBySession(tenantID, sessionID string) []Event
+	// ListEvents returns one page of a session's events in session order.
+	ListEvents(ctx context.Context, query ListQuery) (EventPage, error)
+	// GetSession and ListSessions summarize sessions without copying events.
+	GetSession(tenantID, sessionID string) (SessionSummary, bool)
+	ListSessions(ctx context.Context, query SessionQuery) (SessionPage, error)
+	// RetrieveByAnchors expands the anchor events with their contiguity
+	// buffers and annotates each result with why it was included.
+	RetrieveByAnchors(
+		ctx context.Context,
+		tenantID, sessionID string,
+		anchorEventIDs []string,
+		topK, bufferBefore, bufferAfter int,
+	) ([]RetrievedEvent, error)
+	// RetrieveBySimilarity picks the top_k events whose embeddings best match
+	// query and expands them with the same contiguity buffers as RetrieveByAnchors.
+	RetrieveBySimilarity(ctx context.Context, query SimilarityQuery) ([]RetrievedEvent, error)
+	// RetrieveByText picks the top_k events whose payload text best matches
+	// the query by BM25, optionally fused with similarity, and expands them
+	// like RetrieveByAnchors.
+	RetrieveByText(ctx context.Context, query TextQuery) ([]RetrievedEvent, error)
+	// RetrieveAcrossSessions searches several sessions of one tenant by
+	// similarity and groups the results by session.
+	RetrieveAcrossSessions(ctx context.Context, query CrossSessionQuery) ([]SessionResults, error)
+	// AssembleContext packs the best-ranked events and their buffers into a
+	// token budget and joins their text.
+	AssembleContext(ctx context.Context, req ContextRequest) (AssembledContext, error)
+	// Segment segments req against the session's recorded surprise history,
+	// then appends the resulting events and adds req.Surprise to that history
+	// as one all-or-nothing step. In stream mode the session keeps an open
+	// tail that later calls continue and only confirmed events are appended.
+	Segment(ctx context.Context, req SegmentRequest) (Segmentation, error)
+	// UpdateEvent replaces the mutable fields of one event and increments its
+	// version. A non-zero ifVersion must match the stored version, or the
+	// update fails with ErrVersionConflict.
+	UpdateEvent(
+		ctx context.Context,
+		tenantID, sessionID, eventID string,
+		patch EventPatch,
+		ifVersion int64,
+	) (Event, error)
+	// DeleteEvent, DeleteSession and PurgeTenant remove stored data so that
+	// reads, retrieval, quotas and Stats no longer see it.
+	DeleteEvent(ctx context.Context, tenantID, sessionID, eventID string) (Deletion, error)
+	DeleteSession(ctx context.Context, tenantID, sessionID string) (Deletion, error)
+	PurgeTenant(ctx context.Context, tenantID string) (Deletion, error)
+	// SetSessionRetention overrides the retention policy for one existing
+	// session; a zero Retention removes the override.
+	SetSessionRetention(ctx context.Context, tenantID, sessionID string, retention Retention) error
+	// SetSessionSpanPolicy overrides the span policy for one session, opening
+	// it if needed; an empty policy removes the override.
+	SetSessionSpanPolicy(ctx context.Context, tenantID, sessionID string, policy SpanPolicy) error
+	// SetSessionLabels replaces the labels of one session, opening it if
+	// needed; empty labels remove them all.
+	SetSessionLabels(ctx context.Context, tenantID, sessionID string, labels map[string]string) error
+	// SpanReport lists the gaps and overlaps between a session's events.
+	SpanReport(tenantID, sessionID string) (SpanReport, bool)
+	// Sweep expires whatever the retention policy no longer allows.
+	Sweep(ctx context.Context) (Deletion, error)
+	Stats() StoreStats
+}
+
+// InMemoryStore keeps all sessions in process memory. Its contents are lost on restart.
+type InMemoryStore struct {
 	mu       sync.RWMutex
 	sessions map[sessionKey]*sessionEvents
+	usage    map[string]*tenantUsage
+	opts     StoreOptions
+	now      func() time.Time
+
+	// text holds one inverted index over payload text per tenant; a tenant
+	// without indexed text has no entry.
+	text map[string]*textIndex
+	// tenantSessions holds each tenant's session ids in ascending order, so
+	// session listings never scan other tenants or sort.
+	tenantSessions map[string][]string
+}
+
+// StoreOptions configures an InMemoryStore.
+type StoreOptions struct {
+	Index  IndexOptions
+	Quotas QuotaPolicy
+	// Retention is enforced by Sweep; without sweeps nothing expires.
+	Retention RetentionPolicy
+	// SpanPolicies constrain how the token spans of appended events relate
+	// to each other; the default allows anything.
+	SpanPolicies SpanPolicies
+	// Observer, if set, receives segmentation and retrieval statistics.
+	Observer Observer
 }
 
 type sessionKey struct {
@@ -26,23 +145,85 @@ type sessionKey struct {
 type sessionEvents struct {
 	ordered []Event
 	byID    map[string]Event
+	// embeddingDim is shared by every embedding in the session; 0 until the first one arrives.
+	embeddingDim int
+	// index holds every embedding in the session; nil until the first one arrives.
+	index *hnswIndex
+	// surpriseHistory holds the most recent segmented surprise values, oldest
+	// first, so adaptive thresholds carry over between Segment calls.
+	surpriseHistory []float64
+	// segments counts the segmentations applied to the session, so a
+	// segmentation built outside the lock can tell whether it went stale.
+	segments uint64
+	// pending is the open tail of a streamed session; nil when no stream is open.
+	pending *pendingSegment
+	// maxSpan bounds the token length of every event in the session, so token
+	// range lookups can binary search on StartToken. It never shrinks.
+	maxSpan int
+	// endToken, firstCreatedAt and lastCreatedAt summarize the events so
+	// session listings never scan them; see noteEvent.
+	endToken       int
+	firstCreatedAt time.Time
+	lastCreatedAt  time.Time
+	// retention overrides the store policy for this session; nil inherits it.
+	retention *Retention
+	// spanPolicy overrides the store span policy for this session; empty
+	// inherits it.
+	spanPolicy SpanPolicy
+	// labels select the session for cross-session retrieval; nil when unset.
+	labels map[string]string
+	// lastAccess is when the session was last read or written, in Unix
+	// nanoseconds. It is atomic because reads update it under the read lock.
+	lastAccess atomic.Int64
+}
+
+func (session *sessionEvents) touch(now time.Time) {
+	session.lastAccess.Store(now.UnixNano())
+}
+
+func (session *sessionEvents) lastAccessTime() time.Time {
+	return time.Unix(0, session.lastAccess.Load()).UTC()
+}
+
+var _ Store = (*InMemoryStore)(nil)
+
+func NewStore() *InMemoryStore {
+	return NewStoreWithOptions(StoreOptions{})
 }
 
-func NewStore() *Store {
-	return &Store{
+func NewStoreWithOptions(opts StoreOptions) *InMemoryStore {
+	opts.Index = opts.Index.withDefaults()
+	return &InMemoryStore{
 		sessions: make(map[sessionKey]*sessionEvents),
+		usage:    make(map[string]*tenantUsage),
+		text:     make(map[string]*textIndex),
+		now:      time.Now,
+		opts:     opts,
+
+		tenantSessions: make(map[string][]string),
 	}
 }
 
-func (s *Store) Append(event Event) error {
-	return s.AppendMany([]Event{event})
+func (s *InMemoryStore) Append(ctx context.Context, event Event) error {
+	return s.AppendMany(ctx, []Event{event})
+}
+
+func (s *InMemoryStore) AppendMany(ctx context.Context, events []Event) error {
+	return s.appendMany(ctx, events, true, nil)
 }
 
-func (s *Store) AppendMany(events []Event) error {
+// appendMany validates the batch and, once it is known to be accepted, calls
+// commit before mutating any state. A commit error aborts the whole batch.
+// Quotas and span policies are enforced for new writes but not for log
+// replay, so tightening either never makes stored data unreadable.
+func (s *InMemoryStore) appendMany(ctx context.Context, events []Event, enforcePolicies bool, commit func() error) (err error) {
 	if len(events) == 0 {
 		return nil
 	}
 
+	_, span := tracer.Start(ctx, "memory.AppendMany", trace.WithAttributes(attribute.Int("events", len(events))))
+	defer func() { endSpan(span, err) }()
+
 	for _, event := range events {
 		if err := validateEvent(event); err != nil {
 			return err
@@ -51,14 +232,53 @@ func (s *Store) AppendMany(events []Event) error {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	lockAcquired(span)
 
+	if err := s.checkAppendLocked(events); err != nil {
+		return err
+	}
+	if enforcePolicies {
+		if err := s.checkQuotasLocked(events); err != nil {
+			return err
+		}
+		if err := s.checkSpanPoliciesLocked(events); err != nil {
+			return err
+		}
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+		span.AddEvent("log committed")
+	}
+
+	s.applyAppendLocked(events)
+	return nil
+}
+
+// checkAppendLocked reports whether events can be appended without violating
+// event id uniqueness or per-session embedding dimensions. The caller must
+// hold s.mu.
+func (s *InMemoryStore) checkAppendLocked(events []Event) error {
 	seenBySession := make(map[sessionKey]map[string]struct{})
+	batchDims := make(map[sessionKey]int)
 	for _, event := range events {
 		key := sessionKey{tenantID: event.TenantID, sessionID: event.SessionID}
 		if existingSession, ok := s.sessions[key]; ok {
 			if _, exists := existingSession.byID[event.EventID]; exists {
 				return ErrDuplicateEventID
 			}
+			if _, seen := batchDims[key]; !seen {
+				batchDims[key] = existingSession.embeddingDim
+			}
+		}
+
+		if dim := event.embeddingDim(); dim != 0 {
+			if batchDims[key] != 0 && batchDims[key] != dim {
+				return errSessionEmbeddingDimMismatch
+			}
+			batchDims[key] = dim
 		}
 
 		seenIDs, ok := seenBySession[key]
@@ -72,23 +292,273 @@ func (s *Store) AppendMany(events []Event) error {
 		seenIDs[event.EventID] = struct{}{}
 	}
 
+	return nil
+}
+
+// applyAppendLocked stores a batch that passed checkAppendLocked. The caller
+// must hold s.mu.
+func (s *InMemoryStore) applyAppendLocked(events []Event) {
 	updatedSessions := make(map[sessionKey]struct{})
 	for _, event := range events {
+		// Stored events must not alias caller-owned payload slices.
+		event = event.clone()
+		event.Version = FirstEventVersion
 		key := sessionKey{tenantID: event.TenantID, sessionID: event.SessionID}
 		session := s.ensureSession(key)
 		session.byID[event.EventID] = event
 		session.ordered = append(session.ordered, event)
+		session.noteEvent(event)
+		usage := s.tenantUsage(key.tenantID)
+		usage.events++
+		usage.payloadBytes += event.payloadBytes()
+		if dim := event.embeddingDim(); dim != 0 {
+			session.embeddingDim = dim
+			s.indexEvent(session, event)
+		}
+		s.indexTextLocked(event)
 		updatedSessions[key] = struct{}{}
 	}
 
+	now := s.now()
 	for key := range updatedSessions {
 		sortSessionEvents(s.sessions[key])
+		s.sessions[key].touch(now)
 	}
+}
+
+func (s *InMemoryStore) Segment(ctx context.Context, req SegmentRequest) (Segmentation, error) {
+	return s.segment(ctx, req, nil)
+}
 
+// segment builds the segmentation from a copy of the session's surprise
+// history and stream tail taken under the read lock, so boundary detection
+// and refinement never hold the write lock. The write lock is then taken to
+// check that no other segmentation of the session landed in the meantime;
+// if one did, the segmentation is rebuilt from the newer state, so
+// concurrent calls on one session still see each other's surprise history.
+// Like appendMany, commit runs once the result is known to be accepted and
+// before any state changes.
+func (s *InMemoryStore) segment(
+	ctx context.Context,
+	req SegmentRequest,
+	commit func(Segmentation, *pendingSegment) error,
+) (_ Segmentation, err error) {
+	ctx, span := tracer.Start(ctx, "memory.Segment", trace.WithAttributes(
+		attribute.Int("surprise", len(req.Surprise)),
+		attribute.Bool("stream", req.Stream),
+		attribute.Bool("flush", req.Flush),
+	))
+	defer func() { endSpan(span, err) }()
+
+	key := sessionKey{tenantID: req.TenantID, sessionID: req.SessionID}
+	for attempt := 1; ; attempt++ {
+		// After maxSegmentAttempts lost races the build runs under the write
+		// lock, so a hot session cannot starve its writers.
+		optimistic := attempt <= maxSegmentAttempts
+		var state segmentState
+		if optimistic {
+			s.mu.RLock()
+			state = s.segmentStateLocked(key)
+			s.mu.RUnlock()
+		} else {
+			s.mu.Lock()
+			lockAcquired(span)
+			state = s.segmentStateLocked(key)
+		}
+
+		segmentation, next, err := buildSegmentation(ctx, req, state.pending, state.history)
+		if optimistic {
+			if err != nil {
+				return Segmentation{}, err
+			}
+			s.mu.Lock()
+			lockAcquired(span)
+			if !s.segmentStateCurrentLocked(key, state) {
+				s.mu.Unlock()
+				span.AddEvent("segmentation state changed")
+				continue
+			}
+		}
+		defer s.mu.Unlock()
+		if err != nil {
+			return Segmentation{}, err
+		}
+		span.SetAttributes(attribute.Int("attempts", attempt))
+		return s.commitSegmentLocked(span, key, req, segmentation, next, commit)
+	}
+}
+
+// maxSegmentAttempts bounds how often segment rebuilds a segmentation outside
+// the write lock after losing a race on the same session.
+const maxSegmentAttempts = 3
+
+// segmentState is the part of a session a segmentation is built from.
+type segmentState struct {
+	// session is nil when the session did not exist.
+	session *sessionEvents
+	// segments is session.segments when the state was read.
+	segments uint64
+	history  []float64
+	pending  *pendingSegment
+}
+
+// segmentStateLocked copies the state a segmentation of key is built from.
+// The caller must hold s.mu, for reading at least.
+func (s *InMemoryStore) segmentStateLocked(key sessionKey) segmentState {
+	session, ok := s.sessions[key]
+	if !ok {
+		return segmentState{}
+	}
+	return segmentState{
+		session:  session,
+		segments: session.segments,
+		history:  slices.Clone(session.surpriseHistory),
+		pending:  session.pending.clone(),
+	}
+}
+
+// segmentStateCurrentLocked reports whether state is still what a
+// segmentation of key would be built from. The caller must hold s.mu.
+func (s *InMemoryStore) segmentStateCurrentLocked(key sessionKey, state segmentState) bool {
+	session, ok := s.sessions[key]
+	if ok && session == state.session {
+		return session.segments == state.segments
+	}
+	// The session was created or replaced meanwhile; only an empty state
+	// carries over.
+	empty := !ok || (len(session.surpriseHistory) == 0 && session.pending == nil)
+	return empty && len(state.history) == 0 && state.pending == nil
+}
+
+// commitSegmentLocked checks a built segmentation against the store, commits
+// it and applies it. The caller must hold s.mu.
+func (s *InMemoryStore) commitSegmentLocked(
+	span trace.Span,
+	key sessionKey,
+	req SegmentRequest,
+	segmentation Segmentation,
+	next *pendingSegment,
+	commit func(Segmentation, *pendingSegment) error,
+) (Segmentation, error) {
+	if err := s.checkAppendLocked(segmentation.Events); err != nil {
+		return Segmentation{}, err
+	}
+	if err := s.checkQuotasLocked(segmentation.Events, key); err != nil {
+		return Segmentation{}, err
+	}
+	if err := s.checkSpanPoliciesLocked(segmentation.Events); err != nil {
+		return Segmentation{}, err
+	}
+
+	if commit != nil {
+		if err := commit(segmentation, next); err != nil {
+			return Segmentation{}, err
+		}
+		span.AddEvent("log committed")
+	}
+
+	s.applySegmentLocked(key, segmentation.Events, req.Surprise, next)
+	if s.opts.Observer != nil {
+		lengths := make([]int, len(segmentation.Events))
+		for i, event := range segmentation.Events {
+			lengths[i] = event.EndTokenExclusive - event.StartToken
+		}
+		s.opts.Observer.ObserveSegmentation(req.TenantID, len(segmentation.Boundaries), lengths)
+	}
+	return segmentation, nil
+}
+
+// buildSegmentation runs boundary detection in its own span, separating the
+// compute cost of a segmentation from lock wait and logging. It reads only
+// its arguments, so it needs no lock.
+func buildSegmentation(
+	ctx context.Context,
+	req SegmentRequest,
+	pending *pendingSegment,
+	history []float64,
+) (segmentation Segmentation, next *pendingSegment, err error) {
+	_, span := tracer.Start(ctx, "memory.BuildSegmentation")
+	defer func() {
+		span.SetAttributes(attribute.Int("boundaries", len(segmentation.Boundaries)))
+		endSpan(span, err)
+	}()
+
+	switch {
+	case req.Stream:
+		return buildStreamSegmentation(req, pending, history)
+	case req.Flush:
+		return Segmentation{}, nil, errFlushRequiresStream
+	case pending != nil:
+		return Segmentation{}, nil, errStreamPending
+	default:
+		segmentation, err = BuildSegmentation(req, history)
+		return segmentation, nil, err
+	}
+}
+
+func (s *InMemoryStore) Stats() StoreStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := StoreStats{Tenants: make(map[string]TenantStats, len(s.usage))}
+	for tenantID, usage := range s.usage {
+		stats.Tenants[tenantID] = TenantStats{
+			Sessions:     usage.sessions,
+			Events:       usage.events,
+			PayloadBytes: usage.payloadBytes,
+		}
+	}
+	return stats
+}
+
+// replaySegment re-applies a logged segmentation without recomputing it.
+func (s *InMemoryStore) replaySegment(tenantID, sessionID string, events []Event, surprise []float64, pending *pendingSegment) error {
+	for _, event := range events {
+		if err := validateEvent(event); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkAppendLocked(events); err != nil {
+		return err
+	}
+	s.applySegmentLocked(sessionKey{tenantID: tenantID, sessionID: sessionID}, events, surprise, pending)
 	return nil
 }
 
-func (s *Store) ensureSession(key sessionKey) *sessionEvents {
+// applySegmentLocked stores the events of an accepted segmentation, appends
+// surprise to the session history, keeping only the newest
+// maxSurpriseHistory values, and replaces the pending stream tail. The caller
+// must hold s.mu.
+func (s *InMemoryStore) applySegmentLocked(key sessionKey, events []Event, surprise []float64, pending *pendingSegment) {
+	s.applyAppendLocked(events)
+
+	session := s.ensureSession(key)
+	session.touch(s.now())
+	session.pending = pending.clone()
+	history := append(session.surpriseHistory, surprise...)
+	if len(history) > maxSurpriseHistory {
+		// Copy rather than reslice so the dropped prefix can be collected.
+		history = append([]float64(nil), history[len(history)-maxSurpriseHistory:]...)
+	}
+	session.surpriseHistory = history
+	session.segments++
+}
+
+// indexEvent inserts every embedding of event into the session index.
+func (s *InMemoryStore) indexEvent(session *sessionEvents, event Event) {
+	if session.index == nil {
+		session.index = newHNSWIndex(s.opts.Index)
+	}
+	for ordinal, vector := range event.Embeddings {
+		session.index.insert(event.EventID, ordinal, vector)
+	}
+}
+
+func (s *InMemoryStore) ensureSession(key sessionKey) *sessionEvents {
 	events, ok := s.sessions[key]
 	if ok {
 		return events
@@ -99,24 +569,55 @@ func (s *Store) ensureSession(key sessionKey) *sessionEvents {
 		byID:    make(map[string]Event),
 	}
 	s.sessions[key] = events
+	s.tenantUsage(key.tenantID).sessions++
+	sessionIDs := s.tenantSessions[key.tenantID]
+	i, _ := slices.BinarySearch(sessionIDs, key.sessionID)
+	s.tenantSessions[key.tenantID] = slices.Insert(sessionIDs, i, key.sessionID)
 	return events
 }
 
+func (s *InMemoryStore) tenantUsage(tenantID string) *tenantUsage {
+	usage, ok := s.usage[tenantID]
+	if !ok {
+		usage = &tenantUsage{}
+		s.usage[tenantID] = usage
+	}
+	return usage
+}
+
 func sortSessionEvents(events *sessionEvents) {
 	sort.Slice(events.ordered, func(i, j int) bool {
-		left := events.ordered[i]
-		right := events.ordered[j]
-		if left.StartToken != right.StartToken {
-			return left.StartToken < right.StartToken
-		}
-		if !left.CreatedAt.Equal(right.CreatedAt) {
-			return left.CreatedAt.Before(right.CreatedAt)
-		}
-		return left.EventID < right.EventID
+		return eventLess(events.ordered[i], events.ordered[j])
+	})
+}
+
+// eventLess is the session order: StartToken, then CreatedAt, then EventID.
+func eventLess(left, right Event) bool {
+	if left.StartToken != right.StartToken {
+		return left.StartToken < right.StartToken
+	}
+	if !left.CreatedAt.Equal(right.CreatedAt) {
+		return left.CreatedAt.Before(right.CreatedAt)
+	}
+	return left.EventID < right.EventID
+}
+
+// orderedIndex locates a stored event in session order by binary search.
+func (session *sessionEvents) orderedIndex(eventID string) (int, bool) {
+	event, ok := session.byID[eventID]
+	if !ok {
+		return 0, false
+	}
+	i := sort.Search(len(session.ordered), func(i int) bool {
+		return !eventLess(session.ordered[i], event)
 	})
+	if i == len(session.ordered) || session.ordered[i].EventID != eventID {
+		return 0, false
+	}
+	return i, true
 }
 
-func (s *Store) Get(tenantID, sessionID, eventID string) (Event, bool) {
+func (s *InMemoryStore) Get(tenantID, sessionID, eventID string) (Event, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -126,15 +627,16 @@ func (s *Store) Get(tenantID, sessionID, eventID string) (Event, bool) {
 		return Event{}, false
 	}
 
+	events.touch(s.now())
 	event, found := events.byID[eventID]
 	if !found {
 		return Event{}, false
 	}
 
-	return event, true
+	return event.clone(), true
 }
 
-func (s *Store) ListBySession(tenantID, sessionID string) []Event {
+func (s *InMemoryStore) ListBySession(tenantID, sessionID string) []Event {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -143,17 +645,27 @@ func (s *Store) ListBySession(tenantID, sessionID string) []Event {
 	if !ok {
 		return []Event{}
 	}
+	events.touch(s.now())
 
 	list := make([]Event, len(events.ordered))
-	copy(list, events.ordered)
+	for i, event := range events.ordered {
+		list[i] = event.clone()
+	}
 	return list
 }
 
-func (s *Store) RetrieveByAnchors(
+func (s *InMemoryStore) RetrieveByAnchors(
+	ctx context.Context,
 	tenantID, sessionID string,
 	anchorEventIDs []string,
 	topK, bufferBefore, bufferAfter int,
-) ([]Event, error) {
+) (_ []RetrievedEvent, err error) {
+	_, span := tracer.Start(ctx, "memory.RetrieveByAnchors", trace.WithAttributes(
+		attribute.Int("anchors", len(anchorEventIDs)),
+		attribute.Int("top_k", topK),
+	))
+	defer func() { endSpan(span, err) }()
+
 	if topK <= 0 {
 		return nil, errRetrieveTopKNonPositive
 	}
@@ -163,27 +675,31 @@ func (s *Store) RetrieveByAnchors(
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	lockAcquired(span)
 
 	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
 	session, ok := s.sessions[key]
 	if !ok || len(session.ordered) == 0 {
-		return []Event{}, nil
+		return []RetrievedEvent{}, nil
 	}
+	session.touch(s.now())
 
 	// Bound top_k to practical limits before using it as map/slice capacity.
 	effectiveTopK := min(topK, len(anchorEventIDs), len(session.ordered))
+	anchorIndexes := session.anchorIndexes(anchorEventIDs, effectiveTopK)
+	return s.expandObserved(tenantID, effectiveTopK, session.ordered, anchorIndexes, nil, bufferBefore, bufferAfter), nil
+}
 
-	// Build event_id -> ordered index for O(1) anchor lookups.
-	indexByID := make(map[string]int, len(session.ordered))
-	for i, event := range session.ordered {
-		indexByID[event.EventID] = i
-	}
-
-	anchorIndexes := make([]int, 0, effectiveTopK)
-	// Dedupe repeated anchor ids while preserving first-seen request order.
-	seenAnchors := make(map[int]struct{}, effectiveTopK)
+// anchorIndexes resolves up to topK anchor ids to positions in session order,
+// skipping unknown and repeated ids and keeping first-seen request order.
+func (session *sessionEvents) anchorIndexes(anchorEventIDs []string, topK int) []int {
+	anchorIndexes := make([]int, 0, topK)
+	seenAnchors := make(map[int]struct{}, topK)
 	for _, eventID := range anchorEventIDs {
-		index, found := indexByID[eventID]
+		if len(anchorIndexes) == topK {
+			break
+		}
+		index, found := session.orderedIndex(eventID)
 		if !found {
 			continue
 		}
@@ -192,36 +708,263 @@ func (s *Store) RetrieveByAnchors(
 		}
 		seenAnchors[index] = struct{}{}
 		anchorIndexes = append(anchorIndexes, index)
-		if len(anchorIndexes) == effectiveTopK {
-			break
-		}
 	}
+	return anchorIndexes
+}
+
+// SimilarityQuery asks for the TopK events whose embeddings best match
+// QueryEmbedding. Recency, when set, blends how recent each event is into
+// its score.
+type SimilarityQuery struct {
+	TenantID       string
+	SessionID      string
+	QueryEmbedding []float32
+	Metric         SimilarityMetric
+	TopK           int
+	BufferBefore   int
+	BufferAfter    int
+	Recency        *Recency
+}
+
+func (query SimilarityQuery) validate() error {
+	if query.TopK <= 0 {
+		return errRetrieveTopKNonPositive
+	}
+	if query.BufferBefore < 0 || query.BufferAfter < 0 {
+		return errRetrieveBufferNegative
+	}
+	if err := query.Metric.validate(); err != nil {
+		return err
+	}
+	if err := validateVector(query.QueryEmbedding); err != nil {
+		return fmt.Errorf("query_embedding: %w", err)
+	}
+	return query.Recency.validate(query.Metric)
+}
+
+func (s *InMemoryStore) RetrieveBySimilarity(ctx context.Context, query SimilarityQuery) (_ []RetrievedEvent, err error) {
+	_, span := tracer.Start(ctx, "memory.RetrieveBySimilarity", trace.WithAttributes(
+		attribute.String("metric", string(query.Metric)),
+		attribute.Int("top_k", query.TopK),
+		attribute.Bool("recency", query.Recency != nil),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lockAcquired(span)
 
-	if len(anchorIndexes) == 0 {
-		return []Event{}, nil
+	key := sessionKey{tenantID: query.TenantID, sessionID: query.SessionID}
+	session, ok := s.sessions[key]
+	if !ok || session.embeddingDim == 0 {
+		return []RetrievedEvent{}, nil
+	}
+	session.touch(s.now())
+	if len(query.QueryEmbedding) != session.embeddingDim {
+		return nil, errQueryEmbeddingDimMismatch
 	}
 
-	// Merge expanded anchor windows via set semantics to avoid duplicates.
-	includeIndexes := make(map[int]struct{})
-	for _, anchor := range anchorIndexes {
-		start := max(0, anchor-bufferBefore)
-		end := min(len(session.ordered)-1, anchor+bufferAfter)
-		for i := start; i <= end; i++ {
-			includeIndexes[i] = struct{}{}
+	anchorIndexes, scores, useIndex := s.similarAnchors(session, query.QueryEmbedding, query.Metric, query.TopK, query.Recency)
+	span.SetAttributes(attribute.Bool("index", useIndex))
+	return s.expandObserved(query.TenantID, query.TopK, session.ordered, anchorIndexes, scores, query.BufferBefore, query.BufferAfter), nil
+}
+
+// similarAnchors returns the positions in session order of the topK events
+// most similar to query, best first, with their scores and whether the HNSW
+// index answered. A non-nil recency reweights the scores before the cut.
+// query must match the session's embedding dimension. The caller must hold
+// s.mu.
+func (s *InMemoryStore) similarAnchors(
+	session *sessionEvents,
+	query []float32,
+	metric SimilarityMetric,
+	topK int,
+	recency *Recency,
+) ([]int, []float64, bool) {
+	var ranking []rankedIndex
+	now := s.now()
+	useIndex := metric == SimilarityCosine && session.index != nil && session.index.len() >= s.opts.Index.MinVectors
+	if useIndex {
+		k := topK
+		if recency != nil {
+			k = max(topK, recencyCandidates)
+		}
+		matches := session.index.search(query, k, s.opts.Index.EfSearch)
+		ranking = make([]rankedIndex, 0, len(matches))
+		for _, match := range matches {
+			if i, ok := session.orderedIndex(match.eventID); ok {
+				score := recency.apply(now, session, session.ordered[i], match.score)
+				ranking = append(ranking, rankedIndex{index: i, score: score})
+			}
+		}
+	} else {
+		// Exhaustive scan: exact, and the only option for the dot metric,
+		// which the cosine graph cannot answer.
+		queryNorm := vectorNorm(query)
+		ranking = make([]rankedIndex, 0, len(session.ordered))
+		for i, event := range session.ordered {
+			if len(event.Embeddings) == 0 {
+				continue
+			}
+			// An event is as similar as its best representative vector.
+			best := math.Inf(-1)
+			for _, vector := range event.Embeddings {
+				best = max(best, similarity(metric, query, queryNorm, vector))
+			}
+			ranking = append(ranking, rankedIndex{index: i, score: recency.apply(now, session, event, best)})
+		}
+	}
+	sortRanking(ranking)
+
+	anchorIndexes, scores := splitRanking(ranking, topK)
+	return anchorIndexes, scores, useIndex
+}
+
+// rankedIndex is a position in session order with its ranking score.
+type rankedIndex struct {
+	index int
+	score float64
+}
+
+// sortRanking orders by descending score, then by session order.
+func sortRanking(ranking []rankedIndex) {
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].score != ranking[j].score {
+			return ranking[i].score > ranking[j].score
 		}
+		return ranking[i].index < ranking[j].index
+	})
+}
+
+// splitRanking returns the positions and scores of the first topK entries.
+func splitRanking(ranking []rankedIndex, topK int) ([]int, []float64) {
+	ranking = ranking[:min(topK, len(ranking))]
+	indexes := make([]int, len(ranking))
+	scores := make([]float64, len(ranking))
+	for i, ranked := range ranking {
+		indexes[i], scores[i] = ranked.index, ranked.score
 	}
+	return indexes, scores
+}
 
-	orderedIndexes := make([]int, 0, len(includeIndexes))
-	for i := range includeIndexes {
-		orderedIndexes = append(orderedIndexes, i)
+// expandObserved expands anchors and reports the retrieval to the observer.
+func (s *InMemoryStore) expandObserved(
+	tenantID string,
+	requested int,
+	ordered []Event,
+	anchorIndexes []int,
+	scores []float64,
+	bufferBefore, bufferAfter int,
+) []RetrievedEvent {
+	result := expandAnchors(ordered, anchorIndexes, scores, bufferBefore, bufferAfter)
+	if s.opts.Observer != nil {
+		s.opts.Observer.ObserveRetrieval(tenantID, requested, len(anchorIndexes), len(result))
 	}
-	// Re-establish deterministic session order after set-based collection.
-	sort.Ints(orderedIndexes)
+	return result
+}
 
-	result := make([]Event, 0, len(orderedIndexes))
-	for _, i := range orderedIndexes {
-		result = append(result, session.ordered[i])
+// snapshotSessionsLocked copies every session in a deterministic order.
+// The caller must hold s.mu.
+func (s *InMemoryStore) snapshotSessionsLocked() []sessionSnapshot {
+	sessions := make([]sessionSnapshot, 0, len(s.sessions))
+	for key, session := range s.sessions {
+		events := make([]Event, len(session.ordered))
+		copy(events, session.ordered)
+		snapshot := sessionSnapshot{
+			TenantID:        key.tenantID,
+			SessionID:       key.sessionID,
+			Events:          events,
+			SurpriseHistory: append([]float64(nil), session.surpriseHistory...),
+			Pending:         session.pending.clone(),
+			Retention:       session.retention,
+			SpanPolicy:      session.spanPolicy,
+			Labels:          session.labels,
+			LastAccess:      session.lastAccessTime(),
+		}
+		// A graph with tombstones still holds vectors of deleted events,
+		// which must not reach disk; restore rebuilds it from the events.
+		if session.index != nil && session.index.tombstones == 0 {
+			snapshot.Index = session.index.snapshot()
+		}
+		sessions = append(sessions, snapshot)
 	}
 
-	return result, nil
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].TenantID != sessions[j].TenantID {
+			return sessions[i].TenantID < sessions[j].TenantID
+		}
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+	return sessions
+}
+
+// restore replaces all sessions with the snapshot contents.
+func (s *InMemoryStore) restore(sessions []sessionSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = make(map[sessionKey]*sessionEvents, len(sessions))
+	s.usage = make(map[string]*tenantUsage)
+	// Text indexes are not snapshotted; they are cheap to rebuild.
+	s.text = make(map[string]*textIndex)
+	s.tenantSessions = make(map[string][]string)
+	for _, snapshot := range sessions {
+		key := sessionKey{tenantID: snapshot.TenantID, sessionID: snapshot.SessionID}
+		session := &sessionEvents{
+			ordered:         snapshot.Events,
+			byID:            make(map[string]Event, len(snapshot.Events)),
+			surpriseHistory: snapshot.SurpriseHistory,
+			pending:         snapshot.Pending,
+			retention:       snapshot.Retention,
+			spanPolicy:      snapshot.SpanPolicy,
+			labels:          snapshot.Labels,
+		}
+		// Snapshots written before last access was recorded count as an
+		// access at load time, so upgrading never expires sessions at once.
+		if snapshot.LastAccess.IsZero() {
+			session.touch(s.now())
+		} else {
+			session.touch(snapshot.LastAccess)
+		}
+		usage := s.tenantUsage(key.tenantID)
+		usage.sessions++
+		vectors := 0
+		for i, event := range snapshot.Events {
+			// Events snapshotted before versioning start at the first version.
+			if event.Version == 0 {
+				event.Version = FirstEventVersion
+				snapshot.Events[i] = event
+			}
+			session.byID[event.EventID] = event
+			session.noteEvent(event)
+			usage.events++
+			usage.payloadBytes += event.payloadBytes()
+			if dim := event.embeddingDim(); dim != 0 {
+				session.embeddingDim = dim
+			}
+			vectors += len(event.Embeddings)
+			s.indexTextLocked(event)
+		}
+		sortSessionEvents(session)
+
+		if index, ok := restoreHNSWIndex(snapshot.Index, s.opts.Index, session.byID); ok && index.len() == vectors {
+			session.index = index
+		} else {
+			// Graph missing or built with other parameters: rebuild from the events.
+			for _, event := range session.ordered {
+				if len(event.Embeddings) > 0 {
+					s.indexEvent(session, event)
+				}
+			}
+		}
+		s.sessions[key] = session
+		s.tenantSessions[key.tenantID] = append(s.tenantSessions[key.tenantID], key.sessionID)
+	}
+	for _, sessionIDs := range s.tenantSessions {
+		slices.Sort(sessionIDs)
+	}
 }
@@ -1,8 +1,10 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -16,7 +18,7 @@ func TestStoreAppendGetList(t *testing.T) {
 		t.Fatalf("new event: %v", err)
 	}
 
-	if err := store.Append(event); err != nil {
+	if err := store.Append(context.Background(), event); err != nil {
 		t.Fatalf("append: %v", err)
 	}
 
@@ -50,10 +52,10 @@ func TestStoreRejectsDuplicateEventIDInSession(t *testing.T) {
 		t.Fatalf("new second event: %v", err)
 	}
 
-	if err := store.Append(first); err != nil {
+	if err := store.Append(context.Background(), first); err != nil {
 		t.Fatalf("append first: %v", err)
 	}
-	err = store.Append(second)
+	err = store.Append(context.Background(), second)
 	if !errors.Is(err, ErrDuplicateEventID) {
 		t.Fatalf("expected error %v, got %v", ErrDuplicateEventID, err)
 	}
@@ -61,7 +63,7 @@ func TestStoreRejectsDuplicateEventIDInSession(t *testing.T) {
 
 func TestStoreRejectsInvalidEvent(t *testing.T) {
 	store := NewStore()
-	err := store.Append(Event{})
+	err := store.Append(context.Background(), Event{})
 	if !errors.Is(err, errEventIDRequired) {
 		t.Fatalf("expected error %v, got %v", errEventIDRequired, err)
 	}
@@ -78,7 +80,7 @@ func TestStoreAllowsSameEventIDAcrossSessionsAndTenants(t *testing.T) {
 	}
 
 	for _, event := range cases {
-		if err := store.Append(event); err != nil {
+		if err := store.Append(context.Background(), event); err != nil {
 			t.Fatalf("append event %v: %v", event, err)
 		}
 	}
@@ -95,7 +97,7 @@ func TestStoreListBySessionOrdering(t *testing.T) {
 	}
 
 	for _, event := range events {
-		if err := store.Append(event); err != nil {
+		if err := store.Append(context.Background(), event); err != nil {
 			t.Fatalf("append %q: %v", event.EventID, err)
 		}
 	}
@@ -117,7 +119,7 @@ func TestStoreIsolationAndNotFound(t *testing.T) {
 	store := NewStore()
 	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
 	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)
-	if err := store.Append(event); err != nil {
+	if err := store.Append(context.Background(), event); err != nil {
 		t.Fatalf("append: %v", err)
 	}
 
@@ -144,7 +146,7 @@ func TestStoreListBySessionReturnsCopy(t *testing.T) {
 	store := NewStore()
 	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
 	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)
-	if err := store.Append(event); err != nil {
+	if err := store.Append(context.Background(), event); err != nil {
 		t.Fatalf("append: %v", err)
 	}
 
@@ -157,6 +159,33 @@ func TestStoreListBySessionReturnsCopy(t *testing.T) {
 	}
 }
 
+func TestStoreDoesNotAliasPayload(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 2, now)
+	event.Payload = &Payload{Text: "hi there", TokenIDs: []int{1, 2}}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	event.Payload.Text = "mutated"
+	event.Payload.TokenIDs[0] = 99
+
+	got, ok := store.Get("tenant_1", "session_1", "evt_1")
+	if !ok {
+		t.Fatalf("expected event to exist")
+	}
+	if got.Payload.Text != "hi there" || got.Payload.TokenIDs[0] != 1 {
+		t.Fatalf("expected stored payload to be unchanged, got %#v", got.Payload)
+	}
+
+	got.Payload.TokenIDs[1] = 99
+	list := store.ListBySession("tenant_1", "session_1")
+	if list[0].Payload.TokenIDs[1] != 2 {
+		t.Fatalf("expected returned payload to be a copy, got %#v", list[0].Payload)
+	}
+}
+
 func TestStoreAppendManyRejectsDuplicatesInBatch(t *testing.T) {
 	store := NewStore()
 	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
@@ -166,7 +195,7 @@ func TestStoreAppendManyRejectsDuplicatesInBatch(t *testing.T) {
 		mustEvent(t, "evt_1", "tenant_1", "session_1", 10, 20, now.Add(time.Second)),
 	}
 
-	err := store.AppendMany(events)
+	err := store.AppendMany(context.Background(), events)
 	if !errors.Is(err, ErrDuplicateEventID) {
 		t.Fatalf("expected error %v, got %v", ErrDuplicateEventID, err)
 	}
@@ -190,12 +219,12 @@ func TestStoreRetrieveByAnchorsAppliesBuffers(t *testing.T) {
 			(i+1)*10,
 			base.Add(time.Duration(i)*time.Second),
 		)
-		if err := store.Append(event); err != nil {
+		if err := store.Append(context.Background(), event); err != nil {
 			t.Fatalf("append %q: %v", event.EventID, err)
 		}
 	}
 
-	events, err := store.RetrieveByAnchors("tenant_1", "session_1", []string{"evt_3"}, 1, 1, 1)
+	events, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"evt_3"}, 1, 1, 1)
 	if err != nil {
 		t.Fatalf("retrieve: %v", err)
 	}
@@ -221,12 +250,12 @@ func TestStoreRetrieveByAnchorsHonorsTopKAndDeduplicates(t *testing.T) {
 			(i+1)*10,
 			base.Add(time.Duration(i)*time.Second),
 		)
-		if err := store.Append(event); err != nil {
+		if err := store.Append(context.Background(), event); err != nil {
 			t.Fatalf("append %q: %v", event.EventID, err)
 		}
 	}
 
-	events, err := store.RetrieveByAnchors("tenant_1", "session_1", []string{"evt_2", "evt_2", "evt_1"}, 1, 0, 0)
+	events, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"evt_2", "evt_2", "evt_1"}, 1, 0, 0)
 	if err != nil {
 		t.Fatalf("retrieve: %v", err)
 	}
@@ -239,17 +268,329 @@ func TestStoreRetrieveByAnchorsHonorsTopKAndDeduplicates(t *testing.T) {
 func TestStoreRetrieveByAnchorsValidation(t *testing.T) {
 	store := NewStore()
 
-	_, err := store.RetrieveByAnchors("tenant_1", "session_1", []string{"evt_1"}, 0, 0, 0)
+	_, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"evt_1"}, 0, 0, 0)
 	if !errors.Is(err, errRetrieveTopKNonPositive) {
 		t.Fatalf("expected error %v, got %v", errRetrieveTopKNonPositive, err)
 	}
 
-	_, err = store.RetrieveByAnchors("tenant_1", "session_1", []string{"evt_1"}, 1, -1, 0)
+	_, err = store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"evt_1"}, 1, -1, 0)
 	if !errors.Is(err, errRetrieveBufferNegative) {
 		t.Fatalf("expected error %v, got %v", errRetrieveBufferNegative, err)
 	}
 }
 
+func TestStoreRetrieveBySimilarityExpandsTopMatches(t *testing.T) {
+	store := NewStore()
+	base := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	vectors := [][]float32{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+		{0.9, 0.1, 0},
+		{0, 0.2, 0.8},
+	}
+	for i, vector := range vectors {
+		event := mustEvent(t, fmt.Sprintf("evt_%d", i+1), "tenant_1", "session_1", i*10, (i+1)*10, base)
+		event.Embeddings = [][]float32{vector}
+		if err := store.Append(context.Background(), event); err != nil {
+			t.Fatalf("append %q: %v", event.EventID, err)
+		}
+	}
+
+	events, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{0, 0, 2}, Metric: SimilarityCosine, TopK: 1, BufferBefore: 1})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(events) != 2 || events[0].EventID != "evt_2" || events[1].EventID != "evt_3" {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+
+	events, err = store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0, 0}, Metric: SimilarityCosine, TopK: 2})
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(events) != 2 || events[0].EventID != "evt_1" || events[1].EventID != "evt_4" {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+}
+
+func TestStoreRetrieveBySimilarityUsesBestVectorAndMetric(t *testing.T) {
+	store := NewStore()
+	base := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+
+	multi := mustEvent(t, "evt_multi", "tenant_1", "session_1", 0, 10, base)
+	multi.Embeddings = [][]float32{{0, 1}, {1, 0}}
+	long := mustEvent(t, "evt_long", "tenant_1", "session_1", 10, 20, base)
+	long.Embeddings = [][]float32{{10, 10}}
+	if err := store.AppendMany(context.Background(), []Event{multi, long}); err != nil {
+		t.Fatalf("append many: %v", err)
+	}
+
+	events, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 1})
+	if err != nil {
+		t.Fatalf("retrieve cosine: %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != "evt_multi" {
+		t.Fatalf("expected best vector to win under cosine, got %#v", events)
+	}
+
+	events, err = store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityDot, TopK: 1})
+	if err != nil {
+		t.Fatalf("retrieve dot: %v", err)
+	}
+	if len(events) != 1 || events[0].EventID != "evt_long" {
+		t.Fatalf("expected larger magnitude to win under dot, got %#v", events)
+	}
+}
+
+func TestStoreRetrieveBySimilarityUsesIndexForLargeSessions(t *testing.T) {
+	exact := NewStore()
+	indexed := NewStoreWithOptions(StoreOptions{Index: IndexOptions{MinVectors: 1}})
+	base := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+
+	rng := rand.New(rand.NewSource(5))
+	for i, vector := range randomVectors(rng, 300, 16) {
+		event := mustEvent(t, fmt.Sprintf("evt_%03d", i), "tenant_1", "session_1", i*10, (i+1)*10, base)
+		event.Embeddings = [][]float32{vector}
+		if err := exact.Append(context.Background(), event); err != nil {
+			t.Fatalf("append exact: %v", err)
+		}
+		if err := indexed.Append(context.Background(), event); err != nil {
+			t.Fatalf("append indexed: %v", err)
+		}
+	}
+
+	query := randomVectors(rng, 1, 16)[0]
+	want, err := exact.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: query, Metric: SimilarityCosine, TopK: 3, BufferBefore: 1, BufferAfter: 1})
+	if err != nil {
+		t.Fatalf("retrieve exact: %v", err)
+	}
+	got, err := indexed.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: query, Metric: SimilarityCosine, TopK: 3, BufferBefore: 1, BufferAfter: 1})
+	if err != nil {
+		t.Fatalf("retrieve indexed: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].EventID != want[i].EventID {
+			t.Fatalf("expected indexed retrieval %v, got %v", retrievedIDs(want), retrievedIDs(got))
+		}
+	}
+}
+
+func TestStoreRejectsMixedEmbeddingDimensionsInSession(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+
+	first := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)
+	first.Embeddings = [][]float32{{1, 0}}
+	if err := store.Append(context.Background(), first); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	second := mustEvent(t, "evt_2", "tenant_1", "session_1", 10, 20, now)
+	second.Embeddings = [][]float32{{1, 0, 0}}
+	if err := store.Append(context.Background(), second); !errors.Is(err, errSessionEmbeddingDimMismatch) {
+		t.Fatalf("expected error %v, got %v", errSessionEmbeddingDimMismatch, err)
+	}
+
+	other := mustEvent(t, "evt_2", "tenant_1", "session_2", 0, 10, now)
+	other.Embeddings = [][]float32{{1, 0, 0}}
+	if err := store.Append(context.Background(), other); err != nil {
+		t.Fatalf("expected other session to accept its own dimension, got %v", err)
+	}
+}
+
+func TestStoreRetrieveBySimilarityValidation(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 10, now)
+	event.Embeddings = [][]float32{{1, 0}}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if _, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0}, Metric: "euclid", TopK: 1}); !errors.Is(err, errUnknownSimilarityMetric) {
+		t.Fatalf("expected error %v, got %v", errUnknownSimilarityMetric, err)
+	}
+	if _, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{0, 0}, Metric: SimilarityCosine, TopK: 1}); !errors.Is(err, errEmbeddingZeroVector) {
+		t.Fatalf("expected error %v, got %v", errEmbeddingZeroVector, err)
+	}
+	if _, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0, 0}, Metric: SimilarityCosine, TopK: 1}); !errors.Is(err, errQueryEmbeddingDimMismatch) {
+		t.Fatalf("expected error %v, got %v", errQueryEmbeddingDimMismatch, err)
+	}
+	if _, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine}); !errors.Is(err, errRetrieveTopKNonPositive) {
+		t.Fatalf("expected error %v, got %v", errRetrieveTopKNonPositive, err)
+	}
+}
+
+func eventIDs(events []Event) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func retrievedIDs(events []RetrievedEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func TestStoreSegmentCarriesSurpriseHistoryAcrossCalls(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+	adaptive := &AdaptiveThreshold{Window: 4, Gamma: 1}
+
+	first := SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     0,
+		Surprise:       []float64{1, 1.1, 1, 1.2},
+		Adaptive:       adaptive,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "a",
+	}
+	firstSegmentation, err := store.Segment(context.Background(), first)
+	if err != nil {
+		t.Fatalf("segment first slice: %v", err)
+	}
+
+	second := SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     4,
+		Surprise:       []float64{1, 1.1, 1, 3, 1},
+		Adaptive:       adaptive,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "b",
+	}
+	segmentation, err := store.Segment(context.Background(), second)
+	if err != nil {
+		t.Fatalf("segment second slice: %v", err)
+	}
+	if len(segmentation.Boundaries) != 1 || segmentation.Boundaries[0] != 8 {
+		t.Fatalf("expected history to suppress the early bump, got boundaries %v", segmentation.Boundaries)
+	}
+	if got, want := len(store.ListBySession("tenant_1", "session_1")), len(firstSegmentation.Events)+len(segmentation.Events); got != want {
+		t.Fatalf("expected %d stored events, got %d", want, got)
+	}
+
+	// The same slice in a fresh session has no history to compare against.
+	second.SessionID = "session_2"
+	segmentation, err = store.Segment(context.Background(), second)
+	if err != nil {
+		t.Fatalf("segment fresh session: %v", err)
+	}
+	if len(segmentation.Boundaries) != 2 {
+		t.Fatalf("expected two boundaries without history, got %v", segmentation.Boundaries)
+	}
+}
+
+func TestStoreSegmentBoundsSurpriseHistory(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+
+	surprise := make([]float64, maxSurpriseHistory+10)
+	for i := range surprise {
+		surprise[i] = float64(i)
+	}
+	if _, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		Surprise:       surprise,
+		Threshold:      1,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	}); err != nil {
+		t.Fatalf("segment: %v", err)
+	}
+
+	history := store.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}].surpriseHistory
+	if len(history) != maxSurpriseHistory || history[0] != 10 {
+		t.Fatalf("expected the newest %d values, got %d starting at %v", maxSurpriseHistory, len(history), history[0])
+	}
+}
+
+func TestStoreSegmentRejectsDuplicateWithoutRecordingHistory(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+
+	if err := store.Append(context.Background(), mustEvent(t, "seg_0", "tenant_1", "session_1", 0, 1, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	_, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     1,
+		Surprise:       []float64{0.1, 0.2},
+		Threshold:      1,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	})
+	if !errors.Is(err, ErrDuplicateEventID) {
+		t.Fatalf("expected error %v, got %v", ErrDuplicateEventID, err)
+	}
+	if history := store.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}].surpriseHistory; len(history) != 0 {
+		t.Fatalf("expected rejected segmentation to leave history empty, got %v", history)
+	}
+}
+
+func TestStoreSegmentDetectsStaleState(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2026, 2, 8, 8, 0, 0, 0, time.UTC)
+	key := sessionKey{tenantID: "tenant_1", sessionID: "session_1"}
+	req := SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		Surprise:       []float64{0.1, 0.9},
+		Threshold:      0.5,
+		MinBoundaryGap: 1,
+		CreatedAt:      now,
+		EventIDPrefix:  "seg",
+	}
+
+	// A session created by a plain append carries no segmentation state.
+	missing := store.segmentStateLocked(key)
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 100, 101, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if !store.segmentStateCurrentLocked(key, missing) {
+		t.Fatalf("expected an empty state to stay current")
+	}
+
+	// A segmentation landing after the state was read makes it stale.
+	before := store.segmentStateLocked(key)
+	if _, err := store.Segment(context.Background(), req); err != nil {
+		t.Fatalf("segment: %v", err)
+	}
+	if store.segmentStateCurrentLocked(key, before) {
+		t.Fatalf("expected the state to be stale after another segmentation")
+	}
+	after := store.segmentStateLocked(key)
+	if !store.segmentStateCurrentLocked(key, after) || len(after.history) != 2 {
+		t.Fatalf("expected a fresh state holding the history, got %+v", after)
+	}
+
+	// Deleting and recreating the session does not bring old history back.
+	if _, err := store.DeleteSession(context.Background(), "tenant_1", "session_1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if store.segmentStateCurrentLocked(key, after) {
+		t.Fatalf("expected the state of a deleted session to be stale")
+	}
+}
+
 func mustEvent(t *testing.T, eventID, tenantID, sessionID string, startToken, endTokenExclusive int, createdAt time.Time) Event {
 	t.Helper()
 
@@ -260,3 +601,15 @@ func mustEvent(t *testing.T, eventID, tenantID, sessionID string, startToken, en
 
 	return event
 }
+
+// mustStore returns a store holding events.
+func mustStore(t *testing.T, events ...Event) *InMemoryStore {
+	t.Helper()
+
+	store := NewStore()
+	if err := store.AppendMany(context.Background(), events); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	return store
+}
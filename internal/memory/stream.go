@@ -0,0 +1,166 @@
+package memory
+
+import "fmt"
+
+var (
+	errStreamNotContiguous   = invalidInput("start_token must continue the pending stream tail")
+	errStreamPending         = invalidInput("session has a pending stream tail; continue or flush the stream first")
+	errStreamRefinement      = invalidInput("refinement is not supported in stream mode")
+	errStreamTailTooLong     = invalidInput("pending stream tail would exceed %d tokens; flush the stream", maxSurpriseHistory)
+	errFlushRequiresStream   = invalidInput("flush requires stream mode")
+	errStreamSurpriseMissing = invalidInput("surprise must contain at least one value unless flushing")
+)
+
+// pendingSegment is the open tail of a streamed session: tokens after the
+// last confirmed boundary whose event cannot be closed yet. It is persisted
+// with the session so a stream survives restarts.
+type pendingSegment struct {
+	StartToken int       `json:"start_token"`
+	Surprise   []float64 `json:"surprise"`
+	// AfterBoundary reports whether StartToken is a confirmed boundary, so
+	// later boundaries must keep the minimum gap from it.
+	AfterBoundary bool `json:"after_boundary,omitempty"`
+}
+
+func (p *pendingSegment) endToken() int {
+	return p.StartToken + len(p.Surprise)
+}
+
+func (p *pendingSegment) clone() *pendingSegment {
+	if p == nil {
+		return nil
+	}
+	cloned := *p
+	cloned.Surprise = append([]float64(nil), p.Surprise...)
+	return &cloned
+}
+
+// PendingTail is the token range a streaming segmentation left open.
+type PendingTail struct {
+	StartToken        int `json:"start_token"`
+	EndTokenExclusive int `json:"end_token_exclusive"`
+}
+
+// buildStreamSegmentation continues pending with req. Boundaries are detected
+// over the pending surprise plus the new values, so a peak on the last token
+// of one call is judged once the next call supplies its right neighbour. A
+// boundary is only final once no later peak within MinBoundaryGap can replace
+// it; everything after the last final boundary becomes the new pending tail.
+// Flush finalizes every detected boundary and closes the tail as one event.
+// history is the session's surprise history, which already ends with the
+// pending values.
+func buildStreamSegmentation(req SegmentRequest, pending *pendingSegment, history []float64) (Segmentation, *pendingSegment, error) {
+	if req.StartToken < 0 {
+		return Segmentation{}, nil, errSegmentStartTokenNegative
+	}
+	if req.EventIDPrefix == "" {
+		return Segmentation{}, nil, errSegmentEventIDPrefixMissing
+	}
+	if req.Refinement != nil {
+		return Segmentation{}, nil, errStreamRefinement
+	}
+	if len(req.Surprise) == 0 && !req.Flush {
+		return Segmentation{}, nil, errStreamSurpriseMissing
+	}
+	if req.MinBoundaryGap <= 0 {
+		return Segmentation{}, nil, errInvalidMinBoundaryGap
+	}
+	if req.Adaptive != nil {
+		if err := req.Adaptive.validate(); err != nil {
+			return Segmentation{}, nil, err
+		}
+	} else if req.Threshold < 0 {
+		return Segmentation{}, nil, errNegativeSurpriseThreshold
+	}
+
+	empty := Segmentation{Events: []Event{}, RawBoundaries: []int{}, Boundaries: []int{}, Thresholds: []float64{}}
+	if pending == nil && len(req.Surprise) == 0 {
+		return empty, nil, nil
+	}
+
+	start := req.StartToken
+	series := req.Surprise
+	minBoundary := 0
+	if pending != nil {
+		if len(req.Surprise) > 0 && req.StartToken != pending.endToken() {
+			return Segmentation{}, nil, fmt.Errorf("%w at token %d", errStreamNotContiguous, pending.endToken())
+		}
+		start = pending.StartToken
+		series = make([]float64, 0, len(pending.Surprise)+len(req.Surprise))
+		series = append(series, pending.Surprise...)
+		series = append(series, req.Surprise...)
+		history = history[:max(0, len(history)-len(pending.Surprise))]
+		if pending.AfterBoundary {
+			minBoundary = req.MinBoundaryGap
+		}
+	}
+
+	thresholdAt := func(int) float64 { return req.Threshold }
+	if req.Adaptive != nil {
+		thresholds := adaptiveThresholds(series, history, *req.Adaptive)
+		thresholdAt = func(i int) float64 { return thresholds[i] }
+	}
+	relative, thresholds := detectBoundaries(series, req.MinBoundaryGap, minBoundary, thresholdAt)
+
+	confirmed := len(relative)
+	if !req.Flush {
+		for confirmed > 0 && len(series)-relative[confirmed-1] < req.MinBoundaryGap {
+			confirmed--
+		}
+	}
+	relative = relative[:confirmed]
+	thresholds = thresholds[:confirmed]
+
+	closedTokens := len(series)
+	if !req.Flush {
+		closedTokens = 0
+		if confirmed > 0 {
+			closedTokens = relative[confirmed-1]
+		}
+	}
+
+	var next *pendingSegment
+	if closedTokens < len(series) {
+		next = &pendingSegment{
+			StartToken:    start + closedTokens,
+			Surprise:      append([]float64(nil), series[closedTokens:]...),
+			AfterBoundary: confirmed > 0 || (pending != nil && pending.AfterBoundary),
+		}
+		if len(next.Surprise) > maxSurpriseHistory {
+			return Segmentation{}, nil, errStreamTailTooLong
+		}
+	}
+
+	segmentation := empty
+	if closedTokens > 0 {
+		// The last confirmed boundary closes the final event instead of
+		// opening one, unless the stream is being flushed.
+		cuts := relative
+		if !req.Flush {
+			cuts = relative[:confirmed-1]
+		}
+		events, _, err := buildEventsFromBoundaries(
+			req.TenantID,
+			req.SessionID,
+			start,
+			closedTokens,
+			cuts,
+			req.CreatedAt,
+			req.EventIDPrefix,
+		)
+		if err != nil {
+			return Segmentation{}, nil, err
+		}
+		segmentation.Events = events
+	}
+	for i, boundary := range relative {
+		segmentation.Boundaries = append(segmentation.Boundaries, start+boundary)
+		segmentation.Thresholds = append(segmentation.Thresholds, thresholds[i])
+	}
+	segmentation.RawBoundaries = segmentation.Boundaries
+	if next != nil {
+		segmentation.Pending = &PendingTail{StartToken: next.StartToken, EndTokenExclusive: next.endToken()}
+	}
+
+	return segmentation, next, nil
+}
@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func streamRequest(startToken int, surprise []float64, prefix string) SegmentRequest {
+	return SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		StartToken:     startToken,
+		Surprise:       surprise,
+		Threshold:      0.8,
+		MinBoundaryGap: 1,
+		CreatedAt:      time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC),
+		EventIDPrefix:  prefix,
+		Stream:         true,
+	}
+}
+
+func tokenRanges(events []Event) [][2]int {
+	ranges := make([][2]int, len(events))
+	for i, event := range events {
+		ranges[i] = [2]int{event.StartToken, event.EndTokenExclusive}
+	}
+	return ranges
+}
+
+func TestStreamSegmentationMatchesOneShotAcrossChunkEdges(t *testing.T) {
+	t.Parallel()
+
+	surprise := []float64{0.1, 0.2, 1.2, 0.1, 0.15, 1.5, 0.2, 0.1}
+	oneShot, _, err := BuildEventsFromSurprise("tenant_1", "session_1", 100, surprise, 0.8, 1, time.Now(), "seg")
+	if err != nil {
+		t.Fatalf("build one-shot events: %v", err)
+	}
+
+	// Each chunk ends on a surprise peak, which can only be confirmed once
+	// the next chunk supplies its right neighbour.
+	store := NewStore()
+	chunks := [][]float64{surprise[0:3], surprise[3:6], surprise[6:]}
+	startToken := 100
+	for i, chunk := range chunks {
+		segmentation, err := store.Segment(context.Background(), streamRequest(startToken, chunk, fmt.Sprintf("chunk%d", i)))
+		if err != nil {
+			t.Fatalf("segment chunk %d: %v", i, err)
+		}
+		if i == 0 && len(segmentation.Events) != 0 {
+			t.Fatalf("expected no events before the first peak is confirmed, got %#v", segmentation.Events)
+		}
+		if segmentation.Pending == nil {
+			t.Fatalf("expected an open tail after chunk %d", i)
+		}
+		startToken += len(chunk)
+	}
+
+	flushed, err := store.Segment(context.Background(), SegmentRequest{
+		TenantID:       "tenant_1",
+		SessionID:      "session_1",
+		MinBoundaryGap: 1,
+		CreatedAt:      time.Date(2026, 2, 14, 12, 5, 0, 0, time.UTC),
+		EventIDPrefix:  "flush",
+		Stream:         true,
+		Flush:          true,
+	})
+	if err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if flushed.Pending != nil || len(flushed.Events) != 1 {
+		t.Fatalf("expected flush to close the tail as one event, got %#v", flushed)
+	}
+
+	got := tokenRanges(store.ListBySession("tenant_1", "session_1"))
+	want := tokenRanges(oneShot)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected streamed events %v, got %v", want, got)
+	}
+}
+
+func TestStreamSegmentationWaitsForMinimumGap(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	first := streamRequest(0, []float64{0.1, 1.0, 0.2}, "a")
+	first.MinBoundaryGap = 3
+	segmentation, err := store.Segment(context.Background(), first)
+	if err != nil {
+		t.Fatalf("segment first chunk: %v", err)
+	}
+	if len(segmentation.Boundaries) != 0 {
+		t.Fatalf("expected the peak to stay unconfirmed within the gap, got %v", segmentation.Boundaries)
+	}
+
+	// A stronger peak inside the gap replaces the earlier one, as it would
+	// in a single call.
+	second := streamRequest(3, []float64{1.5, 0.1, 0.1, 0.1}, "b")
+	second.MinBoundaryGap = 3
+	segmentation, err = store.Segment(context.Background(), second)
+	if err != nil {
+		t.Fatalf("segment second chunk: %v", err)
+	}
+	if !reflect.DeepEqual(segmentation.Boundaries, []int{4}) {
+		t.Fatalf("expected boundaries %v, got %v", []int{4}, segmentation.Boundaries)
+	}
+	if segmentation.Pending == nil || segmentation.Pending.StartToken != 4 || segmentation.Pending.EndTokenExclusive != 7 {
+		t.Fatalf("unexpected pending tail: %#v", segmentation.Pending)
+	}
+}
+
+func TestStreamSegmentationKeepsGapFromConfirmedBoundary(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	first := streamRequest(0, []float64{0.1, 1.5, 0.1, 0.9, 0.1}, "a")
+	first.MinBoundaryGap = 3
+	segmentation, err := store.Segment(context.Background(), first)
+	if err != nil {
+		t.Fatalf("segment first chunk: %v", err)
+	}
+	if !reflect.DeepEqual(segmentation.Boundaries, []int{2}) {
+		t.Fatalf("expected boundaries %v, got %v", []int{2}, segmentation.Boundaries)
+	}
+
+	// The weaker peak at token 3 was already rejected for being within the
+	// gap of boundary 2 and must not resurface from the pending tail.
+	second := streamRequest(5, []float64{0.1, 0.1}, "b")
+	second.MinBoundaryGap = 3
+	segmentation, err = store.Segment(context.Background(), second)
+	if err != nil {
+		t.Fatalf("segment second chunk: %v", err)
+	}
+	if len(segmentation.Boundaries) != 0 || len(segmentation.Events) != 0 {
+		t.Fatalf("expected no new boundaries, got %#v", segmentation)
+	}
+}
+
+func TestStreamSegmentationRejectsInvalidUse(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	if _, err := store.Segment(context.Background(), streamRequest(0, []float64{0.1, 0.2}, "a")); err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+
+	notContiguous := streamRequest(5, []float64{0.1}, "b")
+	notStream := streamRequest(2, []float64{0.1}, "c")
+	notStream.Stream = false
+	refined := streamRequest(2, []float64{0.1}, "d")
+	refined.Refinement = &Refinement{Objective: RefinementModularity}
+	flushOnly := streamRequest(2, nil, "e")
+	flushOnly.Stream = false
+	flushOnly.Flush = true
+
+	cases := []struct {
+		name string
+		req  SegmentRequest
+		err  error
+	}{
+		{name: "not contiguous", req: notContiguous, err: errStreamNotContiguous},
+		{name: "non-stream call on open stream", req: notStream, err: errStreamPending},
+		{name: "refinement", req: refined, err: errStreamRefinement},
+		{name: "flush without stream", req: flushOnly, err: errFlushRequiresStream},
+		{name: "empty chunk", req: streamRequest(2, nil, "f"), err: errStreamSurpriseMissing},
+	}
+
+	for _, tc := range cases {
+		if _, err := store.Segment(context.Background(), tc.req); !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestStreamSegmentationBoundsPendingTail(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore()
+	flat := make([]float64, maxSurpriseHistory+1)
+	for i := range flat {
+		flat[i] = 0.1
+	}
+
+	_, err := store.Segment(context.Background(), streamRequest(0, flat, "a"))
+	if !errors.Is(err, errStreamTailTooLong) {
+		t.Fatalf("expected error %v, got %v", errStreamTailTooLong, err)
+	}
+}
@@ -0,0 +1,89 @@
+package memory
+
+import "math"
+
+// SurpriseNormalization selects how per-token surprise is derived from log-probabilities.
+type SurpriseNormalization string
+
+const (
+	// SurpriseNLL uses the negative log-likelihood of the sampled token.
+	SurpriseNLL SurpriseNormalization = "none"
+	// SurpriseEntropy subtracts the entropy of the top-k distribution from the
+	// negative log-likelihood, so a token only scores high when it was more
+	// surprising than the model expected at that position.
+	SurpriseEntropy SurpriseNormalization = "entropy"
+)
+
+var (
+	errLogprobsRequired             = invalidInput("logprobs must contain at least one token")
+	errLogprobInvalid               = invalidInput("logprob values must be finite and not greater than 0")
+	errTopLogprobsRequired          = invalidInput("entropy normalization requires top_logprobs for every token")
+	errUnknownSurpriseNormalization = invalidInput("surprise normalization must be one of: none, entropy")
+)
+
+// TokenLogprob is the log-probability (natural log) a model assigned to one
+// sampled token, with optional log-probabilities of its top-k alternatives.
+type TokenLogprob struct {
+	Logprob     float64
+	TopLogprobs []float64
+}
+
+// SurpriseFromLogprobs converts per-token log-probabilities into surprise
+// scores in nats, one per token, ready for DetectBoundaries.
+func SurpriseFromLogprobs(tokens []TokenLogprob, normalization SurpriseNormalization) ([]float64, error) {
+	switch normalization {
+	case SurpriseNLL, SurpriseEntropy:
+	default:
+		return nil, errUnknownSurpriseNormalization
+	}
+	if len(tokens) == 0 {
+		return nil, errLogprobsRequired
+	}
+
+	surprise := make([]float64, len(tokens))
+	for i, token := range tokens {
+		if !validLogprob(token.Logprob) {
+			return nil, errLogprobInvalid
+		}
+		for _, logprob := range token.TopLogprobs {
+			if !validLogprob(logprob) {
+				return nil, errLogprobInvalid
+			}
+		}
+
+		surprise[i] = -token.Logprob
+		if normalization == SurpriseEntropy {
+			if len(token.TopLogprobs) == 0 {
+				return nil, errTopLogprobsRequired
+			}
+			surprise[i] -= topKEntropy(token.TopLogprobs)
+		}
+	}
+
+	return surprise, nil
+}
+
+func validLogprob(logprob float64) bool {
+	return logprob <= 0 && !math.IsNaN(logprob) && !math.IsInf(logprob, 0)
+}
+
+// topKEntropy is the entropy in nats of the top-k alternatives renormalized
+// to a distribution. Log-sum-exp keeps very negative log-probabilities stable.
+func topKEntropy(logprobs []float64) float64 {
+	maxLogprob := math.Inf(-1)
+	for _, logprob := range logprobs {
+		maxLogprob = max(maxLogprob, logprob)
+	}
+	var sum float64
+	for _, logprob := range logprobs {
+		sum += math.Exp(logprob - maxLogprob)
+	}
+	logNormalizer := maxLogprob + math.Log(sum)
+
+	var entropy float64
+	for _, logprob := range logprobs {
+		normalized := logprob - logNormalizer
+		entropy -= math.Exp(normalized) * normalized
+	}
+	return entropy
+}
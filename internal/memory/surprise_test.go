@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSurpriseFromLogprobsNegativeLogLikelihood(t *testing.T) {
+	t.Parallel()
+
+	got, err := SurpriseFromLogprobs([]TokenLogprob{
+		{Logprob: 0},
+		{Logprob: math.Log(0.25)},
+	}, SurpriseNLL)
+	if err != nil {
+		t.Fatalf("surprise from logprobs: %v", err)
+	}
+
+	want := []float64{0, math.Log(4)}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Fatalf("expected surprise %v, got %v", want, got)
+		}
+	}
+}
+
+func TestSurpriseFromLogprobsEntropyNormalized(t *testing.T) {
+	t.Parallel()
+
+	half := math.Log(0.5)
+	got, err := SurpriseFromLogprobs([]TokenLogprob{
+		// Two equally likely alternatives: picking either is exactly as
+		// surprising as expected.
+		{Logprob: half, TopLogprobs: []float64{half, half}},
+		// A confident model that picked an unlikely token.
+		{Logprob: math.Log(0.01), TopLogprobs: []float64{math.Log(0.98), math.Log(0.01), math.Log(0.01)}},
+	}, SurpriseEntropy)
+	if err != nil {
+		t.Fatalf("surprise from logprobs: %v", err)
+	}
+
+	if math.Abs(got[0]) > 1e-12 {
+		t.Fatalf("expected zero surprise for an expected pick, got %v", got[0])
+	}
+	entropy := -(0.98*math.Log(0.98) + 2*0.01*math.Log(0.01))
+	if want := -math.Log(0.01) - entropy; math.Abs(got[1]-want) > 1e-9 {
+		t.Fatalf("expected surprise %v, got %v", want, got[1])
+	}
+}
+
+func TestSurpriseFromLogprobsValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		tokens        []TokenLogprob
+		normalization SurpriseNormalization
+		err           error
+	}{
+		{name: "empty", normalization: SurpriseNLL, err: errLogprobsRequired},
+		{name: "unknown normalization", tokens: []TokenLogprob{{Logprob: -1}}, normalization: "zscore", err: errUnknownSurpriseNormalization},
+		{name: "positive logprob", tokens: []TokenLogprob{{Logprob: 0.5}}, normalization: SurpriseNLL, err: errLogprobInvalid},
+		{name: "nan logprob", tokens: []TokenLogprob{{Logprob: math.NaN()}}, normalization: SurpriseNLL, err: errLogprobInvalid},
+		{name: "invalid alternative", tokens: []TokenLogprob{{Logprob: -1, TopLogprobs: []float64{math.Inf(-1)}}}, normalization: SurpriseNLL, err: errLogprobInvalid},
+		{name: "entropy without alternatives", tokens: []TokenLogprob{{Logprob: -1}}, normalization: SurpriseEntropy, err: errTopLogprobsRequired},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := SurpriseFromLogprobs(tc.tokens, tc.normalization)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer reports store spans through the global OpenTelemetry provider, which
+// is a no-op until the binary installs one.
+var tracer = otel.Tracer("memplane/internal/memory")
+
+// lockAcquired marks when the store lock was taken, so lock contention shows
+// up as the gap between the span start and this event.
+func lockAcquired(span trace.Span) {
+	span.AddEvent("store lock acquired")
+}
+
+// endSpan records err, if any, and ends span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrEventNotFound reports an update to an event that is not stored.
+	ErrEventNotFound = errors.New("event not found")
+	// ErrVersionConflict reports an update whose expected version is no
+	// longer the stored one, because another writer got there first.
+	ErrVersionConflict = errors.New("event version conflict")
+)
+
+// EventPatch replaces the mutable fields of a stored event. Nil fields are
+// left as they are; an empty value clears the field. Token spans, CreatedAt
+// and ids are immutable.
+type EventPatch struct {
+	Payload    *Payload
+	Metadata   *map[string]string
+	Embeddings *[][]float32
+	Tags       *[]string
+}
+
+func (p Payload) isEmpty() bool {
+	return p.Text == "" && len(p.TokenIDs) == 0 && p.Role == "" && p.Speaker == ""
+}
+
+func (e Event) withPatch(patch EventPatch) Event {
+	e = e.clone()
+	if patch.Payload != nil {
+		e.Payload = nil
+		if !patch.Payload.isEmpty() {
+			payload := *patch.Payload
+			e.Payload = &payload
+		}
+	}
+	if patch.Metadata != nil {
+		e.Metadata = nil
+		if len(*patch.Metadata) > 0 {
+			e.Metadata = *patch.Metadata
+		}
+	}
+	if patch.Embeddings != nil {
+		e.Embeddings = nil
+		if len(*patch.Embeddings) > 0 {
+			e.Embeddings = *patch.Embeddings
+		}
+	}
+	if patch.Tags != nil {
+		e.Tags = nil
+		if len(*patch.Tags) > 0 {
+			e.Tags = *patch.Tags
+		}
+	}
+	// Stored events must not alias the caller's patch.
+	return e.clone()
+}
+
+func (s *InMemoryStore) UpdateEvent(
+	ctx context.Context,
+	tenantID, sessionID, eventID string,
+	patch EventPatch,
+	ifVersion int64,
+) (Event, error) {
+	return s.updateEvent(ctx, tenantID, sessionID, eventID, patch, ifVersion, nil)
+}
+
+// updateEvent applies patch to one event. A non-zero ifVersion must match the
+// stored version. Like appendMany, commit receives the updated event once it
+// is validated and before any state changes.
+func (s *InMemoryStore) updateEvent(
+	ctx context.Context,
+	tenantID, sessionID, eventID string,
+	patch EventPatch,
+	ifVersion int64,
+	commit func(Event) error,
+) (_ Event, err error) {
+	_, span := tracer.Start(ctx, "memory.UpdateEvent")
+	defer func() { endSpan(span, err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lockAcquired(span)
+
+	key := sessionKey{tenantID: tenantID, sessionID: sessionID}
+	session, ok := s.sessions[key]
+	if !ok {
+		return Event{}, ErrEventNotFound
+	}
+	current, ok := session.byID[eventID]
+	if !ok {
+		return Event{}, ErrEventNotFound
+	}
+	if ifVersion != 0 && ifVersion != current.Version {
+		return Event{}, fmt.Errorf("%w: event is at version %d", ErrVersionConflict, current.Version)
+	}
+
+	updated := current.withPatch(patch)
+	updated.Version = current.Version + 1
+	span.SetAttributes(attribute.Int64("version", updated.Version))
+	if err := validateEvent(updated); err != nil {
+		return Event{}, err
+	}
+	if err := s.checkUpdateLocked(session, current, updated); err != nil {
+		return Event{}, err
+	}
+
+	if commit != nil {
+		if err := commit(updated); err != nil {
+			return Event{}, err
+		}
+		span.AddEvent("log committed")
+	}
+
+	s.applyUpdateLocked(key, session, current, updated)
+	return updated.clone(), nil
+}
+
+// checkUpdateLocked reports whether replacing current with updated keeps the
+// session's embedding dimension and the tenant's payload quota. The caller
+// must hold s.mu.
+func (s *InMemoryStore) checkUpdateLocked(session *sessionEvents, current, updated Event) error {
+	if dim := updated.embeddingDim(); dim != 0 && dim != session.embeddingDim {
+		// The dimension may only change when no other event holds vectors.
+		if session.index != nil && session.index.len() > len(current.Embeddings) {
+			return errSessionEmbeddingDimMismatch
+		}
+	}
+
+	quota := s.opts.Quotas.forTenant(current.TenantID)
+	if quota.MaxPayloadBytes == 0 {
+		return nil
+	}
+	usage := s.tenantUsage(current.TenantID)
+	growth := updated.payloadBytes() - current.payloadBytes()
+	if growth > 0 && usage.payloadBytes+growth > quota.MaxPayloadBytes {
+		return fmt.Errorf("%w: at most %d payload bytes per tenant", ErrQuotaExceeded, quota.MaxPayloadBytes)
+	}
+	return nil
+}
+
+// applyUpdateLocked replaces a stored event in place; its position in session
+// order cannot change since spans, CreatedAt and ids are immutable. The
+// caller must hold s.mu.
+func (s *InMemoryStore) applyUpdateLocked(key sessionKey, session *sessionEvents, current, updated Event) {
+	if i, ok := session.orderedIndex(current.EventID); ok {
+		session.ordered[i] = updated
+	}
+	session.byID[updated.EventID] = updated
+	usage := s.tenantUsage(key.tenantID)
+	usage.payloadBytes += updated.payloadBytes() - current.payloadBytes()
+	session.touch(s.now())
+	if eventText(updated) != eventText(current) {
+		s.unindexTextLocked(current)
+		s.indexTextLocked(updated)
+	}
+
+	// Payload, metadata and tag patches leave the graph as it is.
+	if !slices.EqualFunc(current.Embeddings, updated.Embeddings, slices.Equal[[]float32]) {
+		s.unindexEventLocked(session, current)
+		if dim := updated.embeddingDim(); dim != 0 {
+			session.embeddingDim = dim
+			s.indexEvent(session, updated)
+		}
+		s.compactIndexLocked(session)
+	}
+}
+
+// replayUpdate installs an updated event from the log. Quotas and versions
+// were checked when it was logged.
+func (s *InMemoryStore) replayUpdate(updated Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sessionKey{tenantID: updated.TenantID, sessionID: updated.SessionID}
+	session, ok := s.sessions[key]
+	if !ok {
+		return fmt.Errorf("%w: update for unknown event %q", ErrCorruptLog, updated.EventID)
+	}
+	current, ok := session.byID[updated.EventID]
+	if !ok {
+		return fmt.Errorf("%w: update for unknown event %q", ErrCorruptLog, updated.EventID)
+	}
+	s.applyUpdateLocked(key, session, current, updated)
+	return nil
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateEventReplacesMutableFields(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 2, now)
+	event.Payload = &Payload{Text: "hi", Role: "user"}
+	event.Tags = []string{"draft"}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if got, _ := store.Get("tenant_1", "session_1", "evt_1"); got.Version != FirstEventVersion {
+		t.Fatalf("expected version %d, got %d", FirstEventVersion, got.Version)
+	}
+
+	metadata := map[string]string{"source": "import"}
+	tags := []string{}
+	updated, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{
+		Metadata: &metadata,
+		Tags:     &tags,
+	}, FirstEventVersion)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Version != 2 || updated.Metadata["source"] != "import" || updated.Tags != nil || updated.Payload.Text != "hi" {
+		t.Fatalf("unexpected updated event: %+v", updated)
+	}
+
+	// The stored event must not alias the caller's patch.
+	metadata["source"] = "changed"
+	if got, _ := store.Get("tenant_1", "session_1", "evt_1"); got.Metadata["source"] != "import" || got.Version != 2 {
+		t.Fatalf("unexpected stored event: %+v", got)
+	}
+	if got := store.Stats().Tenants["tenant_1"].PayloadBytes; got != int64(len("hi")+len("user")+len("source")+len("import")) {
+		t.Fatalf("expected usage to follow the update, got %d", got)
+	}
+}
+
+func TestUpdateEventRejectsConflictsAndInvalidEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithOptions(StoreOptions{Quotas: QuotaPolicy{Default: Quota{MaxPayloadBytes: 16}}})
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 2, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	tags := []string{"a"}
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_2", EventPatch{Tags: &tags}, 0); !errors.Is(err, ErrEventNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrEventNotFound, err)
+	}
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{Tags: &tags}, 0); err != nil {
+		t.Fatalf("unconditional update: %v", err)
+	}
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{Tags: &tags}, FirstEventVersion); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected error %v, got %v", ErrVersionConflict, err)
+	}
+
+	for name, patch := range map[string]EventPatch{
+		"token ids outside span": {Payload: &Payload{TokenIDs: []int{1, 2, 3}}},
+		"duplicate tags":         {Tags: &[]string{"a", "a"}},
+		"over quota":             {Payload: &Payload{Text: "more than sixteen bytes"}},
+	} {
+		if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", patch, 0); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	if got, _ := store.Get("tenant_1", "session_1", "evt_1"); got.Version != 2 {
+		t.Fatalf("expected rejected updates to leave version 2, got %d", got.Version)
+	}
+}
+
+func TestUpdateEventReindexesEmbeddings(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	first := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now)
+	first.Embeddings = [][]float32{{1, 0}}
+	if err := store.AppendMany(context.Background(), []Event{first, mustEvent(t, "evt_2", "tenant_1", "session_1", 1, 2, now)}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	// A vector computed later makes the event retrievable.
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_2", EventPatch{Embeddings: &[][]float32{{0, 1}}}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	events, err := store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{0, 1}, Metric: SimilarityCosine, TopK: 1})
+	if err != nil || len(events) != 1 || events[0].EventID != "evt_2" {
+		t.Fatalf("expected evt_2, got %v, %v", retrievedIDs(events), err)
+	}
+
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_2", EventPatch{Embeddings: &[][]float32{{0, 1, 0}}}, 0); !errors.Is(err, errSessionEmbeddingDimMismatch) {
+		t.Fatalf("expected error %v, got %v", errSessionEmbeddingDimMismatch, err)
+	}
+
+	// Replacing a vector drops the old one from the index.
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{Embeddings: &[][]float32{}}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	events, err = store.RetrieveBySimilarity(context.Background(), SimilarityQuery{TenantID: "tenant_1", SessionID: "session_1", QueryEmbedding: []float32{1, 0}, Metric: SimilarityCosine, TopK: 2})
+	if err != nil || len(events) != 1 || events[0].EventID != "evt_2" {
+		t.Fatalf("expected only evt_2 to stay indexed, got %v, %v", retrievedIDs(events), err)
+	}
+}
+
+func TestUpdateEventKeepsIndexWhenEmbeddingsUnchanged(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore()
+	event := mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 1, now)
+	event.Embeddings = [][]float32{{1, 0}}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	session := store.sessions[sessionKey{tenantID: "tenant_1", sessionID: "session_1"}]
+	index := session.index
+
+	metadata := map[string]string{"source": "import"}
+	same := [][]float32{{1, 0}}
+	for _, patch := range []EventPatch{
+		{Metadata: &metadata},
+		{Tags: &[]string{"reviewed"}},
+		{Payload: &Payload{Text: "hi"}},
+		{Embeddings: &same},
+	} {
+		if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", patch, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if session.index != index || index.len() != 1 {
+			t.Fatalf("expected patch %+v to leave the index alone", patch)
+		}
+	}
+}
+
+func TestDurableStoreReplaysUpdates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store := mustOpenDurableStore(t, dir)
+	if err := store.Append(context.Background(), mustEvent(t, "evt_1", "tenant_1", "session_1", 0, 2, now)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{Payload: &Payload{Text: "fixed"}}, FirstEventVersion); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := store.UpdateEvent(context.Background(), "tenant_1", "session_1", "evt_1", EventPatch{Payload: &Payload{Text: "stale"}}, FirstEventVersion); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected error %v, got %v", ErrVersionConflict, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	reopened := mustOpenDurableStore(t, dir)
+	defer reopened.Close()
+	got, ok := reopened.Get("tenant_1", "session_1", "evt_1")
+	if !ok || got.Version != 2 || got.Payload == nil || got.Payload.Text != "fixed" {
+		t.Fatalf("expected the update to be replayed, got %+v", got)
+	}
+}
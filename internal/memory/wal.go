@@ -0,0 +1,382 @@
+package memory
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCorruptLog reports a write-ahead log record that fails its checksum
+// somewhere other than the tail of the log, or a gap in the record sequence.
+var ErrCorruptLog = errors.New("write-ahead log is corrupt")
+
+var errLogClosed = errors.New("write-ahead log is closed")
+
+const (
+	walOpAppend        = "append"
+	walOpSegment       = "segment"
+	walOpDeleteEvent   = "delete_event"
+	walOpDeleteSession = "delete_session"
+	walOpPurgeTenant   = "purge_tenant"
+	walOpExpire        = "expire"
+	walOpSetRetention  = "set_retention"
+	walOpUpdateEvent   = "update_event"
+	walOpSetSpanPolicy = "set_span_policy"
+	walOpSetLabels     = "set_labels"
+
+	walHeaderSize = 8
+	// Upper bound for a single framed record; guards replay against garbage lengths.
+	maxWALRecordBytes = 64 << 20
+
+	walSegmentPrefix = "wal-"
+	walSegmentSuffix = ".log"
+)
+
+var walChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walRecord is one durable mutation. Every accepted batch is exactly one record,
+// so replay either sees the whole batch or none of it.
+type walRecord struct {
+	Seq    uint64  `json:"seq"`
+	Op     string  `json:"op"`
+	Events []Event `json:"events,omitempty"`
+	// TenantID, SessionID and Surprise record the surprise history a segment
+	// operation adds to its session, and Pending the stream tail it leaves.
+	TenantID  string          `json:"tenant_id,omitempty"`
+	SessionID string          `json:"session_id,omitempty"`
+	Surprise  []float64       `json:"surprise,omitempty"`
+	Pending   *pendingSegment `json:"pending,omitempty"`
+	// EventID names the event a delete_event tombstone removes. Delete
+	// operations carry no event data, only the ids of what they remove.
+	EventID string `json:"event_id,omitempty"`
+	// Expired lists what an expire operation removed, so replay does not
+	// depend on the clock or the policy in force.
+	Expired []expiredSession `json:"expired,omitempty"`
+	// Retention is the override a set_retention operation installs.
+	Retention *Retention `json:"retention,omitempty"`
+	// SpanPolicy is the override a set_span_policy operation installs.
+	SpanPolicy SpanPolicy `json:"span_policy,omitempty"`
+	// Labels are the labels a set_labels operation installs.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// writeAheadLog appends length-prefixed, checksummed JSON records to segment
+// files in dir and fsyncs after every record. Each segment is named after the
+// first sequence number it may contain, so segments fully covered by a
+// snapshot can be deleted without reading them.
+//
+// Frame layout: uint32 payload length | uint32 CRC-32C of payload | payload.
+type writeAheadLog struct {
+	dir     string
+	file    *os.File
+	size    int64
+	lastSeq uint64
+	broken  error
+}
+
+type walSegment struct {
+	firstSeq uint64
+	path     string
+}
+
+// openWriteAheadLog replays every record after afterSeq and opens the newest
+// segment for appending. A torn tail in the newest segment is truncated.
+func openWriteAheadLog(dir string, afterSeq uint64, apply func(walRecord) error) (*writeAheadLog, error) {
+	segments, err := listWALSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &writeAheadLog{dir: dir, lastSeq: afterSeq}
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if !last && segments[i+1].firstSeq <= afterSeq+1 {
+			// Every record in this segment is already covered by the snapshot.
+			continue
+		}
+		if segment.firstSeq > log.lastSeq+1 {
+			return nil, fmt.Errorf("%w: segment %s starts after sequence %d", ErrCorruptLog, filepath.Base(segment.path), log.lastSeq)
+		}
+
+		file, err := os.OpenFile(segment.path, os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open write-ahead log segment: %w", err)
+		}
+		size, err := log.replaySegment(file, last, apply)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		if !last {
+			if err := file.Close(); err != nil {
+				return nil, fmt.Errorf("close write-ahead log segment: %w", err)
+			}
+			continue
+		}
+
+		log.file = file
+		log.size = size
+	}
+
+	if log.file == nil {
+		if err := log.openSegment(log.lastSeq + 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return log, nil
+}
+
+// replaySegment applies records newer than lastSeq in order and returns the
+// offset of the end of the last intact record.
+func (l *writeAheadLog) replaySegment(file *os.File, allowTornTail bool, apply func(walRecord) error) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat write-ahead log: %w", err)
+	}
+	fileSize := info.Size()
+
+	var offset int64
+	header := make([]byte, walHeaderSize)
+	for offset < fileSize {
+		torn := false
+		if _, err := io.ReadFull(file, header); err != nil {
+			if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+				return 0, fmt.Errorf("read write-ahead log: %w", err)
+			}
+			torn = true
+		}
+
+		var record walRecord
+		recordEnd := fileSize
+		if !torn {
+			length := int64(binary.LittleEndian.Uint32(header[0:4]))
+			checksum := binary.LittleEndian.Uint32(header[4:8])
+			recordEnd = offset + walHeaderSize + length
+			if length > maxWALRecordBytes || recordEnd > fileSize {
+				// Partially written frame at the tail.
+				torn = true
+			} else {
+				payload := make([]byte, length)
+				if _, err := io.ReadFull(file, payload); err != nil {
+					return 0, fmt.Errorf("read write-ahead log: %w", err)
+				}
+				if crc32.Checksum(payload, walChecksumTable) != checksum || json.Unmarshal(payload, &record) != nil {
+					if recordEnd != fileSize {
+						return 0, fmt.Errorf("%w: invalid record at offset %d of %s", ErrCorruptLog, offset, file.Name())
+					}
+					torn = true
+				}
+			}
+		}
+
+		if torn {
+			if !allowTornTail {
+				return 0, fmt.Errorf("%w: truncated record at offset %d of %s", ErrCorruptLog, offset, file.Name())
+			}
+			break
+		}
+
+		if record.Seq > l.lastSeq {
+			if record.Seq != l.lastSeq+1 {
+				return 0, fmt.Errorf("%w: sequence %d follows %d", ErrCorruptLog, record.Seq, l.lastSeq)
+			}
+			if err := apply(record); err != nil {
+				return 0, fmt.Errorf("replay write-ahead log record %d: %w", record.Seq, err)
+			}
+			l.lastSeq = record.Seq
+		}
+		offset = recordEnd
+	}
+
+	if offset < fileSize {
+		if err := truncateFile(file, offset); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek write-ahead log: %w", err)
+	}
+
+	return offset, nil
+}
+
+// append durably writes the record, assigning it the next sequence number.
+func (l *writeAheadLog) append(record walRecord) error {
+	if l.file == nil {
+		return errLogClosed
+	}
+	if l.broken != nil {
+		return l.broken
+	}
+
+	record.Seq = l.lastSeq + 1
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode write-ahead log record: %w", err)
+	}
+	if len(payload) > maxWALRecordBytes {
+		return fmt.Errorf("write-ahead log record exceeds %d bytes", maxWALRecordBytes)
+	}
+
+	if _, err := l.file.Write(encodeFrame(payload)); err != nil {
+		l.rollback()
+		return fmt.Errorf("write write-ahead log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		l.rollback()
+		return fmt.Errorf("sync write-ahead log: %w", err)
+	}
+
+	l.size += walHeaderSize + int64(len(payload))
+	l.lastSeq = record.Seq
+	return nil
+}
+
+// rotate closes the active segment and starts a new one for records after lastSeq.
+func (l *writeAheadLog) rotate() error {
+	if l.file == nil {
+		return errLogClosed
+	}
+	if l.broken != nil {
+		return l.broken
+	}
+
+	if err := l.file.Close(); err != nil {
+		l.file = nil
+		l.broken = fmt.Errorf("close write-ahead log segment: %w", err)
+		return l.broken
+	}
+	l.file = nil
+
+	if err := l.openSegment(l.lastSeq + 1); err != nil {
+		l.broken = err
+		return err
+	}
+	return nil
+}
+
+// removeCoveredSegments deletes inactive segments whose records all have a
+// sequence number at or below seq.
+func (l *writeAheadLog) removeCoveredSegments(seq uint64) error {
+	segments, err := listWALSegments(l.dir)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i+1].firstSeq > seq+1 {
+			break
+		}
+		if err := os.Remove(segments[i].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove write-ahead log segment: %w", err)
+		}
+	}
+
+	return syncDir(l.dir)
+}
+
+func (l *writeAheadLog) openSegment(firstSeq uint64) error {
+	path := filepath.Join(l.dir, walSegmentName(firstSeq))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create write-ahead log segment: %w", err)
+	}
+	if err := syncDir(l.dir); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = 0
+	return nil
+}
+
+// rollback drops a partially written frame so later records do not follow garbage.
+// If that fails the log refuses further writes.
+func (l *writeAheadLog) rollback() {
+	if err := truncateFile(l.file, l.size); err != nil {
+		l.broken = err
+		return
+	}
+	if _, err := l.file.Seek(l.size, io.SeekStart); err != nil {
+		l.broken = fmt.Errorf("seek write-ahead log: %w", err)
+	}
+}
+
+func (l *writeAheadLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func encodeFrame(payload []byte) []byte {
+	frame := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, walChecksumTable))
+	copy(frame[walHeaderSize:], payload)
+	return frame
+}
+
+func walSegmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%s%020d%s", walSegmentPrefix, firstSeq, walSegmentSuffix)
+}
+
+func listWALSegments(dir string) ([]walSegment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list write-ahead log segments: %w", err)
+	}
+
+	segments := make([]walSegment, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentSuffix) {
+			continue
+		}
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, walSegment{firstSeq: firstSeq, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].firstSeq < segments[j].firstSeq
+	})
+	return segments, nil
+}
+
+func truncateFile(file *os.File, size int64) error {
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("truncate write-ahead log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync write-ahead log: %w", err)
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open data directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync data directory: %w", err)
+	}
+	return nil
+}
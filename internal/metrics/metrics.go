@@ -0,0 +1,187 @@
+// Package metrics exports Prometheus metrics for the HTTP API and the memory store.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"memplane/internal/memory"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "memplane"
+
+// Metrics owns a registry with request, segmentation and retrieval metrics.
+// It implements memory.Observer so the store can report without importing
+// anything HTTP or Prometheus specific.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	segmentBoundaries  prometheus.Histogram
+	segmentEventTokens prometheus.Histogram
+
+	retrieveAnchorsRequested prometheus.Counter
+	retrieveAnchorsFound     prometheus.Counter
+	retrieveExpansion        prometheus.Histogram
+
+	expiredSessions *prometheus.CounterVec
+	expiredEvents   *prometheus.CounterVec
+}
+
+var _ memory.Observer = (*Metrics)(nil)
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		segmentBoundaries: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "segment_boundaries",
+			Help:      "Boundaries finalized per segmentation call.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		segmentEventTokens: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "segment_event_tokens",
+			Help:      "Length in tokens of events created by segmentation.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+		}),
+		retrieveAnchorsRequested: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retrieve_anchors_requested_total",
+			Help:      "Anchors requested by retrieval calls, bounded by top_k.",
+		}),
+		retrieveAnchorsFound: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retrieve_anchors_found_total",
+			Help:      "Anchors retrieval calls found in the session.",
+		}),
+		retrieveExpansion: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "retrieve_returned_events",
+			Help:      "Events returned per retrieval call after contiguity expansion.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		expiredSessions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retention_expired_sessions_total",
+			Help:      "Sessions removed by retention sweeps, by reason.",
+		}, []string{"reason"}),
+		expiredEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retention_expired_events_total",
+			Help:      "Events removed by retention sweeps, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.segmentBoundaries,
+		m.segmentEventTokens,
+		m.retrieveAnchorsRequested,
+		m.retrieveAnchorsFound,
+		m.retrieveExpansion,
+		m.expiredSessions,
+		m.expiredEvents,
+	)
+	return m
+}
+
+// RegisterStore exports per-tenant store gauges, read from store.Stats on
+// every scrape.
+func (m *Metrics) RegisterStore(store memory.Store) error {
+	return m.registry.Register(newStoreCollector(store))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records one served HTTP request. route is the matched route
+// template, never the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"route": route, "method": method, "status": strconv.Itoa(status)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveSegmentation(_ string, boundaries int, eventLengths []int) {
+	m.segmentBoundaries.Observe(float64(boundaries))
+	for _, length := range eventLengths {
+		m.segmentEventTokens.Observe(float64(length))
+	}
+}
+
+func (m *Metrics) ObserveRetrieval(_ string, requested, found, returned int) {
+	m.retrieveAnchorsRequested.Add(float64(requested))
+	m.retrieveAnchorsFound.Add(float64(found))
+	m.retrieveExpansion.Observe(float64(returned))
+}
+
+func (m *Metrics) ObserveExpiry(_ string, reason memory.ExpiryReason, sessions, events int) {
+	m.expiredSessions.WithLabelValues(string(reason)).Add(float64(sessions))
+	m.expiredEvents.WithLabelValues(string(reason)).Add(float64(events))
+}
+
+type storeCollector struct {
+	store    memory.Store
+	sessions *prometheus.Desc
+	events   *prometheus.Desc
+	bytes    *prometheus.Desc
+}
+
+func newStoreCollector(store memory.Store) *storeCollector {
+	return &storeCollector{
+		store: store,
+		sessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "store", "sessions"),
+			"Sessions stored per tenant.",
+			[]string{"tenant_id"}, nil,
+		),
+		events: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "store", "events"),
+			"Events stored per tenant.",
+			[]string{"tenant_id"}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "store", "payload_bytes"),
+			"Payload bytes stored per tenant.",
+			[]string{"tenant_id"}, nil,
+		),
+	}
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessions
+	ch <- c.events
+	ch <- c.bytes
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	for tenantID, stats := range c.store.Stats().Tenants {
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(stats.Sessions), tenantID)
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.GaugeValue, float64(stats.Events), tenantID)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.PayloadBytes), tenantID)
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memplane/internal/memory"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsExportsObservations(t *testing.T) {
+	m := New()
+	store := memory.NewStoreWithOptions(memory.StoreOptions{Observer: m})
+	if err := m.RegisterStore(store); err != nil {
+		t.Fatalf("register store: %v", err)
+	}
+
+	event, err := memory.NewEvent("evt_1", "tenant_1", "session_1", 0, 4, time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	if err := store.Append(context.Background(), event); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := store.RetrieveByAnchors(context.Background(), "tenant_1", "session_1", []string{"evt_1"}, 3, 0, 0); err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	m.ObserveSegmentation("tenant_1", 2, []int{3, 5, 1})
+	m.ObserveRequest("/v1/events", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`memplane_http_requests_total{method="GET",route="/v1/events",status="200"} 1`,
+		`memplane_http_request_duration_seconds_count{method="GET",route="/v1/events",status="200"} 1`,
+		`memplane_store_sessions{tenant_id="tenant_1"} 1`,
+		`memplane_store_events{tenant_id="tenant_1"} 1`,
+		`memplane_store_payload_bytes{tenant_id="tenant_1"} 0`,
+		`memplane_segment_boundaries_sum 2`,
+		`memplane_segment_event_tokens_count 3`,
+		`memplane_retrieve_anchors_requested_total 1`,
+		`memplane_retrieve_anchors_found_total 1`,
+		`memplane_retrieve_returned_events_sum 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in scrape output:\n%s", want, body)
+		}
+	}
+}